@@ -0,0 +1,203 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+	Priority Scheduler
+
+	channel 只能照放進去的順序（FIFO）取出 task，所以這裡改用「每個 priority 一條 FIFO queue」的 scheduler：
+		- worker 每次取 task 時，比較各條 queue 的第一個 task，取 effective priority 最高的
+		- effective priority = 原本的 priority + 等待時間 / AgingInterval（aging），
+		  等越久的低優先 task 會慢慢升級，避免一直被高優先 task 插隊而餓死（starvation）
+		- task 如果設定了 deadline，而 worker 取到它時 deadline 已經過了，就直接丟掉，
+		  future 會收到 TaskExpiredError，不會浪費 worker 的時間
+		- 所有 queue 共用 QueueSize 的容量，滿了之後 Submit 一樣會 block
+*/
+
+var TaskExpiredError = errors.New("task deadline passed before it was scheduled")
+var InvalidPriorityError = errors.New("priority out of range")
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// SchedulerConfig configures the task queue of a priority pool.
+type SchedulerConfig struct {
+	Levels        int           // number of priority levels, priorities are 0 ~ Levels-1
+	QueueSize     int           // capacity shared by all levels
+	AgingInterval time.Duration // a waiting task gains one level per interval, 0 disables aging
+}
+
+// QueueStats is a snapshot of the scheduler, indexed by priority.
+type QueueStats struct {
+	Depth   []int
+	Dropped []uint64
+}
+
+type entry[T any, R any] struct {
+	*job[T, R]
+	priority Priority
+	deadline time.Time
+	enqueued time.Time
+}
+
+type scheduler[T any, R any] struct {
+	cfg SchedulerConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	queues  [][]*entry[T, R]
+	size    int
+	dropped []uint64
+	closed  bool
+	changed chan struct{} // closed and replaced whenever the state changes
+}
+
+func newScheduler[T any, R any](cfg SchedulerConfig) *scheduler[T, R] {
+	if cfg.Levels <= 0 {
+		cfg.Levels = 1
+	}
+	if cfg.QueueSize < 0 {
+		cfg.QueueSize = 0
+	}
+	return &scheduler[T, R]{
+		cfg:     cfg,
+		now:     time.Now,
+		queues:  make([][]*entry[T, R], cfg.Levels),
+		dropped: make([]uint64, cfg.Levels),
+		changed: make(chan struct{}),
+	}
+}
+
+// notify must be called with s.mu held.
+func (s *scheduler[T, R]) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *scheduler[T, R]) push(ctx context.Context, e *entry[T, R]) error {
+	if e.priority < 0 || int(e.priority) >= s.cfg.Levels {
+		return InvalidPriorityError
+	}
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return PoolClosedError
+		}
+		// an unbuffered queue still holds one task, like the hand-off of an unbuffered channel
+		if s.size < s.cfg.QueueSize || s.size == 0 {
+			e.enqueued = s.now()
+			s.queues[e.priority] = append(s.queues[e.priority], e)
+			s.size++
+			s.notify()
+			s.mu.Unlock()
+			return nil
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pop blocks until a task is ready, quit is closed, or the scheduler is closed and drained.
+func (s *scheduler[T, R]) pop(quit <-chan struct{}) (*entry[T, R], bool) {
+	for {
+		s.mu.Lock()
+		if e := s.next(); e != nil {
+			s.mu.Unlock()
+			return e, true
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return nil, false
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-quit:
+			return nil, false
+		}
+	}
+}
+
+// next removes the task to run next, dropping expired ones. It must be called with s.mu held.
+func (s *scheduler[T, R]) next() *entry[T, R] {
+	now := s.now()
+	for s.size > 0 {
+		best := -1
+		var bestScore int64
+		for level, q := range s.queues {
+			if len(q) == 0 {
+				continue
+			}
+			score := int64(level)
+			if s.cfg.AgingInterval > 0 {
+				score += int64(now.Sub(q[0].enqueued) / s.cfg.AgingInterval)
+			}
+			// on a tie, the task waiting longer wins
+			if best < 0 || score > bestScore ||
+				(score == bestScore && q[0].enqueued.Before(s.queues[best][0].enqueued)) {
+				best, bestScore = level, score
+			}
+		}
+		e := s.queues[best][0]
+		s.queues[best][0] = nil
+		s.queues[best] = s.queues[best][1:]
+		s.size--
+		s.notify()
+		if !e.deadline.IsZero() && !now.Before(e.deadline) {
+			s.dropped[best]++
+			var zero R
+			e.future.complete(zero, TaskExpiredError)
+			continue
+		}
+		return e
+	}
+	return nil
+}
+
+func (s *scheduler[T, R]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.notify()
+	}
+}
+
+func (s *scheduler[T, R]) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+func (s *scheduler[T, R]) stats() QueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := QueueStats{
+		Depth:   make([]int, len(s.queues)),
+		Dropped: make([]uint64, len(s.dropped)),
+	}
+	for i, q := range s.queues {
+		stats.Depth[i] = len(q)
+	}
+	copy(stats.Dropped, s.dropped)
+	return stats
+}
@@ -0,0 +1,163 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func popAll(s *scheduler[string, string]) []string {
+	ret := []string{}
+	for s.size > 0 {
+		e := s.next()
+		if e == nil {
+			break
+		}
+		ret = append(ret, e.task)
+	}
+	return ret
+}
+
+func pushTask(t *testing.T, s *scheduler[string, string], task string, priority Priority, deadline time.Time) *Future[string] {
+	e := &entry[string, string]{
+		job:      &job[string, string]{task: task, future: newFuture[string]()},
+		priority: priority,
+		deadline: deadline,
+	}
+	if err := s.push(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	return e.future
+}
+
+func TestSchedulerPriorityOrder(t *testing.T) {
+	s := newScheduler[string, string](SchedulerConfig{Levels: 3, QueueSize: 10})
+	pushTask(t, s, "low-1", PriorityLow, time.Time{})
+	pushTask(t, s, "high-1", PriorityHigh, time.Time{})
+	pushTask(t, s, "normal-1", PriorityNormal, time.Time{})
+	pushTask(t, s, "high-2", PriorityHigh, time.Time{})
+
+	s.mu.Lock()
+	got := popAll(s)
+	s.mu.Unlock()
+	expected := []string{"high-1", "high-2", "normal-1", "low-1"}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestSchedulerAging(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	s := newScheduler[string, string](SchedulerConfig{Levels: 3, QueueSize: 10, AgingInterval: time.Second})
+	s.now = clock.Now
+
+	pushTask(t, s, "low", PriorityLow, time.Time{})
+	clock.Advance(2 * time.Second) // low has aged to the high level
+	pushTask(t, s, "high", PriorityHigh, time.Time{})
+
+	s.mu.Lock()
+	got := popAll(s)
+	s.mu.Unlock()
+	if got[0] != "low" || got[1] != "high" {
+		t.Errorf("expected the aged task first, got %v", got)
+	}
+}
+
+func TestSchedulerDropsExpiredTasks(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	s := newScheduler[string, string](SchedulerConfig{Levels: 3, QueueSize: 10})
+	s.now = clock.Now
+
+	expired := pushTask(t, s, "expired", PriorityHigh, clock.Now().Add(time.Second))
+	pushTask(t, s, "no-deadline", PriorityNormal, time.Time{})
+	pushTask(t, s, "in-time", PriorityLow, clock.Now().Add(time.Minute))
+	clock.Advance(2 * time.Second)
+
+	stats := s.stats()
+	if stats.Depth[PriorityLow] != 1 || stats.Depth[PriorityNormal] != 1 || stats.Depth[PriorityHigh] != 1 {
+		t.Errorf("unexpected depth %v", stats.Depth)
+	}
+
+	s.mu.Lock()
+	got := popAll(s)
+	s.mu.Unlock()
+	if len(got) != 2 || got[0] != "no-deadline" || got[1] != "in-time" {
+		t.Errorf("unexpected tasks %v", got)
+	}
+	if _, err := expired.Result(); err != TaskExpiredError {
+		t.Errorf("expected TaskExpiredError, got %v", err)
+	}
+	stats = s.stats()
+	if stats.Dropped[PriorityHigh] != 1 || stats.Depth[PriorityHigh] != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSchedulerInvalidPriority(t *testing.T) {
+	s := newScheduler[string, string](SchedulerConfig{Levels: 2, QueueSize: 1})
+	e := &entry[string, string]{job: &job[string, string]{future: newFuture[string]()}, priority: PriorityHigh}
+	if err := s.push(context.Background(), e); err != InvalidPriorityError {
+		t.Errorf("expected InvalidPriorityError, got %v", err)
+	}
+}
+
+func TestPriorityPool(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	order := []string{}
+	pool, _ := NewPriorityPool(1, SchedulerConfig{Levels: 3, QueueSize: 10}, func(ctx context.Context, s string) (string, error) {
+		if s == "blocker" {
+			<-release
+		}
+		mu.Lock()
+		order = append(order, s)
+		mu.Unlock()
+		return s, nil
+	})
+
+	ctx := context.Background()
+	pool.SubmitWithPriority(ctx, "blocker", PriorityNormal, time.Time{})
+	time.Sleep(10 * time.Millisecond) // the only worker is busy now
+	pool.SubmitWithPriority(ctx, "low", PriorityLow, time.Time{})
+	expired, _ := pool.SubmitWithPriority(ctx, "expired", PriorityHigh, time.Now().Add(5*time.Millisecond))
+	pool.SubmitWithPriority(ctx, "high", PriorityHigh, time.Time{})
+	if depth := pool.QueueStats().Depth; depth[PriorityLow] != 1 || depth[PriorityHigh] != 2 {
+		t.Errorf("unexpected depth %v", depth)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	pool.Shutdown(ctx)
+
+	if _, err := expired.Result(); err != TaskExpiredError {
+		t.Errorf("expected TaskExpiredError, got %v", err)
+	}
+	expected := []string{"blocker", "high", "low"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, order)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+/*
+	Worker Pool（generic 版本）
+
+	把 waitGroup/worker_pool_test.go 裡 sqrWorker / sqrWorker2 的 tasks / results channel 模式抽成可重用的 package：
+		- task 與 result 的型別由 type parameter 決定
+		- worker 的數量可以在建立時設定，也可以在執行期用 Resize 調整
+		- task queue 是 bounded queue，Submit(ctx, task) 在 queue 滿的時候會 block 直到有空位或 ctx 結束
+		  （queue 的實作與 priority / deadline 排程請看 scheduler.go）
+		- 每個 task 都會拿到一個 Future，透過 Future 取得結果
+		- 單一 task panic 不會讓 worker 掛掉，panic 會被 recover 並轉成 PanicError
+		- Shutdown(ctx) 會把 queue 內的 task 做完才結束，ShutdownNow 則會取消所有 task
+*/
+
+var PoolClosedError = errors.New("worker pool is closed")
+var InvalidSizeError = errors.New("number of workers should be greater than 0")
+
+// PanicError wraps the value recovered from a panicking task.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// Handler is the job every worker runs for a task.
+type Handler[T any, R any] func(ctx context.Context, task T) (R, error)
+
+// Future holds the result of a submitted task.
+type Future[R any] struct {
+	done  chan struct{}
+	value R
+	err   error
+}
+
+func newFuture[R any]() *Future[R] {
+	return &Future[R]{done: make(chan struct{})}
+}
+
+func (f *Future[R]) complete(value R, err error) {
+	f.value = value
+	f.err = err
+	close(f.done)
+}
+
+// Done is closed once the result is available.
+func (f *Future[R]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get blocks until the task finished or ctx is done.
+func (f *Future[R]) Get(ctx context.Context) (R, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+// Result blocks until the task finished.
+func (f *Future[R]) Result() (R, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+type job[T any, R any] struct {
+	task   T
+	future *Future[R]
+}
+
+type Pool[T any, R any] struct {
+	handler Handler[T, R]
+	queue   *scheduler[T, R]
+
+	ctx    context.Context // passed to every handler, cancelled by ShutdownNow
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	closed  bool
+	quits   []chan struct{} // one per running worker, closed to retire it
+	workers sync.WaitGroup
+}
+
+// NewPool starts numOfWorkers workers sharing a FIFO task queue of size queueSize.
+func NewPool[T any, R any](numOfWorkers int, queueSize int, handler Handler[T, R]) (*Pool[T, R], error) {
+	return NewPriorityPool(numOfWorkers, SchedulerConfig{Levels: 1, QueueSize: queueSize}, handler)
+}
+
+// NewPriorityPool starts numOfWorkers workers sharing a priority task queue.
+func NewPriorityPool[T any, R any](numOfWorkers int, cfg SchedulerConfig, handler Handler[T, R]) (*Pool[T, R], error) {
+	if numOfWorkers <= 0 {
+		return nil, InvalidSizeError
+	}
+	p := &Pool[T, R]{
+		handler: handler,
+		queue:   newScheduler[T, R](cfg),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.mu.Lock()
+	p.grow(numOfWorkers)
+	p.mu.Unlock()
+	return p, nil
+}
+
+// Submit puts task into the queue with the lowest priority, blocking while the queue is full.
+func (p *Pool[T, R]) Submit(ctx context.Context, task T) (*Future[R], error) {
+	return p.SubmitWithPriority(ctx, task, 0, time.Time{})
+}
+
+// SubmitWithPriority puts task into the queue of the given priority.
+// A task still waiting in the queue at deadline is dropped with TaskExpiredError;
+// a zero deadline means no deadline.
+func (p *Pool[T, R]) SubmitWithPriority(ctx context.Context, task T, priority Priority, deadline time.Time) (*Future[R], error) {
+	e := &entry[T, R]{
+		job:      &job[T, R]{task: task, future: newFuture[R]()},
+		priority: priority,
+		deadline: deadline,
+	}
+	if err := p.queue.push(ctx, e); err != nil {
+		return nil, err
+	}
+	return e.future, nil
+}
+
+// Size returns the current number of workers.
+func (p *Pool[T, R]) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.quits)
+}
+
+// QueueLen returns the number of tasks waiting for a worker.
+func (p *Pool[T, R]) QueueLen() int {
+	return p.queue.len()
+}
+
+// QueueStats returns the queue depth and the number of expired tasks per priority.
+func (p *Pool[T, R]) QueueStats() QueueStats {
+	return p.queue.stats()
+}
+
+// Resize changes the number of workers at runtime.
+// Retired workers finish the task they are running before they exit.
+func (p *Pool[T, R]) Resize(numOfWorkers int) error {
+	if numOfWorkers <= 0 {
+		return InvalidSizeError
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return PoolClosedError
+	}
+	if n := numOfWorkers - len(p.quits); n > 0 {
+		p.grow(n)
+	} else {
+		for _, quit := range p.quits[numOfWorkers:] {
+			close(quit)
+		}
+		p.quits = p.quits[:numOfWorkers]
+	}
+	return nil
+}
+
+// grow must be called with p.mu held.
+func (p *Pool[T, R]) grow(n int) {
+	for i := 0; i < n; i++ {
+		quit := make(chan struct{})
+		p.quits = append(p.quits, quit)
+		p.workers.Add(1)
+		go p.work(quit)
+	}
+}
+
+func (p *Pool[T, R]) work(quit chan struct{}) {
+	defer p.workers.Done()
+	for {
+		// check quit first, so a retired worker won't pick up another task
+		select {
+		case <-quit:
+			return
+		default:
+		}
+		e, ok := p.queue.pop(quit)
+		if !ok {
+			return
+		}
+		p.run(e.job)
+	}
+}
+
+func (p *Pool[T, R]) run(j *job[T, R]) {
+	if err := p.ctx.Err(); err != nil {
+		var zero R
+		j.future.complete(zero, err)
+		return
+	}
+	var (
+		value R
+		err   error
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			var zero R
+			value, err = zero, &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		j.future.complete(value, err)
+	}()
+	value, err = p.handler(p.ctx, j.task)
+}
+
+// close stops accepting tasks, workers exit once the queue is drained.
+func (p *Pool[T, R]) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.queue.close()
+}
+
+// Shutdown stops accepting tasks and waits until queued tasks are done or ctx is done.
+func (p *Pool[T, R]) Shutdown(ctx context.Context) error {
+	p.close()
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ShutdownNow cancels the running tasks, fails the queued ones with context.Canceled
+// and waits for the workers to exit.
+func (p *Pool[T, R]) ShutdownNow() {
+	p.cancel()
+	p.close()
+	p.workers.Wait()
+}
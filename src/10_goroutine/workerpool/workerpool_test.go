@@ -0,0 +1,174 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func square(ctx context.Context, num int) (int, error) {
+	time.Sleep(10 * time.Millisecond) // 模擬會阻塞的任務
+	return num * num, nil
+}
+
+func TestPoolSquare(t *testing.T) {
+	pool, err := NewPool(3, 10, square)
+	if err != nil {
+		t.Fatal(err)
+	}
+	futures := []*Future[int]{}
+	for i := 1; i <= 5; i++ {
+		f, err := pool.Submit(context.Background(), i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures = append(futures, f)
+	}
+	for i, f := range futures {
+		ret, err := f.Result()
+		if err != nil || ret != (i+1)*(i+1) {
+			t.Errorf("task %d: expected %d, got %d (%v)", i+1, (i+1)*(i+1), ret, err)
+		}
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	pool, _ := NewPool(1, 1, func(ctx context.Context, s string) (string, error) {
+		if s == "boom" {
+			panic(s)
+		}
+		return s, nil
+	})
+	defer pool.ShutdownNow()
+
+	f1, _ := pool.Submit(context.Background(), "boom")
+	_, err := f1.Result()
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Errorf("expected PanicError, got %v", err)
+	}
+	// the worker survives the panic
+	f2, _ := pool.Submit(context.Background(), "ok")
+	if ret, err := f2.Result(); err != nil || ret != "ok" {
+		t.Errorf("expected ok, got %q (%v)", ret, err)
+	}
+}
+
+func TestSubmitBlocksOnFullQueue(t *testing.T) {
+	release := make(chan struct{})
+	pool, _ := NewPool(1, 1, func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	})
+	defer pool.ShutdownNow()
+
+	pool.Submit(context.Background(), 1) // picked up by the worker
+	time.Sleep(10 * time.Millisecond)
+	pool.Submit(context.Background(), 2) // waiting in queue
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Submit(ctx, 3); err != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	close(release)
+}
+
+func TestShutdownFinishesQueuedTasks(t *testing.T) {
+	var done int32
+	pool, _ := NewPool(2, 10, func(ctx context.Context, n int) (int, error) {
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&done, 1)
+		return n, nil
+	})
+	for i := 0; i < 10; i++ {
+		pool.Submit(context.Background(), i)
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if done != 10 {
+		t.Errorf("expected 10 tasks done, got %d", done)
+	}
+	if _, err := pool.Submit(context.Background(), 11); err != PoolClosedError {
+		t.Errorf("expected PoolClosedError, got %v", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	pool, _ := NewPool(1, 1, func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	})
+	pool.Submit(context.Background(), 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	close(release)
+	pool.Shutdown(context.Background())
+}
+
+func TestShutdownNowCancelsTasks(t *testing.T) {
+	pool, _ := NewPool(1, 10, func(ctx context.Context, n int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	futures := []*Future[int]{}
+	for i := 0; i < 5; i++ {
+		f, _ := pool.Submit(context.Background(), i)
+		futures = append(futures, f)
+	}
+	pool.ShutdownNow()
+	for _, f := range futures {
+		if _, err := f.Result(); err != context.Canceled {
+			t.Errorf("expected Canceled, got %v", err)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	var running, maxRunning int32
+	pool, _ := NewPool(1, 100, func(ctx context.Context, n int) (int, error) {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&maxRunning)
+			if cur <= old || atomic.CompareAndSwapInt32(&maxRunning, old, cur) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return n, nil
+	})
+	if err := pool.Resize(4); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Size() != 4 {
+		t.Errorf("expected 4 workers, got %d", pool.Size())
+	}
+	for i := 0; i < 40; i++ {
+		pool.Submit(context.Background(), i)
+	}
+	pool.Resize(2)
+	if pool.Size() != 2 {
+		t.Errorf("expected 2 workers, got %d", pool.Size())
+	}
+	if err := pool.Resize(0); err != InvalidSizeError {
+		t.Errorf("expected InvalidSizeError, got %v", err)
+	}
+	pool.Shutdown(context.Background())
+	if maxRunning > 4 || maxRunning < 2 {
+		t.Errorf("unexpected concurrency %d", maxRunning)
+	}
+	if err := pool.Resize(3); err != PoolClosedError {
+		t.Errorf("expected PoolClosedError, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package future
+
+import (
+	"context"
+	"strings"
+)
+
+// AllFailedError is returned by Any when every future failed, in the order of the futures.
+type AllFailedError struct {
+	Errors []error
+}
+
+func (e AllFailedError) Error() string {
+	var strs []string
+	for _, err := range e.Errors {
+		strs = append(strs, err.Error())
+	}
+	return "future: all failed: " + strings.Join(strs, ";")
+}
+
+// Result is the outcome of one future in Settled.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+func cancelAll[T any](fs []*Future[T]) {
+	for _, f := range fs {
+		f.Cancel()
+	}
+}
+
+// combine returns a future that is settled by collect and cancels fs when it is cancelled.
+func combine[T any, U any](fs []*Future[T], collect func(ctx context.Context) (U, error)) *Future[U] {
+	f := Go(context.Background(), collect)
+	go func() {
+		<-f.done
+		cancelAll(fs) // settled futures ignore Cancel
+	}()
+	return f
+}
+
+// All resolves to the values of fs in order once all succeeded.
+// It fails with the first error and cancels the remaining futures.
+func All[T any](fs ...*Future[T]) *Future[[]T] {
+	return combine(fs, func(ctx context.Context) ([]T, error) {
+		pending := make(chan *Future[T], len(fs))
+		for _, f := range fs {
+			go func(f *Future[T]) {
+				select {
+				case <-f.done:
+					pending <- f
+				case <-ctx.Done():
+				}
+			}(f)
+		}
+		for range fs {
+			select {
+			case f := <-pending:
+				if f.err != nil {
+					return nil, f.err
+				}
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		values := make([]T, len(fs))
+		for i, f := range fs {
+			values[i] = f.value
+		}
+		return values, nil
+	})
+}
+
+// Any resolves to the value of the first future that succeeded and cancels the others.
+// It fails with AllFailedError when all futures failed, and with NoFutureError without futures like Race.
+func Any[T any](fs ...*Future[T]) *Future[T] {
+	if len(fs) == 0 {
+		return Rejected[T](NoFutureError)
+	}
+	return combine(fs, func(ctx context.Context) (T, error) {
+		var zero T
+		pending := make(chan *Future[T], len(fs))
+		for _, f := range fs {
+			go func(f *Future[T]) {
+				select {
+				case <-f.done:
+					pending <- f
+				case <-ctx.Done():
+				}
+			}(f)
+		}
+		for range fs {
+			select {
+			case f := <-pending:
+				if f.err == nil {
+					return f.value, nil
+				}
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			}
+		}
+		errs := AllFailedError{}
+		for _, f := range fs {
+			errs.Errors = append(errs.Errors, f.err)
+		}
+		return zero, errs
+	})
+}
+
+// Race settles like the first future that settled, successful or not, and cancels the others.
+func Race[T any](fs ...*Future[T]) *Future[T] {
+	if len(fs) == 0 {
+		return Rejected[T](NoFutureError)
+	}
+	return combine(fs, func(ctx context.Context) (T, error) {
+		first := make(chan *Future[T], len(fs))
+		for _, f := range fs {
+			go func(f *Future[T]) {
+				select {
+				case <-f.done:
+					first <- f
+				case <-ctx.Done():
+				}
+			}(f)
+		}
+		select {
+		case f := <-first:
+			return f.value, f.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	})
+}
+
+// Settled waits for all futures and resolves to their outcomes in order. It never fails by itself.
+func Settled[T any](fs ...*Future[T]) *Future[[]Result[T]] {
+	return combine(fs, func(ctx context.Context) ([]Result[T], error) {
+		results := make([]Result[T], len(fs))
+		for i, f := range fs {
+			select {
+			case <-f.done:
+				results[i] = Result[T]{f.value, f.err}
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return results, nil
+	})
+}
@@ -0,0 +1,167 @@
+package future
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+/*
+	Future / Promise
+
+	csp/async_service_test.go 的 AsyncService 用一個 chan string 當作 future：
+		- unbuffered channel：如果沒有人來讀結果，送結果的 goroutine 會永遠 block 在 retCh <- ret（goroutine leak）
+		- buffered channel：不會 leak，但結果只能被讀一次，也沒辦法取消或串接
+
+	這裡的 Future[T] 不用 channel 傳值，而是把結果存在 struct 裡，再 close(done) 通知所有等待的人：
+		- 送結果永遠不會 block，所以 goroutine 一定可以結束
+		- 結果可以被 Await 很多次
+		- Cancel 會 cancel 傳給 fn 的 ctx，並讓 future 立即以 context.Canceled 結束
+*/
+
+var TimeoutError = errors.New("future: await timeout")
+var NoFutureError = errors.New("future: no future to wait for")
+
+// PanicError wraps the value recovered from a panicking fn.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("future: fn panicked: %v", e.Value)
+}
+
+// Unwrap is the panic value when fn panicked with an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+type Future[T any] struct {
+	done   chan struct{}
+	once   sync.Once
+	value  T
+	err    error
+	ctx    context.Context // the ctx given to Go, used by Then
+	cancel context.CancelFunc
+}
+
+func newFuture[T any](ctx context.Context) (*Future[T], context.Context) {
+	f := &Future[T]{done: make(chan struct{}), ctx: ctx}
+	runCtx, cancel := context.WithCancel(ctx)
+	f.cancel = cancel
+	return f, runCtx
+}
+
+// settle stores the first result only, later results are dropped.
+func (f *Future[T]) settle(value T, err error) {
+	f.once.Do(func() {
+		f.value, f.err = value, err
+		close(f.done)
+		f.cancel()
+	})
+}
+
+// Go runs fn in a new goroutine and returns a Future of its result.
+func Go[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) *Future[T] {
+	f, runCtx := newFuture[T](ctx)
+	go func() {
+		var (
+			value T
+			err   error
+		)
+		defer func() {
+			if r := recover(); r != nil {
+				var zero T
+				value, err = zero, &PanicError{Value: r, Stack: debug.Stack()}
+			}
+			f.settle(value, err)
+		}()
+		value, err = fn(runCtx)
+	}()
+	// the parent ctx may be cancelled before fn notices it
+	go func() {
+		select {
+		case <-runCtx.Done():
+			var zero T
+			f.settle(zero, ctx.Err())
+		case <-f.done:
+		}
+	}()
+	return f
+}
+
+// Resolved returns a Future already settled with value.
+func Resolved[T any](value T) *Future[T] {
+	f, _ := newFuture[T](context.Background())
+	f.settle(value, nil)
+	return f
+}
+
+// Rejected returns a Future already settled with err.
+func Rejected[T any](err error) *Future[T] {
+	f, _ := newFuture[T](context.Background())
+	var zero T
+	f.settle(zero, err)
+	return f
+}
+
+// Done is closed once the future is settled.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Cancel cancels the ctx of fn and settles the future with context.Canceled.
+func (f *Future[T]) Cancel() {
+	var zero T
+	f.settle(zero, context.Canceled)
+}
+
+// Await blocks until the future is settled.
+func (f *Future[T]) Await() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// AwaitTimeout blocks until the future is settled or timeout passed.
+// The future keeps running after a timeout, call Cancel to stop it.
+func (f *Future[T]) AwaitTimeout(timeout time.Duration) (T, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-timer.C:
+		var zero T
+		return zero, TimeoutError
+	}
+}
+
+// Then runs fn with the value of f once f succeeded.
+// An error of f is passed through without calling fn; cancelling the returned future cancels f too.
+func Then[T any, U any](f *Future[T], fn func(ctx context.Context, value T) (U, error)) *Future[U] {
+	next := Go(f.ctx, func(ctx context.Context) (U, error) {
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			var zero U
+			return zero, ctx.Err()
+		}
+		if f.err != nil {
+			var zero U
+			return zero, f.err
+		}
+		return fn(ctx, f.value)
+	})
+	go func() {
+		<-next.done
+		if errors.Is(next.err, context.Canceled) {
+			f.Cancel()
+		}
+	}()
+	return next
+}
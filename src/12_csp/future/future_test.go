@@ -0,0 +1,226 @@
+package future
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"go_learning/src/internal/leaktest"
+)
+
+func service(ctx context.Context) (string, error) {
+	select {
+	case <-time.After(time.Millisecond * 50):
+		return "Done", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func sleepValue(d time.Duration, value int, err error) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		select {
+		case <-time.After(d):
+			return value, err
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func TestAwait(t *testing.T) {
+	leaktest.Check(t, func() {
+		f := Go(context.Background(), service)
+		ret, err := f.Await()
+		if err != nil || ret != "Done" {
+			t.Errorf("expected Done, got %q (%v)", ret, err)
+		}
+		// the result can be read again
+		if ret, _ := f.Await(); ret != "Done" {
+			t.Errorf("expected Done, got %q", ret)
+		}
+	})
+}
+
+func TestNeverAwaitedDoesNotLeak(t *testing.T) {
+	// the unbuffered AsyncService blocks forever if nobody reads retCh
+	leaktest.Check(t, func() {
+		Go(context.Background(), service)
+	})
+}
+
+func TestAwaitTimeout(t *testing.T) {
+	leaktest.Check(t, func() {
+		f := Go(context.Background(), service)
+		if _, err := f.AwaitTimeout(time.Millisecond); err != TimeoutError {
+			t.Errorf("expected TimeoutError, got %v", err)
+		}
+		if ret, err := f.AwaitTimeout(time.Second); err != nil || ret != "Done" {
+			t.Errorf("expected Done, got %q (%v)", ret, err)
+		}
+	})
+}
+
+func TestCancel(t *testing.T) {
+	leaktest.Check(t, func() {
+		stopped := make(chan struct{})
+		f := Go(context.Background(), func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			close(stopped)
+			return 0, ctx.Err()
+		})
+		f.Cancel()
+		if _, err := f.Await(); err != context.Canceled {
+			t.Errorf("expected Canceled, got %v", err)
+		}
+		<-stopped
+	})
+}
+
+func TestParentContextCancel(t *testing.T) {
+	leaktest.Check(t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		// fn ignores ctx, the future is settled anyway
+		f := Go(ctx, func(ctx context.Context) (int, error) {
+			time.Sleep(time.Millisecond * 50)
+			return 1, nil
+		})
+		if _, err := f.Await(); err != context.DeadlineExceeded {
+			t.Errorf("expected DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestPanic(t *testing.T) {
+	f := Go(context.Background(), func(ctx context.Context) (int, error) {
+		panic("boom")
+	})
+	var pe *PanicError
+	if _, err := f.Await(); !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Errorf("expected PanicError, got %v", err)
+	}
+	f = Go(context.Background(), func(ctx context.Context) (int, error) {
+		panic(context.Canceled)
+	})
+	if _, err := f.Await(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the panic error to be unwrapped, got %v", err)
+	}
+}
+
+func TestThen(t *testing.T) {
+	leaktest.Check(t, func() {
+		f := Go(context.Background(), sleepValue(time.Millisecond, 21, nil))
+		doubled := Then(f, func(ctx context.Context, v int) (int, error) {
+			return v * 2, nil
+		})
+		str := Then(doubled, func(ctx context.Context, v int) (string, error) {
+			return strconv.Itoa(v), nil
+		})
+		if ret, err := str.Await(); err != nil || ret != "42" {
+			t.Errorf("expected 42, got %q (%v)", ret, err)
+		}
+
+		failed := Then(Rejected[int](errors.New("failed")), func(ctx context.Context, v int) (int, error) {
+			t.Error("fn should not be called")
+			return v, nil
+		})
+		if _, err := failed.Await(); err == nil || err.Error() != "failed" {
+			t.Errorf("expected failed, got %v", err)
+		}
+	})
+}
+
+func TestThenCancelPropagates(t *testing.T) {
+	leaktest.Check(t, func() {
+		f := Go(context.Background(), sleepValue(time.Second, 1, nil))
+		next := Then(f, func(ctx context.Context, v int) (int, error) {
+			return v, nil
+		})
+		next.Cancel()
+		if _, err := f.AwaitTimeout(time.Millisecond * 100); err != context.Canceled {
+			t.Errorf("expected upstream Canceled, got %v", err)
+		}
+	})
+}
+
+func TestAll(t *testing.T) {
+	leaktest.Check(t, func() {
+		ret, err := All(
+			Go(context.Background(), sleepValue(time.Millisecond*20, 1, nil)),
+			Go(context.Background(), sleepValue(time.Millisecond*10, 2, nil)),
+			Resolved(3),
+		).Await()
+		if err != nil || fmt.Sprint(ret) != "[1 2 3]" {
+			t.Errorf("expected [1 2 3], got %v (%v)", ret, err)
+		}
+
+		slow := Go(context.Background(), sleepValue(time.Second, 1, nil))
+		_, err = All(slow, Go(context.Background(), sleepValue(time.Millisecond, 0, errors.New("failed")))).Await()
+		if err == nil || err.Error() != "failed" {
+			t.Errorf("expected failed, got %v", err)
+		}
+		if _, err := slow.AwaitTimeout(time.Millisecond * 100); err != context.Canceled {
+			t.Errorf("expected the slow future to be cancelled, got %v", err)
+		}
+	})
+}
+
+func TestAny(t *testing.T) {
+	leaktest.Check(t, func() {
+		slow := Go(context.Background(), sleepValue(time.Second, 1, nil))
+		ret, err := Any(
+			Go(context.Background(), sleepValue(time.Millisecond, 0, errors.New("failed"))),
+			Go(context.Background(), sleepValue(time.Millisecond*10, 2, nil)),
+			slow,
+		).Await()
+		if err != nil || ret != 2 {
+			t.Errorf("expected 2, got %v (%v)", ret, err)
+		}
+		if _, err := slow.AwaitTimeout(time.Millisecond * 100); err != context.Canceled {
+			t.Errorf("expected the slow future to be cancelled, got %v", err)
+		}
+
+		_, err = Any(Rejected[int](errors.New("e1")), Rejected[int](errors.New("e2"))).Await()
+		var allFailed AllFailedError
+		if !errors.As(err, &allFailed) || len(allFailed.Errors) != 2 {
+			t.Errorf("expected AllFailedError, got %v", err)
+		}
+		if _, err := Any[int]().Await(); err != NoFutureError {
+			t.Errorf("expected NoFutureError, got %v", err)
+		}
+	})
+}
+
+func TestRace(t *testing.T) {
+	leaktest.Check(t, func() {
+		_, err := Race(
+			Go(context.Background(), sleepValue(time.Millisecond, 0, errors.New("failed"))),
+			Go(context.Background(), sleepValue(time.Second, 1, nil)),
+		).Await()
+		if err == nil || err.Error() != "failed" {
+			t.Errorf("expected failed, got %v", err)
+		}
+		if _, err := Race[int]().Await(); err != NoFutureError {
+			t.Errorf("expected NoFutureError, got %v", err)
+		}
+	})
+}
+
+func TestSettled(t *testing.T) {
+	leaktest.Check(t, func() {
+		ret, err := Settled(
+			Go(context.Background(), sleepValue(time.Millisecond*10, 1, nil)),
+			Rejected[int](errors.New("failed")),
+		).Await()
+		if err != nil || len(ret) != 2 {
+			t.Fatalf("unexpected %v (%v)", ret, err)
+		}
+		if ret[0].Value != 1 || ret[0].Err != nil || ret[1].Err == nil {
+			t.Errorf("unexpected %+v", ret)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package hedge
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go_learning/src/17_until_all_done/fanout"
+)
+
+/*
+	Hedged Request
+
+	FirstResponse 是一次把 request 送給所有 runner，取最快回來的那個；但對 RPC 來說同時打所有 replica 太浪費。
+	hedged request 的做法是：
+		1. 先把 request 送給第一個 replica
+		2. 如果過了 hedge delay 還沒有回應（或第一個 replica 直接失敗），再送給下一個 replica
+		3. 取最先成功的回應，並透過 ctx cancel 其他還在進行的 call
+
+	hedge delay 可以是固定值，也可以是最近成功 call 的 latency percentile（例如 p95），
+	這樣只有「比平常慢」的 request 才會多送一次。
+*/
+
+// Call sends the request to one replica.
+type Call[T any] func(ctx context.Context) (T, error)
+
+type Config struct {
+	Delay      time.Duration // hedge delay before enough latency samples are collected
+	Percentile float64       // e.g. 0.95 hedges after the p95 latency, 0 always uses Delay
+	MinSamples int           // samples needed before Percentile is used
+	WindowSize int           // number of recent latencies kept, default 100
+	MaxDelay   time.Duration // upper bound of the percentile delay, 0 means no bound
+	Clock      Clock         // default RealClock
+}
+
+type Hedger struct {
+	cfg Config
+
+	mu        sync.Mutex
+	latencies []time.Duration // ring buffer of recent successful latencies
+	next      int
+}
+
+func NewHedger(cfg Config) *Hedger {
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = 100
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = RealClock{}
+	}
+	return &Hedger{cfg: cfg}
+}
+
+func (h *Hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < h.cfg.WindowSize {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % h.cfg.WindowSize
+}
+
+// Delay returns the current hedge delay.
+func (h *Hedger) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cfg.Percentile <= 0 || len(h.latencies) == 0 || len(h.latencies) < h.cfg.MinSamples {
+		return h.cfg.Delay
+	}
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(h.cfg.Percentile*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	d := sorted[idx]
+	if h.cfg.MaxDelay > 0 && d > h.cfg.MaxDelay {
+		d = h.cfg.MaxDelay
+	}
+	return d
+}
+
+type result[T any] struct {
+	index   int
+	value   T
+	err     error
+	latency time.Duration
+}
+
+// Do sends the request to calls[0], then to the next replica whenever the hedge delay passed
+// or a call failed. It returns the first successful response and cancels the other calls.
+// If every call failed, the error is fanout.Errors.
+func Do[T any](ctx context.Context, h *Hedger, calls ...Call[T]) (T, error) {
+	var zero T
+	if len(calls) == 0 {
+		return zero, fanout.NoTaskError
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result[T], len(calls)) // never block a call goroutine
+	launch := func(i int) {
+		go func() {
+			start := h.cfg.Clock.Now()
+			value, err := calls[i](ctx)
+			results <- result[T]{i, value, err, h.cfg.Clock.Now().Sub(start)}
+		}()
+	}
+
+	// one timer per launched call, it hedges to the next replica if no reply came in time
+	launched, finished := 0, 0
+	var timer Timer
+	var fire <-chan time.Time
+	next := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, fire = nil, nil
+		}
+		launch(launched)
+		launched++
+		if launched < len(calls) {
+			timer = h.cfg.Clock.NewTimer(h.Delay())
+			fire = timer.C()
+		}
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	next()
+	var errs fanout.Errors
+	for {
+		select {
+		case r := <-results:
+			finished++
+			if r.err == nil {
+				h.observe(r.latency)
+				return r.value, nil
+			}
+			errs = append(errs, &fanout.TaskError{Index: r.index, Err: r.err})
+			if finished == len(calls) {
+				return zero, errs
+			}
+			if launched < len(calls) {
+				// don't wait for the hedge delay, the failed replica won't reply
+				next()
+			}
+		case <-fire:
+			next()
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
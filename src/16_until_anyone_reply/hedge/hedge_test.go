@@ -0,0 +1,259 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t)
+}
+
+// fakeClock only moves when Advance is called.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *fakeClock) remove(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if !t.deadline.After(c.now) {
+			t.c <- c.now
+		} else {
+			pending = append(pending, t)
+		}
+	}
+	c.timers = pending
+}
+
+// BlockUntil waits until n timers are waiting to fire.
+func (c *fakeClock) BlockUntil(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		c.mu.Lock()
+		waiting := len(c.timers)
+		c.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d timers", n)
+}
+
+func blockingCall(cancelled *int32) Call[string] {
+	return func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		atomic.AddInt32(cancelled, 1)
+		return "", ctx.Err()
+	}
+}
+
+func reply(s string) Call[string] {
+	return func(ctx context.Context) (string, error) {
+		return s, nil
+	}
+}
+
+func TestHedgeAfterDelay(t *testing.T) {
+	clock := newFakeClock()
+	h := NewHedger(Config{Delay: time.Millisecond * 10, Clock: clock})
+	var cancelled int32
+
+	done := make(chan string)
+	go func() {
+		ret, _ := Do(context.Background(), h, blockingCall(&cancelled), reply("replica-1"))
+		done <- ret
+	}()
+
+	clock.BlockUntil(t, 1)
+	select {
+	case ret := <-done:
+		t.Fatalf("hedged before the delay: %q", ret)
+	default:
+	}
+	clock.Advance(time.Millisecond * 10)
+	if ret := <-done; ret != "replica-1" {
+		t.Errorf("expected replica-1, got %q", ret)
+	}
+	time.Sleep(time.Millisecond * 10)
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Error("the slow call should be cancelled")
+	}
+}
+
+func TestNoHedgeWhenFirstReplyInTime(t *testing.T) {
+	h := NewHedger(Config{Delay: time.Hour, Clock: newFakeClock()})
+	var called int32
+	ret, err := Do(context.Background(), h, reply("replica-0"), func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&called, 1)
+		return "replica-1", nil
+	})
+	if err != nil || ret != "replica-0" {
+		t.Errorf("expected replica-0, got %q (%v)", ret, err)
+	}
+	if called != 0 {
+		t.Error("the second replica should not be called")
+	}
+}
+
+func TestHedgeImmediatelyOnFailure(t *testing.T) {
+	h := NewHedger(Config{Delay: time.Hour, Clock: newFakeClock()})
+	failed := func(ctx context.Context) (string, error) {
+		return "", errors.New("unavailable")
+	}
+	ret, err := Do(context.Background(), h, failed, reply("replica-1"))
+	if err != nil || ret != "replica-1" {
+		t.Errorf("expected replica-1, got %q (%v)", ret, err)
+	}
+
+	_, err = Do(context.Background(), h, failed, failed)
+	if err == nil || err.Error() != "task 0: unavailable;task 1: unavailable" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestHedgeOnFailureWhileHedging(t *testing.T) {
+	clock := newFakeClock()
+	h := NewHedger(Config{Delay: time.Millisecond * 10, Clock: clock})
+	var cancelled int32
+	fail := make(chan struct{})
+	failing := func(ctx context.Context) (string, error) {
+		<-fail
+		return "", errors.New("unavailable")
+	}
+
+	done := make(chan string)
+	go func() {
+		ret, _ := Do(context.Background(), h, failing, blockingCall(&cancelled), reply("replica-2"))
+		done <- ret
+	}()
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Millisecond * 10)
+	// replica-1 is in flight, the timer of replica-2 is waiting
+	clock.BlockUntil(t, 1)
+	close(fail)
+	select {
+	case ret := <-done:
+		if ret != "replica-2" {
+			t.Errorf("expected replica-2, got %q", ret)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the failure of replica-0 should hedge to replica-2 without waiting for the delay")
+	}
+	clock.BlockUntil(t, 0)
+}
+
+func TestHedgeContextCancel(t *testing.T) {
+	clock := newFakeClock()
+	h := NewHedger(Config{Delay: time.Millisecond, Clock: clock})
+	var started, cancelled int32
+	call := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&started, 1)
+		return blockingCall(&cancelled)(ctx)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := Do(ctx, h, call, call)
+		done <- err
+	}()
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Millisecond)
+	for atomic.LoadInt32(&started) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected Canceled, got %v", err)
+	}
+	time.Sleep(time.Millisecond * 10)
+	if atomic.LoadInt32(&cancelled) != 2 {
+		t.Errorf("expected both calls cancelled, got %d", cancelled)
+	}
+}
+
+func TestPercentileDelay(t *testing.T) {
+	h := NewHedger(Config{Delay: time.Second, Percentile: 0.9, MinSamples: 10, WindowSize: 10})
+	for i := 1; i <= 9; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if d := h.Delay(); d != time.Second {
+		t.Errorf("expected the fixed delay before MinSamples, got %v", d)
+	}
+	h.observe(10 * time.Millisecond)
+	if d := h.Delay(); d != 9*time.Millisecond {
+		t.Errorf("expected p90 9ms, got %v", d)
+	}
+	// the window drops the oldest sample
+	h.observe(100 * time.Millisecond)
+	if d := h.Delay(); d != 10*time.Millisecond {
+		t.Errorf("expected p90 10ms, got %v", d)
+	}
+	h.cfg.MaxDelay = 5 * time.Millisecond
+	if d := h.Delay(); d != 5*time.Millisecond {
+		t.Errorf("expected MaxDelay, got %v", d)
+	}
+}
+
+func TestDoObservesLatency(t *testing.T) {
+	clock := newFakeClock()
+	h := NewHedger(Config{Delay: time.Hour, Percentile: 0.5, Clock: clock})
+	slow := func(ctx context.Context) (string, error) {
+		clock.Advance(time.Millisecond * 30)
+		return "slow", nil
+	}
+	Do(context.Background(), h, slow)
+	if d := h.Delay(); d != time.Millisecond*30 {
+		t.Errorf("expected 30ms, got %v", d)
+	}
+}
@@ -0,0 +1,68 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go_learning/src/17_until_all_done/fanout"
+)
+
+var InvalidQuorumError = errors.New("hedge: quorum should be between 1 and the number of calls")
+
+// QuorumError is returned when too many calls failed to reach the quorum.
+type QuorumError struct {
+	Need      int
+	Succeeded int
+	Errors    fanout.Errors
+}
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("hedge: quorum of %d not reached, %d succeeded: %v", e.Need, e.Succeeded, e.Errors)
+}
+
+// Reply is one successful response of a quorum call.
+type Reply[T any] struct {
+	Index int // index of the call in calls
+	Value T
+}
+
+// Quorum sends the request to every replica and returns as soon as need of them succeeded,
+// cancelling the outstanding calls. It fails with *QuorumError as soon as the quorum can't be reached.
+func Quorum[T any](ctx context.Context, need int, calls ...Call[T]) ([]Reply[T], error) {
+	if need <= 0 || need > len(calls) {
+		return nil, InvalidQuorumError
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result[T], len(calls))
+	for i, call := range calls {
+		go func(i int, call Call[T]) {
+			value, err := call(ctx)
+			results <- result[T]{index: i, value: value, err: err}
+		}(i, call)
+	}
+
+	replies := []Reply[T]{}
+	var errs fanout.Errors
+	for range calls {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				errs = append(errs, &fanout.TaskError{Index: r.index, Err: r.err})
+				if len(calls)-len(errs) < need {
+					return replies, &QuorumError{need, len(replies), errs}
+				}
+				continue
+			}
+			replies = append(replies, Reply[T]{r.index, r.value})
+			if len(replies) == need {
+				return replies, nil
+			}
+		case <-ctx.Done():
+			return replies, ctx.Err()
+		}
+	}
+	return replies, &QuorumError{need, len(replies), errs}
+}
@@ -0,0 +1,55 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuorum(t *testing.T) {
+	var cancelled int32
+	failed := func(ctx context.Context) (string, error) {
+		return "", errors.New("unavailable")
+	}
+	replies, err := Quorum(context.Background(), 2,
+		reply("replica-0"), failed, blockingCall(&cancelled), reply("replica-3"))
+	if err != nil || len(replies) != 2 {
+		t.Fatalf("unexpected %v (%v)", replies, err)
+	}
+	for _, r := range replies {
+		if r.Value != "replica-0" && r.Value != "replica-3" {
+			t.Errorf("unexpected reply %+v", r)
+		}
+	}
+	time.Sleep(time.Millisecond * 10)
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Error("the outstanding call should be cancelled")
+	}
+}
+
+func TestQuorumNotReached(t *testing.T) {
+	var cancelled int32
+	failed := func(ctx context.Context) (string, error) {
+		return "", errors.New("unavailable")
+	}
+	// fails as soon as two calls failed, without waiting for the blocking one
+	replies, err := Quorum(context.Background(), 2, failed, failed, blockingCall(&cancelled))
+	var qe *QuorumError
+	if !errors.As(err, &qe) || qe.Need != 2 || qe.Succeeded != 0 || len(qe.Errors) != 2 {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(replies) != 0 {
+		t.Errorf("unexpected replies %v", replies)
+	}
+}
+
+func TestQuorumInvalid(t *testing.T) {
+	if _, err := Quorum(context.Background(), 2, reply("replica-0")); err != InvalidQuorumError {
+		t.Errorf("expected InvalidQuorumError, got %v", err)
+	}
+	if _, err := Quorum[string](context.Background(), 0); err != InvalidQuorumError {
+		t.Errorf("expected InvalidQuorumError, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+	16_until_anyone_reply 的 FirstResponse 與 17_until_all_done 的 AllResponse 固定開 10 個 runner、只能回傳 string，
+	而 FirstResponse 用 unbuffered channel，拿到第一個結果後其他 9 個 goroutine 會永遠 block 在 ch <- ret。
+
+	FirstOf / AllOf 是 generic 的版本：
+		- 結果 channel 的 buffer 跟 task 數量一樣大，所以不管有沒有人讀，task goroutine 都可以結束
+		- 不再需要的 task 會透過 ctx 被 cancel
+		- Options.Limit 可以限制同時執行的 task 數量
+		- Options.Mode 區分「第一個成功」跟「第一個完成」
+*/
+
+var NoTaskError = errors.New("fanout: no task to run")
+
+type Task[T any] func(ctx context.Context) (T, error)
+
+type Mode int
+
+const (
+	// FirstSuccessful ignores failed tasks:
+	// FirstOf returns the first result without error, AllOf runs every task and collects the errors.
+	FirstSuccessful Mode = iota
+	// FirstFinished treats an error as a result:
+	// FirstOf returns whatever finishes first, AllOf stops at the first error.
+	FirstFinished
+)
+
+type Options struct {
+	Mode  Mode
+	Limit int // max number of tasks running at the same time, 0 means no limit
+}
+
+// TaskError is the error of the task at Index.
+type TaskError struct {
+	Index int
+	Err   error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %d: %v", e.Index, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects the *TaskError of every failed task.
+type Errors []error
+
+func (errs Errors) Error() string {
+	var strs []string
+	for _, err := range errs {
+		strs = append(strs, err.Error())
+	}
+	return strings.Join(strs, ";")
+}
+
+func (errs Errors) sort() {
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].(*TaskError).Index < errs[j].(*TaskError).Index
+	})
+}
+
+type result[T any] struct {
+	index int
+	value T
+	err   error
+}
+
+// start runs tasks with at most limit of them at the same time and sends every result to the returned channel.
+// Tasks not started before ctx is done are reported with ctx.Err().
+func start[T any](ctx context.Context, limit int, tasks []Task[T]) <-chan result[T] {
+	results := make(chan result[T], len(tasks)) // never block a task goroutine
+	if limit <= 0 || limit > len(tasks) {
+		limit = len(tasks)
+	}
+	sem := make(chan struct{}, limit)
+	go func() {
+		for i, task := range tasks {
+			// select picks at random when a slot is free and ctx is done too, so check ctx first
+			if ctx.Err() == nil {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+				}
+			}
+			if ctx.Err() != nil {
+				for j := i; j < len(tasks); j++ {
+					results <- result[T]{index: j, err: ctx.Err()}
+				}
+				return
+			}
+			go func(i int, task Task[T]) {
+				defer func() { <-sem }()
+				value, err := task(ctx)
+				results <- result[T]{i, value, err}
+			}(i, task)
+		}
+	}()
+	return results
+}
+
+// FirstOf returns the first successful result and cancels the remaining tasks.
+func FirstOf[T any](ctx context.Context, tasks ...Task[T]) (T, error) {
+	return FirstOfWith(ctx, Options{}, tasks...)
+}
+
+// FirstOfWith is FirstOf with options. If no task succeeds, the error is Errors.
+func FirstOfWith[T any](ctx context.Context, opts Options, tasks ...Task[T]) (T, error) {
+	var zero T
+	if len(tasks) == 0 {
+		return zero, NoTaskError
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := start(ctx, opts.Limit, tasks)
+	var errs Errors
+	for range tasks {
+		r := <-results
+		if r.err == nil || opts.Mode == FirstFinished {
+			return r.value, r.err
+		}
+		errs = append(errs, &TaskError{r.index, r.err})
+	}
+	errs.sort()
+	return zero, errs
+}
+
+// AllOf runs every task and returns the results in the order of tasks.
+// Failed tasks leave a zero value in the results and are reported in Errors.
+func AllOf[T any](ctx context.Context, tasks ...Task[T]) ([]T, error) {
+	return AllOfWith(ctx, Options{}, tasks...)
+}
+
+// AllOfWith is AllOf with options. In FirstFinished mode the first error cancels the remaining tasks
+// and is returned as a *TaskError.
+func AllOfWith[T any](ctx context.Context, opts Options, tasks ...Task[T]) ([]T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	values := make([]T, len(tasks))
+	results := start(ctx, opts.Limit, tasks)
+	var errs Errors
+	for range tasks {
+		r := <-results
+		if r.err != nil {
+			if opts.Mode == FirstFinished {
+				return values, &TaskError{r.index, r.err}
+			}
+			errs = append(errs, &TaskError{r.index, r.err})
+			continue
+		}
+		values[r.index] = r.value
+	}
+	if errs != nil {
+		errs.sort()
+		return values, errs
+	}
+	return values, nil
+}
@@ -0,0 +1,199 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_learning/src/internal/leaktest"
+)
+
+func runTask(id int, d time.Duration, err error) Task[string] {
+	return func(ctx context.Context) (string, error) {
+		select {
+		case <-time.After(d):
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("The result is from %d", id), nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+func TestFirstOf(t *testing.T) {
+	leaktest.Check(t, func() {
+		tasks := []Task[string]{}
+		for i := 0; i < 10; i++ {
+			tasks = append(tasks, runTask(i, time.Duration(i+1)*time.Second, nil))
+		}
+		tasks = append(tasks, runTask(10, time.Millisecond, nil))
+		start := time.Now()
+		ret, err := FirstOf(context.Background(), tasks...)
+		if err != nil || ret != "The result is from 10" {
+			t.Errorf("unexpected %q (%v)", ret, err)
+		}
+		if time.Since(start) > time.Second {
+			t.Error("FirstOf should not wait for the slow tasks")
+		}
+	})
+}
+
+func TestFirstOfSuccessfulVsFinished(t *testing.T) {
+	leaktest.Check(t, func() {
+		failed := errors.New("failed")
+		tasks := []Task[string]{
+			runTask(0, time.Millisecond, failed),
+			runTask(1, time.Millisecond*20, nil),
+		}
+		ret, err := FirstOf(context.Background(), tasks...)
+		if err != nil || ret != "The result is from 1" {
+			t.Errorf("unexpected %q (%v)", ret, err)
+		}
+		_, err = FirstOfWith(context.Background(), Options{Mode: FirstFinished}, tasks...)
+		if err != failed {
+			t.Errorf("expected failed, got %v", err)
+		}
+	})
+}
+
+func TestFirstOfAllFailed(t *testing.T) {
+	_, err := FirstOf(context.Background(),
+		runTask(0, time.Millisecond*5, errors.New("e0")),
+		runTask(1, time.Millisecond, errors.New("e1")),
+	)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	if err.Error() != "task 0: e0;task 1: e1" {
+		t.Errorf("unexpected %q", err.Error())
+	}
+	if _, err := FirstOf[string](context.Background()); err != NoTaskError {
+		t.Errorf("expected NoTaskError, got %v", err)
+	}
+}
+
+func TestAllOf(t *testing.T) {
+	leaktest.Check(t, func() {
+		tasks := []Task[string]{}
+		for i := 0; i < 10; i++ {
+			tasks = append(tasks, runTask(i, time.Duration(10-i)*time.Millisecond, nil))
+		}
+		ret, err := AllOf(context.Background(), tasks...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, r := range ret {
+			if r != fmt.Sprintf("The result is from %d", i) {
+				t.Errorf("unexpected result %d: %q", i, r)
+			}
+		}
+	})
+}
+
+func TestAllOfCollectsErrors(t *testing.T) {
+	ret, err := AllOf(context.Background(),
+		runTask(0, time.Millisecond, nil),
+		runTask(1, time.Millisecond, errors.New("failed")),
+		runTask(2, time.Millisecond*5, nil),
+	)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].(*TaskError).Index != 1 {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if ret[0] == "" || ret[1] != "" || ret[2] == "" {
+		t.Errorf("unexpected results %q", ret)
+	}
+}
+
+func TestAllOfFailFast(t *testing.T) {
+	leaktest.Check(t, func() {
+		start := time.Now()
+		_, err := AllOfWith(context.Background(), Options{Mode: FirstFinished},
+			runTask(0, time.Second, nil),
+			runTask(1, time.Millisecond, errors.New("failed")),
+		)
+		var te *TaskError
+		if !errors.As(err, &te) || te.Index != 1 {
+			t.Errorf("unexpected error %v", err)
+		}
+		if time.Since(start) > time.Second/2 {
+			t.Error("AllOf should cancel the slow task")
+		}
+	})
+}
+
+func TestLimit(t *testing.T) {
+	leaktest.Check(t, func() {
+		var running, maxRunning int32
+		tasks := []Task[int]{}
+		for i := 0; i < 20; i++ {
+			i := i
+			tasks = append(tasks, func(ctx context.Context) (int, error) {
+				cur := atomic.AddInt32(&running, 1)
+				for {
+					old := atomic.LoadInt32(&maxRunning)
+					if cur <= old || atomic.CompareAndSwapInt32(&maxRunning, old, cur) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond * 2)
+				atomic.AddInt32(&running, -1)
+				return i, nil
+			})
+		}
+		ret, err := AllOfWith(context.Background(), Options{Limit: 3}, tasks...)
+		if err != nil || ret[19] != 19 {
+			t.Errorf("unexpected %v (%v)", ret, err)
+		}
+		if maxRunning > 3 {
+			t.Errorf("expected at most 3 running tasks, got %d", maxRunning)
+		}
+	})
+}
+
+func TestLimitedFirstOfSkipsRemainingTasks(t *testing.T) {
+	var started int32
+	tasks := []Task[int]{}
+	for i := 0; i < 10; i++ {
+		i := i
+		tasks = append(tasks, func(ctx context.Context) (int, error) {
+			atomic.AddInt32(&started, 1)
+			time.Sleep(time.Millisecond)
+			return i, nil
+		})
+	}
+	if _, err := FirstOfWith(context.Background(), Options{Limit: 1}, tasks...); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 10)
+	if n := atomic.LoadInt32(&started); n >= 10 {
+		t.Errorf("expected the remaining tasks to be skipped, %d started", n)
+	}
+}
+
+func TestCanceledContextStartsNoTask(t *testing.T) {
+	var started int32
+	tasks := []Task[int]{}
+	for i := 0; i < 100; i++ {
+		tasks = append(tasks, func(ctx context.Context) (int, error) {
+			atomic.AddInt32(&started, 1)
+			return 0, nil
+		})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := AllOfWith(ctx, Options{Limit: 10}, tasks...)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 100 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected 100 canceled tasks, got %v", err)
+	}
+	if n := atomic.LoadInt32(&started); n != 0 {
+		t.Errorf("expected no task to start, %d started", n)
+	}
+}
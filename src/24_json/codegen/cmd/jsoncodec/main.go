@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go_learning/src/24_json/codegen"
+	"log"
+	"os"
+)
+
+const usage = `usage:
+  jsoncodec [-type A,B] [-o file] files      generate the MarshalJSON / UnmarshalJSON of the structs of the files
+  jsoncodec -check [-type A,B] [-o file] files  fail if the generated file is not up to date
+  jsoncodec -check                           check every //go:generate jsoncodec directive of the current package
+
+example (indented, so go generate doesn't take it for a directive of this file):
+  //go:generate go run go_learning/src/24_json/codegen/cmd/jsoncodec struct_def.go
+  $ go generate ./src/24_json/easyjson
+  $ cd src/24_json/easyjson && go run go_learning/src/24_json/codegen/cmd/jsoncodec -check
+`
+
+func main() {
+	log.SetFlags(0)
+	c, err := codegen.ParseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n%s", err, usage)
+		os.Exit(2)
+	}
+	var issues []codegen.Issue
+	switch {
+	case c.Check && len(c.Files) == 0:
+		issues, err = codegen.CheckDirectives(".")
+	case len(c.Files) == 0:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	case c.Check:
+		issues, err = codegen.Check(c)
+	default:
+		issues, err = codegen.Write(c)
+	}
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+	if errors.Is(err, codegen.LintError) {
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+// Package codec is the runtime of the code generated by jsoncodec, on top of the easyjson lexer and writer.
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+/*
+	產生的程式碼大部分直接呼叫 jlexer / jwriter，這裡是與 encoding/json 行為不同、需要補上的部分：
+	* 浮點數：jwriter 用 'g' 格式（1e+06），encoding/json 是 1000000，超過 1e21 或小於 1e-6 才用指數，NaN 與 Inf 是錯誤
+	* ,string 選項：bool 的 "true" 與 string 兩次編碼的 "\"abc\""，jlexer 沒有對應的方法
+	* 不認得的型別（time.Time、有 MarshalJSON 的型別）交給 encoding/json，只有這些欄位用到反射
+	* 截斷的輸入 jlexer 回傳 io.EOF，改成 io.ErrUnexpectedEOF
+*/
+
+// Error is the error of the lexer, the end of a truncated input is an io.ErrUnexpectedEOF.
+func Error(in *jlexer.Lexer) error {
+	err := in.Error()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func appendFloat(out *jwriter.Writer, f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if out.Error == nil {
+			out.Error = fmt.Errorf("json: unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits))
+		}
+		return
+	}
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b := strconv.AppendFloat(nil, f, format, -1, bits)
+	if format == 'e' {
+		// e-09 to e-9 like encoding/json
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	out.Buffer.AppendBytes(b)
+}
+
+// Float64 writes f like encoding/json.
+func Float64(out *jwriter.Writer, f float64) {
+	appendFloat(out, f, 64)
+}
+
+func Float32(out *jwriter.Writer, f float32) {
+	appendFloat(out, float64(f), 32)
+}
+
+// Float64Str writes f quoted, for the ,string option.
+func Float64Str(out *jwriter.Writer, f float64) {
+	out.RawByte('"')
+	appendFloat(out, f, 64)
+	out.RawByte('"')
+}
+
+func Float32Str(out *jwriter.Writer, f float32) {
+	out.RawByte('"')
+	appendFloat(out, float64(f), 32)
+	out.RawByte('"')
+}
+
+// BoolStr reads a quoted bool, for the ,string option.
+func BoolStr(in *jlexer.Lexer) bool {
+	s := in.UnsafeString()
+	if !in.Ok() {
+		return false
+	}
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	in.AddError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into bool", s))
+	return false
+}
+
+// StringStr writes s encoded as JSON in a JSON string, for the ,string option.
+func StringStr(out *jwriter.Writer, s string) {
+	var inner jwriter.Writer
+	inner.String(s)
+	b, _ := inner.BuildBytes()
+	out.String(string(b))
+}
+
+// UnquoteStr reads a string encoded as JSON in a JSON string, for the ,string option.
+func UnquoteStr(in *jlexer.Lexer) string {
+	s := in.String()
+	if !in.Ok() {
+		return ""
+	}
+	inner := jlexer.Lexer{Data: []byte(s)}
+	v := inner.String()
+	inner.Consumed()
+	if err := inner.Error(); err != nil {
+		in.AddError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into string", s))
+		return ""
+	}
+	return v
+}
+
+// Marshal writes v with encoding/json, for the types the generator doesn't know.
+func Marshal(out *jwriter.Writer, v interface{}) {
+	out.Raw(json.Marshal(v))
+}
+
+// Unmarshal reads the next value into v with encoding/json, v is a pointer.
+func Unmarshal(in *jlexer.Lexer, v interface{}) {
+	data := in.Raw()
+	if !in.Ok() {
+		return
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		in.AddError(err)
+	}
+}
+
+// SortedKeys are the keys of m sorted, encoding/json writes the maps in this order.
+func SortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// IsEmpty is the omitempty check of the types the generator doesn't know, like encoding/json.
+func IsEmpty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	case reflect.Invalid:
+		return true
+	}
+	return false
+}
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+// IsZero is the omitzero check of the structs and the types with an IsZero method, like encoding/json.
+// p points to the value, so that an IsZero method of the pointer receiver is used too.
+func IsZero(p interface{}) bool {
+	rv := reflect.ValueOf(p).Elem()
+	if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return true
+	}
+	if z, ok := rv.Interface().(isZeroer); ok {
+		return z.IsZero()
+	}
+	if z, ok := p.(isZeroer); ok {
+		return z.IsZero()
+	}
+	return rv.IsZero()
+}
@@ -0,0 +1,116 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+func TestFloatLikeEncodingJSON(t *testing.T) {
+	values := []float64{0, 1, -1, 0.1, 1e6, 1e20, 1e21, 123456789012345678, 1e-6, 1e-7, 1.5e-9, -2.5e300,
+		math.MaxFloat64, math.SmallestNonzeroFloat64, math.MaxFloat32, 1.0 / 3, math.Copysign(0, -1)}
+	for _, f := range values {
+		var w jwriter.Writer
+		Float64(&w, f)
+		want, _ := json.Marshal(f)
+		if got := string(w.Buffer.BuildBytes()); got != string(want) {
+			t.Errorf("Float64(%v) = %s, want %s", f, got, want)
+		}
+		if math.Abs(f) > math.MaxFloat32 {
+			continue
+		}
+		w = jwriter.Writer{}
+		Float32(&w, float32(f))
+		want, _ = json.Marshal(float32(f))
+		if got := string(w.Buffer.BuildBytes()); got != string(want) {
+			t.Errorf("Float32(%v) = %s, want %s", float32(f), got, want)
+		}
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		var w jwriter.Writer
+		Float64(&w, f)
+		if w.Error == nil {
+			t.Errorf("Float64(%v): want an error", f)
+		}
+	}
+}
+
+func TestQuoted(t *testing.T) {
+	for _, s := range []string{"", "abc", `say "hi"`, "a\\b\n<>& "} {
+		var w jwriter.Writer
+		StringStr(&w, s)
+		data := w.Buffer.BuildBytes()
+		var want struct {
+			S string `json:"s,string"`
+		}
+		want.S = s
+		wantJSON, _ := json.Marshal(want)
+		if `{"s":`+string(data)+`}` != string(wantJSON) {
+			t.Errorf("StringStr(%q) = %s, want %s", s, data, wantJSON)
+		}
+		in := jlexer.Lexer{Data: data}
+		if got := UnquoteStr(&in); got != s || in.Error() != nil {
+			t.Errorf("UnquoteStr(%s) = %q, %v", data, got, in.Error())
+		}
+	}
+	for data, want := range map[string]bool{`"true"`: true, `"false"`: false} {
+		in := jlexer.Lexer{Data: []byte(data)}
+		if got := BoolStr(&in); got != want || in.Error() != nil {
+			t.Errorf("BoolStr(%s) = %v, %v", data, got, in.Error())
+		}
+	}
+	for _, data := range []string{`true`, `"yes"`, `"\"abc"`} {
+		in := jlexer.Lexer{Data: []byte(data)}
+		BoolStr(&in)
+		if in.Error() == nil {
+			t.Errorf("BoolStr(%s): want an error", data)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	in := jlexer.Lexer{Data: []byte(`{"a":`)}
+	in.Delim('{')
+	in.UnsafeFieldName(false)
+	in.WantColon()
+	_ = in.String()
+	if err := Error(&in); err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v, want unexpected EOF", err)
+	}
+}
+
+type zeroer struct{ n int }
+
+func (z *zeroer) IsZero() bool { return z.n < 0 }
+
+func TestIsZero(t *testing.T) {
+	var nilTime *time.Time
+	tests := []struct {
+		name string
+		p    interface{}
+		want bool
+	}{
+		{"zero time", &time.Time{}, true},
+		{"time", &[]time.Time{time.Now()}[0], false},
+		{"nil pointer", &nilTime, true},
+		{"zero value of a pointer receiver", &zeroer{}, false},
+		{"pointer receiver", &zeroer{-1}, true},
+		{"struct", &struct{ A, B int }{}, true},
+		{"map", &map[string]int{}, false},
+	}
+	for _, tt := range tests {
+		if got := IsZero(tt.p); got != tt.want {
+			t.Errorf("%s: IsZero = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	for v, want := range map[interface{}]bool{"": true, "a": false, 0.0: true, uint8(1): false, false: true} {
+		if got := IsEmpty(v); got != want {
+			t.Errorf("IsEmpty(%#v) = %v, want %v", v, got, want)
+		}
+	}
+}
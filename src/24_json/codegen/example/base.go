@@ -0,0 +1,7 @@
+package example
+
+// Base is embedded in Sample, its fields are promoted
+type Base struct {
+	ID   int64  `json:"id"`
+	Note string `json:"note,omitempty"`
+}
@@ -0,0 +1,46 @@
+// Package example shows the code jsoncodec generates, the test compares it with encoding/json.
+package example
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//go:generate go run go_learning/src/24_json/codegen/cmd/jsoncodec example.go
+
+type Level int
+
+type Tags []string
+
+type Labels map[string]string
+
+type Sample struct {
+	Base
+	Name     string           `json:"name"`
+	Level    Level            `json:"level,omitempty"`
+	Score    float64          `json:"score"`
+	Ratio    float32          `json:"ratio,omitempty"`
+	Count    uint8            `json:"count,string"`
+	Active   bool             `json:"active,string"`
+	Quote    string           `json:"quote,string,omitempty"`
+	Data     []byte           `json:"data"`
+	Tags     Tags             `json:"tags,omitempty"`
+	Labels   Labels           `json:"labels"`
+	Scores   map[string][]int `json:"scores,omitempty"`
+	Parent   *Base            `json:"parent"`
+	Children []*Child         `json:"children,omitempty"`
+	Created  time.Time        `json:"created"`
+	Deadline time.Time        `json:"deadline,omitzero"`
+	Extra    interface{}      `json:"extra,omitempty"`
+	Raw      json.RawMessage  `json:"raw,omitempty"`
+	Age      *int             `json:"age,string"`
+	Plain    int
+	Skip     string `json:"-"`
+	hidden   int
+}
+
+type Child struct {
+	Name  string  `json:"name"`
+	Items []Child `json:"items"`
+	Next  *Child  `json:"next,omitempty"`
+}
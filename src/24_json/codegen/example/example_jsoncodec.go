@@ -0,0 +1,625 @@
+// Code generated by jsoncodec example.go; DO NOT EDIT.
+
+package example
+
+import (
+	"strings"
+
+	"go_learning/src/24_json/codegen/codec"
+
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// MarshalJSON supports json.Marshaler interface
+func (v Sample) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	jsoncodecEncodeSample(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Sample) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	jsoncodecDecodeSample(&r, v)
+	r.Consumed()
+	return codec.Error(&r)
+}
+
+func jsoncodecEncodeSample(out *jwriter.Writer, in Sample) {
+	out.RawByte('{')
+	out.RawString("\"id\":")
+	out.Int64(in.Base.ID)
+	if in.Base.Note != "" {
+		out.RawString(",\"note\":")
+		out.String(in.Base.Note)
+	}
+	out.RawString(",\"name\":")
+	out.String(in.Name)
+	if in.Level != 0 {
+		out.RawString(",\"level\":")
+		out.Int(int(in.Level))
+	}
+	out.RawString(",\"score\":")
+	codec.Float64(out, in.Score)
+	if in.Ratio != 0 {
+		out.RawString(",\"ratio\":")
+		codec.Float32(out, in.Ratio)
+	}
+	out.RawString(",\"count\":")
+	out.Uint8Str(in.Count)
+	out.RawString(",\"active\":")
+	if in.Active {
+		out.RawString(`"true"`)
+	} else {
+		out.RawString(`"false"`)
+	}
+	if in.Quote != "" {
+		out.RawString(",\"quote\":")
+		codec.StringStr(out, in.Quote)
+	}
+	out.RawString(",\"data\":")
+	out.Base64Bytes(in.Data)
+	if len(in.Tags) != 0 {
+		out.RawString(",\"tags\":")
+		if in.Tags == nil {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for i1, v1 := range in.Tags {
+				if i1 > 0 {
+					out.RawByte(',')
+				}
+				out.String(v1)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawString(",\"labels\":")
+	if in.Labels == nil {
+		out.RawString("null")
+	} else {
+		out.RawByte('{')
+		for i1, k1 := range codec.SortedKeys(in.Labels) {
+			if i1 > 0 {
+				out.RawByte(',')
+			}
+			out.String(k1)
+			out.RawByte(':')
+			out.String(in.Labels[k1])
+		}
+		out.RawByte('}')
+	}
+	if len(in.Scores) != 0 {
+		out.RawString(",\"scores\":")
+		if in.Scores == nil {
+			out.RawString("null")
+		} else {
+			out.RawByte('{')
+			for i1, k1 := range codec.SortedKeys(in.Scores) {
+				if i1 > 0 {
+					out.RawByte(',')
+				}
+				out.String(k1)
+				out.RawByte(':')
+				if in.Scores[k1] == nil {
+					out.RawString("null")
+				} else {
+					out.RawByte('[')
+					for i3, v3 := range in.Scores[k1] {
+						if i3 > 0 {
+							out.RawByte(',')
+						}
+						out.Int(v3)
+					}
+					out.RawByte(']')
+				}
+			}
+			out.RawByte('}')
+		}
+	}
+	out.RawString(",\"parent\":")
+	if in.Parent == nil {
+		out.RawString("null")
+	} else {
+		codec.Marshal(out, (*in.Parent))
+	}
+	if len(in.Children) != 0 {
+		out.RawString(",\"children\":")
+		if in.Children == nil {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for i1, v1 := range in.Children {
+				if i1 > 0 {
+					out.RawByte(',')
+				}
+				if v1 == nil {
+					out.RawString("null")
+				} else {
+					jsoncodecEncodeChild(out, (*v1))
+				}
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawString(",\"created\":")
+	codec.Marshal(out, in.Created)
+	if !codec.IsZero(&in.Deadline) {
+		out.RawString(",\"deadline\":")
+		codec.Marshal(out, in.Deadline)
+	}
+	if in.Extra != nil {
+		out.RawString(",\"extra\":")
+		codec.Marshal(out, in.Extra)
+	}
+	if !codec.IsEmpty(in.Raw) {
+		out.RawString(",\"raw\":")
+		codec.Marshal(out, in.Raw)
+	}
+	out.RawString(",\"age\":")
+	if in.Age == nil {
+		out.RawString("null")
+	} else {
+		out.IntStr((*in.Age))
+	}
+	out.RawString(",\"Plain\":")
+	out.Int(in.Plain)
+	out.RawByte('}')
+}
+
+func jsoncodecDecodeSample(in *jlexer.Lexer, out *Sample) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch jsoncodecFieldSample(key) {
+		case 0:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Base.ID = in.Int64()
+			}
+		case 1:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Base.Note = in.String()
+			}
+		case 2:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Name = in.String()
+			}
+		case 3:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Level = Level(in.Int())
+			}
+		case 4:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Score = in.Float64()
+			}
+		case 5:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Ratio = in.Float32()
+			}
+		case 6:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Count = in.Uint8Str()
+			}
+		case 7:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Active = codec.BoolStr(in)
+			}
+		case 8:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Quote = codec.UnquoteStr(in)
+			}
+		case 9:
+			if in.IsNull() {
+				in.Skip()
+				out.Data = nil
+			} else {
+				out.Data = in.Bytes()
+			}
+		case 10:
+			if in.IsNull() {
+				in.Skip()
+				out.Tags = nil
+			} else {
+				in.Delim('[')
+				if out.Tags == nil {
+					if !in.IsDelim(']') {
+						out.Tags = make(Tags, 0, 4)
+					} else {
+						out.Tags = Tags{}
+					}
+				} else {
+					out.Tags = out.Tags[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v1 = in.String()
+					}
+					out.Tags = append(out.Tags, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case 11:
+			if in.IsNull() {
+				in.Skip()
+				out.Labels = nil
+			} else {
+				in.Delim('{')
+				if out.Labels == nil {
+					out.Labels = make(Labels)
+				}
+				for !in.IsDelim('}') {
+					k1 := in.String()
+					in.WantColon()
+					var v1 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v1 = in.String()
+					}
+					out.Labels[k1] = v1
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case 12:
+			if in.IsNull() {
+				in.Skip()
+				out.Scores = nil
+			} else {
+				in.Delim('{')
+				if out.Scores == nil {
+					out.Scores = make(map[string][]int)
+				}
+				for !in.IsDelim('}') {
+					k1 := in.String()
+					in.WantColon()
+					var v1 []int
+					if in.IsNull() {
+						in.Skip()
+						v1 = nil
+					} else {
+						in.Delim('[')
+						if v1 == nil {
+							if !in.IsDelim(']') {
+								v1 = make([]int, 0, 4)
+							} else {
+								v1 = []int{}
+							}
+						} else {
+							v1 = v1[:0]
+						}
+						for !in.IsDelim(']') {
+							var v3 int
+							if in.IsNull() {
+								in.Skip()
+							} else {
+								v3 = in.Int()
+							}
+							v1 = append(v1, v3)
+							in.WantComma()
+						}
+						in.Delim(']')
+					}
+					out.Scores[k1] = v1
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case 13:
+			if in.IsNull() {
+				in.Skip()
+				out.Parent = nil
+			} else {
+				if out.Parent == nil {
+					out.Parent = new(Base)
+				}
+				codec.Unmarshal(in, &(*out.Parent))
+			}
+		case 14:
+			if in.IsNull() {
+				in.Skip()
+				out.Children = nil
+			} else {
+				in.Delim('[')
+				if out.Children == nil {
+					if !in.IsDelim(']') {
+						out.Children = make([]*Child, 0, 4)
+					} else {
+						out.Children = []*Child{}
+					}
+				} else {
+					out.Children = out.Children[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 *Child
+					if in.IsNull() {
+						in.Skip()
+						v1 = nil
+					} else {
+						if v1 == nil {
+							v1 = new(Child)
+						}
+						jsoncodecDecodeChild(in, &(*v1))
+					}
+					out.Children = append(out.Children, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case 15:
+			codec.Unmarshal(in, &out.Created)
+		case 16:
+			codec.Unmarshal(in, &out.Deadline)
+		case 17:
+			out.Extra = in.Interface()
+		case 18:
+			codec.Unmarshal(in, &out.Raw)
+		case 19:
+			if in.IsNull() {
+				in.Skip()
+				out.Age = nil
+			} else {
+				if out.Age == nil {
+					out.Age = new(int)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					(*out.Age) = in.IntStr()
+				}
+			}
+		case 20:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Plain = in.Int()
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+// jsoncodecFieldSample is the index of the field of the key, -1 for an unknown key
+func jsoncodecFieldSample(key string) int {
+	switch key {
+	case "id":
+		return 0
+	case "note":
+		return 1
+	case "name":
+		return 2
+	case "level":
+		return 3
+	case "score":
+		return 4
+	case "ratio":
+		return 5
+	case "count":
+		return 6
+	case "active":
+		return 7
+	case "quote":
+		return 8
+	case "data":
+		return 9
+	case "tags":
+		return 10
+	case "labels":
+		return 11
+	case "scores":
+		return 12
+	case "parent":
+		return 13
+	case "children":
+		return 14
+	case "created":
+		return 15
+	case "deadline":
+		return 16
+	case "extra":
+		return 17
+	case "raw":
+		return 18
+	case "age":
+		return 19
+	case "Plain":
+		return 20
+	}
+	switch {
+	case strings.EqualFold(key, "id"):
+		return 0
+	case strings.EqualFold(key, "note"):
+		return 1
+	case strings.EqualFold(key, "name"):
+		return 2
+	case strings.EqualFold(key, "level"):
+		return 3
+	case strings.EqualFold(key, "score"):
+		return 4
+	case strings.EqualFold(key, "ratio"):
+		return 5
+	case strings.EqualFold(key, "count"):
+		return 6
+	case strings.EqualFold(key, "active"):
+		return 7
+	case strings.EqualFold(key, "quote"):
+		return 8
+	case strings.EqualFold(key, "data"):
+		return 9
+	case strings.EqualFold(key, "tags"):
+		return 10
+	case strings.EqualFold(key, "labels"):
+		return 11
+	case strings.EqualFold(key, "scores"):
+		return 12
+	case strings.EqualFold(key, "parent"):
+		return 13
+	case strings.EqualFold(key, "children"):
+		return 14
+	case strings.EqualFold(key, "created"):
+		return 15
+	case strings.EqualFold(key, "deadline"):
+		return 16
+	case strings.EqualFold(key, "extra"):
+		return 17
+	case strings.EqualFold(key, "raw"):
+		return 18
+	case strings.EqualFold(key, "age"):
+		return 19
+	case strings.EqualFold(key, "Plain"):
+		return 20
+	}
+	return -1
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Child) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	jsoncodecEncodeChild(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Child) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	jsoncodecDecodeChild(&r, v)
+	r.Consumed()
+	return codec.Error(&r)
+}
+
+func jsoncodecEncodeChild(out *jwriter.Writer, in Child) {
+	out.RawByte('{')
+	out.RawString("\"name\":")
+	out.String(in.Name)
+	out.RawString(",\"items\":")
+	if in.Items == nil {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for i1, v1 := range in.Items {
+			if i1 > 0 {
+				out.RawByte(',')
+			}
+			jsoncodecEncodeChild(out, v1)
+		}
+		out.RawByte(']')
+	}
+	if in.Next != nil {
+		out.RawString(",\"next\":")
+		if in.Next == nil {
+			out.RawString("null")
+		} else {
+			jsoncodecEncodeChild(out, (*in.Next))
+		}
+	}
+	out.RawByte('}')
+}
+
+func jsoncodecDecodeChild(in *jlexer.Lexer, out *Child) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch jsoncodecFieldChild(key) {
+		case 0:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Name = in.String()
+			}
+		case 1:
+			if in.IsNull() {
+				in.Skip()
+				out.Items = nil
+			} else {
+				in.Delim('[')
+				if out.Items == nil {
+					if !in.IsDelim(']') {
+						out.Items = make([]Child, 0, 4)
+					} else {
+						out.Items = []Child{}
+					}
+				} else {
+					out.Items = out.Items[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 Child
+					jsoncodecDecodeChild(in, &v1)
+					out.Items = append(out.Items, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case 2:
+			if in.IsNull() {
+				in.Skip()
+				out.Next = nil
+			} else {
+				if out.Next == nil {
+					out.Next = new(Child)
+				}
+				jsoncodecDecodeChild(in, &(*out.Next))
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+// jsoncodecFieldChild is the index of the field of the key, -1 for an unknown key
+func jsoncodecFieldChild(key string) int {
+	switch key {
+	case "name":
+		return 0
+	case "items":
+		return 1
+	case "next":
+		return 2
+	}
+	switch {
+	case strings.EqualFold(key, "name"):
+		return 0
+	case strings.EqualFold(key, "items"):
+		return 1
+	case strings.EqualFold(key, "next"):
+		return 2
+	}
+	return -1
+}
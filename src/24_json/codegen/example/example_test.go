@@ -0,0 +1,131 @@
+package example
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go_learning/src/24_json/codegen"
+)
+
+// plain has the fields of Sample without the generated methods, encoding/json encodes it by reflection
+type plain Sample
+
+func samples() []Sample {
+	age := 7
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	return []Sample{
+		{},
+		{
+			Base:     Base{ID: -1 << 62, Note: "<a&b>"},
+			Name:     "名稱 \"quoted\"\n\u2028",
+			Level:    3,
+			Score:    1e21,
+			Ratio:    0.1,
+			Count:    255,
+			Active:   true,
+			Quote:    `say "hi"`,
+			Data:     []byte{0, 1, 2, 255},
+			Tags:     Tags{"a", ""},
+			Labels:   Labels{"z": "1", "a": "2", "<": ">"},
+			Scores:   map[string][]int{"x": {1, 2}, "y": nil, "z": {}},
+			Parent:   &Base{ID: 2},
+			Children: []*Child{{Name: "c", Items: []Child{{Name: "d"}}}, nil, {Next: &Child{Name: "n"}}},
+			Created:  created,
+			Deadline: created.Add(time.Hour),
+			Extra:    map[string]interface{}{"k": []interface{}{1.5, "s", nil, true}},
+			Raw:      json.RawMessage(`{"raw":[1, 2]}`),
+			Age:      &age,
+			Plain:    -5,
+			Skip:     "skipped",
+			hidden:   1,
+		},
+		{Score: 1e-7, Ratio: 1e21, Data: []byte{}, Tags: Tags{}, Labels: Labels{}, Scores: map[string][]int{}, Extra: "", Raw: json.RawMessage("null")},
+		{Score: 123456789, Ratio: -0.000001, Level: -1},
+	}
+}
+
+func TestMarshalLikeEncodingJSON(t *testing.T) {
+	for i, s := range samples() {
+		want, err := json.Marshal(plain(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("sample %d:\n got %s\nwant %s", i, got, want)
+		}
+	}
+}
+
+func TestUnmarshalLikeEncodingJSON(t *testing.T) {
+	inputs := []string{
+		`{}`,
+		`null`,
+		`{"ID":1,"NOTE":"n","Name":"a","unknown":{"x":[1,{"y":null}]},"level":2,"SCORE":-0.5e3,"count":"12","active":"false"}`,
+		`{"name":null,"level":null,"data":null,"tags":null,"labels":null,"parent":null,"children":null,"age":null,"extra":null}`,
+		`{"data":"AAEC/w==","tags":[],"labels":{"\u00e9":"\ud83d\ude00"},"scores":{"a":[1,2],"b":null}}`,
+		`{"parent":{"id":3,"note":"p"},"children":[{"name":"c","items":[{"name":"d","items":null}]},null],"created":"2024-01-02T03:04:05Z"}`,
+		`{"extra":{"a":[1,"b",true,null,{"c":1.5}]},"raw":{"x": 1},"age":"42","quote":"\"q\"","Plain":1,"Skip":"s","hidden":2}`,
+		`{"name":"first","name":"second","Name":"third"}`,
+	}
+	for _, input := range inputs {
+		var want plain
+		wantErr := json.Unmarshal([]byte(input), &want)
+		var got Sample
+		gotErr := json.Unmarshal([]byte(input), &got)
+		if (gotErr != nil) != (wantErr != nil) {
+			t.Errorf("%s: err = %v, want %v", input, gotErr, wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(plain(got), want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", input, got, want)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	inputs := []string{
+		`{"name":1}`,
+		`{"count":12}`,
+		`{"count":"300"}`,
+		`{"active":"yes"}`,
+		`{"age":"x"}`,
+		`{"tags":{}}`,
+		`{"created":"yesterday"}`,
+		`{"data":"%%%"}`,
+		`{"children":[{"name":"c"]}`,
+		`{"name":"a"`,
+		`{"name":"a"}}`,
+		`[]`,
+	}
+	for _, input := range inputs {
+		var s Sample
+		if err := s.UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("%s: got %+v, want an error", input, s)
+		}
+		var p plain
+		if err := json.Unmarshal([]byte(input), &p); err == nil {
+			t.Errorf("%s: encoding/json accepts it", input)
+		}
+	}
+	var s Sample
+	if err := s.UnmarshalJSON([]byte(`{"name":"a",`)); err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
+		t.Errorf("truncated: err = %v, want unexpected EOF", err)
+	}
+}
+
+func TestGeneratedCodeIsUpToDate(t *testing.T) {
+	issues, err := codegen.CheckDirectives(".")
+	for _, issue := range issues {
+		t.Log(issue)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,612 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mailru/easyjson/jwriter"
+)
+
+/*
+	jsoncodec 跟 easyjson 一樣產生不用反射的 MarshalJSON / UnmarshalJSON，
+	但產生器在這個 repo 裡，用 go generate 產生，檢查 tag，也能檢查產生的檔案是否過期：
+		//go:generate go run go_learning/src/24_json/codegen/cmd/jsoncodec struct_def.go
+		$ go generate ./src/24_json/easyjson
+		$ go run ./src/24_json/codegen/cmd/jsoncodec -check   // CI：檔案與 struct 不一致時 exit 1
+	產生的檔案 struct_def_jsoncodec.go 第一行記錄產生的參數，Check 用同樣的參數重新產生再比較內容。
+	編碼的結果與 encoding/json 相同：
+	* 欄位的順序、名稱、omitempty / omitzero / string 選項、內嵌 struct 的欄位
+	* map 的 key 排序，nil 的 slice / map / pointer 是 null，[]byte 是 base64
+	* 解碼時欄位名稱先比對完全相同，再不分大小寫，不認得的欄位略過，null 不改變 string 與數字
+	不認得的型別（time.Time、其他 package 的 struct、有 MarshalJSON 的型別）交給 encoding/json。
+*/
+
+var StaleError = errors.New("generated code is stale, run go generate")
+
+// codecPath is the import path of the runtime of the generated code
+const codecPath = "go_learning/src/24_json/codegen/codec"
+
+// Config of a generation.
+type Config struct {
+	// Dir of the package, the current directory by default
+	Dir string
+	// Files of the structs, relative to Dir
+	Files []string
+	// Types limits the structs generated, all the structs of Files by default
+	Types []string
+	// Output file, <first file>_jsoncodec.go by default
+	Output string
+	// Check compares the generated code with Output instead of writing it
+	Check bool
+}
+
+// ParseArgs parses the arguments of the jsoncodec command.
+func ParseArgs(args []string) (Config, error) {
+	var c Config
+	fs := flag.NewFlagSet("jsoncodec", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	types := fs.String("type", "", "comma separated structs to generate, all the structs of the files by default")
+	fs.StringVar(&c.Output, "o", "", "output file, <first file>_jsoncodec.go by default")
+	fs.BoolVar(&c.Check, "check", false, "fail if the output is not up to date instead of writing it")
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+	c.Files = fs.Args()
+	if *types != "" {
+		c.Types = strings.Split(*types, ",")
+	}
+	return c, nil
+}
+
+// Args are the canonical arguments of c, written in the header of the generated file.
+func (c Config) Args() []string {
+	var args []string
+	if len(c.Types) > 0 {
+		args = append(args, "-type", strings.Join(c.Types, ","))
+	}
+	if c.Output != "" {
+		args = append(args, "-o", c.Output)
+	}
+	return append(args, c.Files...)
+}
+
+func (c Config) withDefaults() (Config, error) {
+	if len(c.Files) == 0 {
+		return c, errors.New("no go files")
+	}
+	if c.Dir == "" {
+		c.Dir = "."
+	}
+	if c.Output == "" {
+		c.Output = strings.TrimSuffix(c.Files[0], ".go") + "_jsoncodec.go"
+	}
+	return c, nil
+}
+
+// Generate returns the formatted code of the codecs of c and the issues of the struct tags.
+// The tags with errors return a LintError and no code.
+func Generate(c Config) ([]byte, []Issue, error) {
+	header := "// Code generated by jsoncodec " + strings.Join(c.Args(), " ") + "; DO NOT EDIT."
+	c, err := c.withDefaults()
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := loadPackage(c.Dir, c.Output)
+	if err != nil {
+		return nil, nil, err
+	}
+	issues, err := p.lint(c.Files)
+	if err != nil {
+		return nil, nil, err
+	}
+	if HasErrors(issues) {
+		return nil, issues, LintError
+	}
+	structs, err := p.targetStructs(c)
+	if err != nil {
+		return nil, issues, err
+	}
+	g := &generator{p: p}
+	for _, st := range structs {
+		g.structCodec(st)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\npackage %s\n\nimport (\n", header, p.name)
+	if g.usesStrings {
+		out.WriteString("\t\"strings\"\n\n")
+	}
+	fmt.Fprintf(&out, "\t%q\n\n\t\"github.com/mailru/easyjson/jlexer\"\n\t\"github.com/mailru/easyjson/jwriter\"\n)\n", codecPath)
+	out.Write(g.body.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, issues, fmt.Errorf("format the generated code: %w", err)
+	}
+	return src, issues, nil
+}
+
+// targetStructs are the structs of the files of c, checked that they can be generated
+func (p *pkg) targetStructs(c Config) ([]*StructType, error) {
+	var specs []*ast.TypeSpec
+	for _, file := range c.Files {
+		fileSpecs, err := p.structsOf(file)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, fileSpecs...)
+	}
+	if len(c.Types) > 0 {
+		byName := map[string]*ast.TypeSpec{}
+		for _, ts := range specs {
+			byName[ts.Name.Name] = ts
+		}
+		specs = specs[:0]
+		for _, name := range c.Types {
+			ts, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("struct %s is not declared in %s", name, strings.Join(c.Files, ", "))
+			}
+			specs = append(specs, ts)
+		}
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no struct in %s", strings.Join(c.Files, ", "))
+	}
+	for _, ts := range specs {
+		m := p.methods[ts.Name.Name]
+		if m["MarshalJSON"] || m["UnmarshalJSON"] {
+			return nil, fmt.Errorf("%s: %s already has a MarshalJSON or UnmarshalJSON method", p.fset.Position(ts.Pos()), ts.Name.Name)
+		}
+		p.targets[ts.Name.Name] = true
+	}
+	structs := make([]*StructType, 0, len(specs))
+	for _, ts := range specs {
+		st, err := p.structType(ts)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, st)
+	}
+	return structs, nil
+}
+
+// Check generates the code of c and compares it with the output file, a different or missing file is a StaleError.
+func Check(c Config) ([]Issue, error) {
+	src, issues, err := Generate(c)
+	if err != nil {
+		return issues, err
+	}
+	c, _ = c.withDefaults()
+	path := filepath.Join(c.Dir, c.Output)
+	old, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return issues, fmt.Errorf("%s is missing: %w", path, StaleError)
+	}
+	if err != nil {
+		return issues, err
+	}
+	if !bytes.Equal(old, src) {
+		return issues, fmt.Errorf("%s: %w", path, StaleError)
+	}
+	return issues, nil
+}
+
+// Write generates the code of c into the output file.
+func Write(c Config) ([]Issue, error) {
+	src, issues, err := Generate(c)
+	if err != nil {
+		return issues, err
+	}
+	c, _ = c.withDefaults()
+	return issues, os.WriteFile(filepath.Join(c.Dir, c.Output), src, 0644)
+}
+
+// CheckDirectives checks the output of each //go:generate jsoncodec directive in the go files of dir,
+// and that every file jsoncodec generated in dir still has its directive.
+func CheckDirectives(dir string) ([]Issue, error) {
+	directives, err := findDirectives(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(directives) == 0 {
+		return nil, fmt.Errorf("%s: no //go:generate jsoncodec directive", dir)
+	}
+	var issues []Issue
+	var errs []error
+	outputs := map[string]bool{}
+	for _, c := range directives {
+		c.Dir = dir
+		found, err := Check(c)
+		issues = append(issues, found...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		c, _ = c.withDefaults()
+		outputs[c.Output] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return issues, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || outputs[e.Name()] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return issues, err
+		}
+		if bytes.HasPrefix(data, []byte(generatedPrefix+" ")) {
+			errs = append(errs, fmt.Errorf("%s has no go:generate directive: %w", filepath.Join(dir, e.Name()), StaleError))
+		}
+	}
+	if len(errs) > 0 {
+		// errors.Is works with the first error
+		err := errs[0]
+		for _, e := range errs[1:] {
+			err = fmt.Errorf("%w; %v", err, e)
+		}
+		return issues, err
+	}
+	return issues, nil
+}
+
+// findDirectives parses the arguments of the //go:generate lines running jsoncodec
+func findDirectives(dir string) ([]Config, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var directives []Config
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if !strings.HasPrefix(line, "//go:generate ") {
+				continue
+			}
+			words := strings.Fields(line)
+			for j, w := range words {
+				if w == "jsoncodec" || strings.HasSuffix(w, "/jsoncodec") {
+					c, err := ParseArgs(words[j+1:])
+					if err != nil {
+						return nil, fmt.Errorf("%s:%d: %v", e.Name(), i+1, err)
+					}
+					directives = append(directives, c)
+					break
+				}
+			}
+		}
+	}
+	return directives, nil
+}
+
+// generator writes the codecs of the structs
+type generator struct {
+	p           *pkg
+	body        bytes.Buffer
+	usesStrings bool
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+// key is the Go string literal of the JSON "name": escaped like the writer does
+func key(name string, comma bool) string {
+	var w jwriter.Writer
+	if comma {
+		w.RawByte(',')
+	}
+	w.String(name)
+	w.RawByte(':')
+	return strconv.Quote(string(w.Buffer.BuildBytes()))
+}
+
+func (g *generator) structCodec(st *StructType) {
+	name := st.Name
+	g.printf("\n// MarshalJSON supports json.Marshaler interface\n")
+	g.printf("func (v %s) MarshalJSON() ([]byte, error) {\n\tw := jwriter.Writer{}\n\tjsoncodecEncode%s(&w, v)\n\treturn w.Buffer.BuildBytes(), w.Error\n}\n", name, name)
+	g.printf("\n// UnmarshalJSON supports json.Unmarshaler interface\n")
+	g.printf("func (v *%s) UnmarshalJSON(data []byte) error {\n\tr := jlexer.Lexer{Data: data}\n\tjsoncodecDecode%s(&r, v)\n\tr.Consumed()\n\treturn codec.Error(&r)\n}\n", name, name)
+
+	// encoder: "first" is needed only until a field is always written
+	g.printf("\nfunc jsoncodecEncode%s(out *jwriter.Writer, in %s) {\n\tout.RawByte('{')\n", name, name)
+	dynamic := len(st.Fields) > 0 && g.omitCondition(st.Fields[0], "in."+st.Fields[0].Path) != ""
+	if dynamic {
+		g.printf("\tfirst := true\n")
+	}
+	written := false
+	for _, f := range st.Fields {
+		expr := "in." + f.Path
+		cond := g.omitCondition(f, expr)
+		if cond != "" {
+			g.printf("\tif %s {\n", cond)
+		}
+		switch {
+		case written:
+			g.printf("\tout.RawString(%s)\n", key(f.JSON, true))
+		case dynamic:
+			g.printf("\tif !first {\n\t\tout.RawByte(',')\n\t}\n\tfirst = false\n\tout.RawString(%s)\n", key(f.JSON, false))
+		default:
+			g.printf("\tout.RawString(%s)\n", key(f.JSON, false))
+		}
+		g.encode(f.Type, expr, f.Quoted, 1)
+		if cond != "" {
+			g.printf("\t}\n")
+		} else {
+			written = true
+		}
+	}
+	g.printf("\tout.RawByte('}')\n}\n")
+
+	// decoder
+	g.printf("\nfunc jsoncodecDecode%s(in *jlexer.Lexer, out *%s) {\n", name, name)
+	g.printf("\tif in.IsNull() {\n\t\tin.Skip()\n\t\treturn\n\t}\n\tin.Delim('{')\n\tfor !in.IsDelim('}') {\n")
+	g.printf("\t\tkey := in.UnsafeFieldName(false)\n\t\tin.WantColon()\n")
+	if len(st.Fields) > 0 {
+		g.printf("\t\tswitch jsoncodecField%s(key) {\n", name)
+		for i, f := range st.Fields {
+			g.printf("\t\tcase %d:\n", i)
+			g.decode(f.Type, "out."+f.Path, f.Quoted, 1)
+		}
+		g.printf("\t\tdefault:\n\t\t\tin.SkipRecursive()\n\t\t}\n")
+	} else {
+		g.printf("\t\t_ = key\n\t\tin.SkipRecursive()\n")
+	}
+	g.printf("\t\tin.WantComma()\n\t}\n\tin.Delim('}')\n}\n")
+
+	// field names: the exact name first, then the name ignoring the case like encoding/json
+	if len(st.Fields) == 0 {
+		return
+	}
+	g.usesStrings = true
+	g.printf("\n// jsoncodecField%s is the index of the field of the key, -1 for an unknown key\n", name)
+	g.printf("func jsoncodecField%s(key string) int {\n\tswitch key {\n", name)
+	for i, f := range st.Fields {
+		g.printf("\tcase %q:\n\t\treturn %d\n", f.JSON, i)
+	}
+	g.printf("\t}\n\tswitch {\n")
+	for i, f := range st.Fields {
+		g.printf("\tcase strings.EqualFold(key, %q):\n\t\treturn %d\n", f.JSON, i)
+	}
+	g.printf("\t}\n\treturn -1\n}\n")
+}
+
+// hasIsZero is true when the type declared in the package has an IsZero method
+func (g *generator) hasIsZero(t *Type) bool {
+	if t.Kind == Pointer {
+		t = t.Elem
+	}
+	return g.p.methods[t.Expr]["IsZero"]
+}
+
+// omitCondition is the condition to write the field, empty when it is always written
+func (g *generator) omitCondition(f Field, expr string) string {
+	var conds []string
+	if f.OmitEmpty {
+		switch f.Type.Kind {
+		case String:
+			conds = append(conds, expr+` != ""`)
+		case Bool:
+			conds = append(conds, expr)
+		case Int, Uint, Float:
+			conds = append(conds, expr+" != 0")
+		case Bytes, Slice, Map:
+			conds = append(conds, "len("+expr+") != 0")
+		case Pointer, Interface:
+			conds = append(conds, expr+" != nil")
+		case Other:
+			conds = append(conds, "!codec.IsEmpty("+expr+")")
+		}
+	}
+	if f.OmitZero {
+		switch {
+		case f.Type.Kind == Struct || f.Type.Kind == Other || g.hasIsZero(f.Type):
+			conds = append(conds, "!codec.IsZero(&"+expr+")")
+		case f.Type.Kind == String:
+			conds = append(conds, expr+` != ""`)
+		case f.Type.Kind == Bool:
+			conds = append(conds, expr)
+		case f.Type.Kind == Int || f.Type.Kind == Uint || f.Type.Kind == Float:
+			conds = append(conds, expr+" != 0")
+		default:
+			conds = append(conds, expr+" != nil")
+		}
+	}
+	if len(conds) == 2 && conds[0] == conds[1] {
+		conds = conds[:1]
+	}
+	return strings.Join(conds, " && ")
+}
+
+var writerMethods = map[string]string{
+	"int": "Int", "int8": "Int8", "int16": "Int16", "int32": "Int32", "int64": "Int64",
+	"uint": "Uint", "uint8": "Uint8", "uint16": "Uint16", "uint32": "Uint32", "uint64": "Uint64", "uintptr": "Uint64",
+}
+
+// convert is the conversion of expr to the basic type, expr itself when it has the type
+func convert(t *Type, expr string) string {
+	if t.Expr == t.Basic {
+		return expr
+	}
+	return t.Basic + "(" + expr + ")"
+}
+
+// encode writes the statements encoding expr of the type t, depth names the loop variables
+func (g *generator) encode(t *Type, expr string, quoted bool, depth int) {
+	indent := strings.Repeat("\t", depth)
+	line := func(format string, args ...interface{}) {
+		g.printf(indent+format+"\n", args...)
+	}
+	switch t.Kind {
+	case String:
+		if quoted {
+			line("codec.StringStr(out, %s)", convert(t, expr))
+		} else {
+			line("out.String(%s)", convert(t, expr))
+		}
+	case Bool:
+		if quoted {
+			line("if %s {\n%s\tout.RawString(`\"true\"`)\n%s} else {\n%s\tout.RawString(`\"false\"`)\n%s}", expr, indent, indent, indent, indent)
+		} else {
+			line("out.Bool(%s)", convert(t, expr))
+		}
+	case Int, Uint:
+		method := writerMethods[t.Basic]
+		value := convert(t, expr)
+		if t.Basic == "uintptr" {
+			value = "uint64(" + expr + ")"
+		}
+		if quoted {
+			method += "Str"
+		}
+		line("out.%s(%s)", method, value)
+	case Float:
+		method := "Float64"
+		if t.Basic == "float32" {
+			method = "Float32"
+		}
+		if quoted {
+			method += "Str"
+		}
+		line("codec.%s(out, %s)", method, convert(t, expr))
+	case Bytes:
+		line("out.Base64Bytes(%s)", expr)
+	case Slice:
+		i, v := fmt.Sprintf("i%d", depth), fmt.Sprintf("v%d", depth)
+		line("if %s == nil {\n%s\tout.RawString(\"null\")\n%s} else {", expr, indent, indent)
+		line("\tout.RawByte('[')")
+		line("\tfor %s, %s := range %s {", i, v, expr)
+		line("\t\tif %s > 0 {\n%s\t\t\tout.RawByte(',')\n%s\t\t}", i, indent, indent)
+		g.encode(t.Elem, v, false, depth+2)
+		line("\t}")
+		line("\tout.RawByte(']')")
+		line("}")
+	case Map:
+		i, k := fmt.Sprintf("i%d", depth), fmt.Sprintf("k%d", depth)
+		line("if %s == nil {\n%s\tout.RawString(\"null\")\n%s} else {", expr, indent, indent)
+		line("\tout.RawByte('{')")
+		line("\tfor %s, %s := range codec.SortedKeys(%s) {", i, k, expr)
+		line("\t\tif %s > 0 {\n%s\t\t\tout.RawByte(',')\n%s\t\t}", i, indent, indent)
+		line("\t\tout.String(%s)", convert(t.Key, k))
+		line("\t\tout.RawByte(':')")
+		g.encode(t.Elem, expr+"["+k+"]", false, depth+2)
+		line("\t}")
+		line("\tout.RawByte('}')")
+		line("}")
+	case Pointer:
+		line("if %s == nil {\n%s\tout.RawString(\"null\")\n%s} else {", expr, indent, indent)
+		g.encode(t.Elem, "(*"+expr+")", quoted, depth+1)
+		line("}")
+	case Struct:
+		line("jsoncodecEncode%s(out, %s)", t.Struct, expr)
+	default:
+		line("codec.Marshal(out, %s)", expr)
+	}
+}
+
+var lexerMethods = map[string]string{
+	"int": "Int", "int8": "Int8", "int16": "Int16", "int32": "Int32", "int64": "Int64",
+	"uint": "Uint", "uint8": "Uint8", "uint16": "Uint16", "uint32": "Uint32", "uint64": "Uint64", "uintptr": "Uint64",
+	"float32": "Float32", "float64": "Float64",
+}
+
+// typed converts the value of the basic type or []byte to the type t
+func typed(t *Type, value string) string {
+	if t.Expr == t.Basic && t.Basic != "uintptr" || t.Kind == Bytes && t.Expr == "[]byte" {
+		return value
+	}
+	return t.Expr + "(" + value + ")"
+}
+
+// decode writes the statements decoding the next value into the variable expr of the type t
+func (g *generator) decode(t *Type, expr string, quoted bool, depth int) {
+	indent := strings.Repeat("\t", depth+2)
+	line := func(format string, args ...interface{}) {
+		g.printf(indent+format+"\n", args...)
+	}
+	// null doesn't change a string, a bool or a number, and sets a slice, a map or a pointer to nil
+	switch t.Kind {
+	case String, Bool, Int, Uint, Float:
+		line("if in.IsNull() {\n%s\tin.Skip()\n%s} else {", indent, indent)
+	case Bytes, Slice, Map, Pointer:
+		line("if in.IsNull() {\n%s\tin.Skip()\n%s\t%s = nil\n%s} else {", indent, indent, expr, indent)
+	}
+	switch t.Kind {
+	case String:
+		if quoted {
+			line("\t%s = %s", expr, typed(t, "codec.UnquoteStr(in)"))
+		} else {
+			line("\t%s = %s", expr, typed(t, "in.String()"))
+		}
+	case Bool:
+		if quoted {
+			line("\t%s = %s", expr, typed(t, "codec.BoolStr(in)"))
+		} else {
+			line("\t%s = %s", expr, typed(t, "in.Bool()"))
+		}
+	case Int, Uint, Float:
+		method := lexerMethods[t.Basic]
+		if quoted {
+			method += "Str"
+		}
+		line("\t%s = %s", expr, typed(t, "in."+method+"()"))
+	case Bytes:
+		line("\t%s = %s", expr, typed(t, "in.Bytes()"))
+	case Slice:
+		v := fmt.Sprintf("v%d", depth)
+		line("\tin.Delim('[')")
+		line("\tif %s == nil {", expr)
+		line("\t\tif !in.IsDelim(']') {\n%s\t\t\t%s = make(%s, 0, 4)\n%s\t\t} else {\n%s\t\t\t%s = %s{}\n%s\t\t}",
+			indent, expr, t.Expr, indent, indent, expr, t.Expr, indent)
+		line("\t} else {\n%s\t\t%s = %s[:0]\n%s\t}", indent, expr, expr, indent)
+		line("\tfor !in.IsDelim(']') {")
+		line("\t\tvar %s %s", v, t.Elem.Expr)
+		g.decode(t.Elem, v, false, depth+2)
+		line("\t\t%s = append(%s, %s)", expr, expr, v)
+		line("\t\tin.WantComma()")
+		line("\t}")
+		line("\tin.Delim(']')")
+	case Map:
+		k, v := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+		line("\tin.Delim('{')")
+		line("\tif %s == nil {\n%s\t\t%s = make(%s)\n%s\t}", expr, indent, expr, t.Expr, indent)
+		line("\tfor !in.IsDelim('}') {")
+		line("\t\t%s := %s", k, typed(t.Key, "in.String()"))
+		line("\t\tin.WantColon()")
+		line("\t\tvar %s %s", v, t.Elem.Expr)
+		g.decode(t.Elem, v, false, depth+2)
+		line("\t\t%s[%s] = %s", expr, k, v)
+		line("\t\tin.WantComma()")
+		line("\t}")
+		line("\tin.Delim('}')")
+	case Pointer:
+		line("\tif %s == nil {\n%s\t\t%s = new(%s)\n%s\t}", expr, indent, expr, t.Elem.Expr, indent)
+		g.decode(t.Elem, "(*"+expr+")", quoted, depth+1)
+	case Struct:
+		line("jsoncodecDecode%s(in, &%s)", t.Struct, expr)
+	case Interface:
+		line("%s = in.Interface()", expr)
+	default:
+		line("codec.Unmarshal(in, &%s)", expr)
+	}
+	switch t.Kind {
+	case String, Bool, Int, Uint, Float, Bytes, Slice, Map, Pointer:
+		line("}")
+	}
+}
@@ -0,0 +1,173 @@
+package codegen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// directive is built so no line of this file starts with it, go generate would run it
+const directive = "//go:" + "generate go run go_learning/src/24_json/codegen/cmd/jsoncodec"
+
+const employee = `package p
+
+` + directive + ` employee.go
+
+type Level int
+
+type Info struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type Employee struct {
+	Info
+	Level  Level             ` + "`json:\"level,omitempty\"`" + `
+	Skills []string          ` + "`json:\"skills\"`" + `
+	Boss   *Employee         ` + "`json:\"boss,omitempty\"`" + `
+	Meta   map[string]Level  ` + "`json:\"meta\"`" + `
+}
+`
+
+func TestGenerate(t *testing.T) {
+	dir := writePackage(t, map[string]string{"employee.go": employee, "other.go": "package p\n\ntype Other struct{ A int }\n"})
+	src, issues, err := Generate(Config{Dir: dir, Files: []string{"employee.go"}})
+	if err != nil || len(issues) != 0 {
+		t.Fatal(issues, err)
+	}
+	code := string(src)
+	for _, want := range []string{
+		"// Code generated by jsoncodec employee.go; DO NOT EDIT.\n\npackage p\n",
+		"func (v Employee) MarshalJSON() ([]byte, error) {",
+		"func (v *Info) UnmarshalJSON(data []byte) error {",
+		"func jsoncodecEncodeEmployee(out *jwriter.Writer, in Employee) {",
+		"jsoncodecEncodeEmployee(out, (*in.Boss))",
+		"out.String(in.Info.Name)",
+		"out.Int(int(in.Level))",
+		"out.Level = Level(in.Int())",
+		"codec.SortedKeys(in.Meta)",
+		`case strings.EqualFold(key, "skills"):`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("the code has no %q", want)
+		}
+	}
+	if strings.Contains(code, "Other") {
+		t.Error("a struct of another file is generated")
+	}
+
+	// only the types asked, the other structs are encoded by encoding/json
+	src, _, err = Generate(Config{Dir: dir, Files: []string{"employee.go"}, Types: []string{"Employee"}, Output: "e.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code = string(src)
+	if !strings.HasPrefix(code, "// Code generated by jsoncodec -type Employee -o e.go employee.go; DO NOT EDIT.") ||
+		strings.Contains(code, "func (v Info) MarshalJSON") || !strings.Contains(code, "out.String(in.Info.Name)") {
+		t.Errorf("-type Employee:\n%s", code)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		types []string
+		want  string
+	}{
+		{"no struct", "package p\n\ntype A int\n", nil, "no struct"},
+		{"unknown type", "package p\n\ntype A struct{}\n", []string{"B"}, "struct B is not declared"},
+		{"custom marshaler", "package p\n\ntype A struct{}\n\nfunc (A) MarshalJSON() ([]byte, error) { return nil, nil }\n", nil, "already has a MarshalJSON"},
+		{"embedded pointer", "package p\n\ntype B struct{ X int }\n\ntype A struct{ *B }\n", []string{"A"}, "embedded *B is not supported"},
+		{"lint error", "package p\n\ntype A struct {\n\tX int `josn:\"x\"`\n}\n", nil, LintError.Error()},
+		{"syntax error", "package p\n\ntype A struct {\n", nil, "expected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writePackage(t, map[string]string{"a.go": tt.src})
+			src, _, err := Generate(Config{Dir: dir, Files: []string{"a.go"}, Types: tt.types})
+			if err == nil || !strings.Contains(err.Error(), tt.want) || src != nil {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if _, _, err := Generate(Config{}); err == nil {
+		t.Error("no files: want an error")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := writePackage(t, map[string]string{"employee.go": employee})
+	c := Config{Dir: dir, Files: []string{"employee.go"}}
+	if _, err := Check(c); !errors.Is(err, StaleError) {
+		t.Fatalf("missing output: err = %v, want StaleError", err)
+	}
+	if _, err := Write(c); err != nil {
+		t.Fatal(err)
+	}
+	// the generated file is skipped when the package is loaded again
+	if _, err := Check(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckDirectives(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// a struct changes after the generation
+	changed := strings.Replace(employee, "`json:\"skills\"`", "`json:\"skill_list\"`", 1)
+	if err := os.WriteFile(filepath.Join(dir, "employee.go"), []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Check(c); !errors.Is(err, StaleError) {
+		t.Errorf("changed struct: err = %v, want StaleError", err)
+	}
+	if _, err := CheckDirectives(dir); !errors.Is(err, StaleError) {
+		t.Errorf("changed struct: directives err = %v, want StaleError", err)
+	}
+	// a typo fails the check with the issue
+	typo := strings.Replace(employee, "`json:\"skills\"`", "`josn:\"skills\"`", 1)
+	if err := os.WriteFile(filepath.Join(dir, "employee.go"), []byte(typo), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if issues, err := CheckDirectives(dir); !errors.Is(err, LintError) || len(issues) != 1 {
+		t.Errorf("typo: issues = %v, err = %v, want LintError", issues, err)
+	}
+	// the generated file without its directive
+	noDirective := strings.Replace(employee, directive+" employee.go\n", "", 1)
+	if err := os.WriteFile(filepath.Join(dir, "employee.go"), []byte(noDirective), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckDirectives(dir); err == nil || !strings.Contains(err.Error(), "no //go:generate jsoncodec directive") {
+		t.Errorf("no directive: err = %v", err)
+	}
+	withOther := noDirective + "\n" + directive + " -o other_jsoncodec.go employee.go\n"
+	if err := os.WriteFile(filepath.Join(dir, "employee.go"), []byte(withOther), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Write(Config{Dir: dir, Files: []string{"employee.go"}, Output: "other_jsoncodec.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckDirectives(dir); !errors.Is(err, StaleError) || !strings.Contains(err.Error(), "employee_jsoncodec.go has no go:generate directive") {
+		t.Errorf("orphan: err = %v", err)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	args := []string{"-type", "A,B", "-o", "out.go", "a.go", "b.go"}
+	c, err := ParseArgs(append([]string{"-check"}, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{Files: []string{"a.go", "b.go"}, Types: []string{"A", "B"}, Output: "out.go", Check: true}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("config = %+v, want %+v", c, want)
+	}
+	if got := c.Args(); !reflect.DeepEqual(got, args) {
+		t.Errorf("args = %q, want %q", got, args)
+	}
+	if _, err := ParseArgs([]string{"-unknown"}); err == nil {
+		t.Error("unknown flag: want an error")
+	}
+}
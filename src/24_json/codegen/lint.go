@@ -0,0 +1,335 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	encoding/json 與產生器遇到寫錯的 tag 都不會報錯，只是默默用了別的名稱：
+		Age int `josn:"age"`       // 沒有 json key，編碼成 "Age"
+		Age int `json:"age,omitemty"` // 不認得的選項被忽略，0 也會輸出
+	Lint 檢查 struct tag：
+	* 錯誤（產生器拒絕產生）：tag 的語法錯誤、重複的 key、像 json 的 key（josn、jsno、Json）、
+	  不認得的選項（會提示最接近的 omitempty / omitzero / string）、同一個 struct 重複的 json 名稱（encoding/json 兩個都略過）
+	* 警告：只有大小寫不同的名稱（解碼時不分大小寫）、不合法的名稱、小寫欄位上的 tag、
+	  不適用的 ,string 選項（只適用 string、數字、bool）
+	拼錯的判斷用 Damerau-Levenshtein 距離（相鄰字元對調算一次）。
+*/
+
+var LintError = errors.New("struct tags have errors")
+
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Issue is a problem of a struct tag.
+type Issue struct {
+	Pos      token.Position
+	Severity Severity
+	// Field like BasicInfo.Age
+	Field   string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", i.Pos, i.Severity, i.Field, i.Message)
+}
+
+// the options of the json tag
+var jsonOptions = []string{"omitempty", "omitzero", "string"}
+
+// tag keys of other packages which are close to json
+var otherKeys = map[string]bool{"bson": true, "jsonb": true, "jsonapi": true, "json5": true}
+
+// lintStruct checks the tags of the fields of the struct spec.
+func (p *pkg) lintStruct(ts *ast.TypeSpec) []Issue {
+	var issues []Issue
+	report := func(pos token.Pos, severity Severity, field, format string, args ...interface{}) {
+		issues = append(issues, Issue{Pos: p.fset.Position(pos), Severity: severity,
+			Field: ts.Name.Name + "." + field, Message: fmt.Sprintf(format, args...)})
+	}
+	names := map[string]string{}
+	folded := map[string]string{}
+	for _, f := range ts.Type.(*ast.StructType).Fields.List {
+		field := fieldName(f)
+		name, ok := p.lintTag(f, field, report)
+		if !ok || len(f.Names) == 0 && name == "" {
+			// json:"-", or an embedded struct whose fields are promoted
+			continue
+		}
+		for _, n := range f.Names {
+			if !ast.IsExported(n.Name) {
+				continue
+			}
+			jsonName := name
+			if jsonName == "" {
+				jsonName = n.Name
+			}
+			if other, ok := names[jsonName]; ok {
+				report(f.Pos(), Error, n.Name, "json name %q is also used by %s, encoding/json drops both", jsonName, other)
+			} else if other, ok := folded[strings.ToLower(jsonName)]; ok {
+				report(f.Pos(), Warning, n.Name, "json name %q differs from %s only by case, decoding matches either", jsonName, other)
+			}
+			names[jsonName] = n.Name
+			folded[strings.ToLower(jsonName)] = n.Name
+		}
+		if len(f.Names) == 0 {
+			if other, ok := names[name]; ok {
+				report(f.Pos(), Error, field, "json name %q is also used by %s, encoding/json drops both", name, other)
+			}
+			names[name] = field
+			folded[strings.ToLower(name)] = field
+		}
+	}
+	return issues
+}
+
+// lintTag checks the tag of the field, the json name is empty when the tag has none, ok is false for json:"-"
+func (p *pkg) lintTag(f *ast.Field, field string, report func(token.Pos, Severity, string, string, ...interface{})) (name string, ok bool) {
+	if f.Tag == nil {
+		return "", true
+	}
+	raw, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		report(f.Tag.Pos(), Error, field, "malformed tag %s", f.Tag.Value)
+		return "", true
+	}
+	pairs, err := tagPairs(raw)
+	if err != nil {
+		report(f.Tag.Pos(), Error, field, "%v", err)
+		return "", true
+	}
+	tag, hasJSON := "", false
+	seen := map[string]bool{}
+	for _, pair := range pairs {
+		if seen[pair.key] {
+			report(f.Tag.Pos(), Error, field, "duplicate tag key %s", pair.key)
+		}
+		seen[pair.key] = true
+		if pair.key == "json" {
+			tag, hasJSON = pair.value, true
+		}
+	}
+	if !hasJSON {
+		for _, pair := range pairs {
+			if looksLikeJSON(pair.key) {
+				report(f.Tag.Pos(), Error, field, "tag key %s looks like a misspelled json, the field is encoded as %q", pair.key, field)
+			}
+		}
+		return "", true
+	}
+	if tag == "-" {
+		return "", false
+	}
+	if len(f.Names) > 0 && !ast.IsExported(f.Names[0].Name) {
+		report(f.Tag.Pos(), Warning, field, "json tag on an unexported field is ignored")
+		return "", false
+	}
+	name, opts := parseTag(tag)
+	if !validName(name) {
+		report(f.Tag.Pos(), Warning, field, "json name %q is invalid, encoding/json uses the field name", name)
+		name = ""
+	}
+	for _, opt := range opts {
+		if opt == "" || contains(jsonOptions, opt) {
+			continue
+		}
+		if suggestion := closest(opt, jsonOptions, 2); suggestion != "" {
+			report(f.Tag.Pos(), Error, field, "unknown json option %q, did you mean %q", opt, suggestion)
+		} else {
+			report(f.Tag.Pos(), Error, field, "unknown json option %q", opt)
+		}
+	}
+	if contains(opts, "string") && !quotable(p.resolve(f.Type, map[string]bool{})) {
+		report(f.Tag.Pos(), Warning, field, "the string option applies only to strings, numbers and bools, it is ignored")
+	}
+	return name, true
+}
+
+// fieldName is the name of the field, or its type when embedded
+func fieldName(f *ast.Field) string {
+	if len(f.Names) > 0 {
+		names := make([]string, len(f.Names))
+		for i, n := range f.Names {
+			names[i] = n.Name
+		}
+		return strings.Join(names, ",")
+	}
+	t := f.Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if sel, ok := t.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	if ident, ok := t.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return "?"
+}
+
+type tagPair struct {
+	key, value string
+}
+
+// tagPairs parses a struct tag like the reflect package does, key:"value" separated by spaces
+func tagPairs(tag string) ([]tagPair, error) {
+	var pairs []tagPair
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return nil, fmt.Errorf("bad syntax for struct tag pair near %q", tag)
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, fmt.Errorf("bad syntax for struct tag value of %s", key)
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return nil, fmt.Errorf("bad syntax for struct tag value of %s", key)
+		}
+		pairs = append(pairs, tagPair{key, value})
+		tag = tag[i+1:]
+		if tag != "" && tag[0] != ' ' {
+			return nil, fmt.Errorf("missing space after the value of %s", key)
+		}
+	}
+	return pairs, nil
+}
+
+func looksLikeJSON(key string) bool {
+	if otherKeys[key] {
+		return false
+	}
+	return strings.EqualFold(key, "json") || len(key) >= 3 && distance(strings.ToLower(key), "json") <= 1
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closest is the candidate nearest to s within max edits, empty when none is
+func closest(s string, candidates []string, max int) string {
+	best, bestDistance := "", max+1
+	for _, c := range candidates {
+		if d := distance(s, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// distance is the Damerau-Levenshtein distance (optimal string alignment) of a and b
+func distance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// Lint checks the struct tags of the files of the package in dir, the issues are sorted by position.
+func Lint(dir string, files ...string) ([]Issue, error) {
+	p, err := loadPackage(dir, "")
+	if err != nil {
+		return nil, err
+	}
+	return p.lint(files)
+}
+
+func (p *pkg) lint(files []string) ([]Issue, error) {
+	var issues []Issue
+	for _, file := range files {
+		specs, err := p.structsOf(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, ts := range specs {
+			issues = append(issues, p.lintStruct(ts)...)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i].Pos, issues[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return issues, nil
+}
+
+// HasErrors is true when one of the issues is an Error.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePackage writes the files of a package into a temporary directory
+func writePackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		severity Severity
+		message  string
+	}{
+		{"misspelled key", "Age int `josn:\"age\"`", Error, "tag key josn looks like a misspelled json"},
+		{"swapped key", "Age int `jsno:\"age\"`", Error, "tag key jsno looks like a misspelled json"},
+		{"upper case key", "Age int `JSON:\"age\"`", Error, "tag key JSON looks like a misspelled json"},
+		{"missing letter", "Age int `jon:\"age\"`", Error, "tag key jon looks like a misspelled json"},
+		{"bson", "Age int `bson:\"age\"`", -1, ""},
+		{"yaml", "Age int `yaml:\"age\"`", -1, ""},
+		{"misspelled key with json", "Age int `json:\"age\" josn:\"x\"`", -1, ""},
+		{"unknown option", "Age int `json:\"age,omitemty\"`", Error, `unknown json option "omitemty", did you mean "omitempty"`},
+		{"unknown option far", "Age int `json:\"age,inline\"`", Error, `unknown json option "inline"`},
+		{"misspelled string", "Age int `json:\"age,strnig\"`", Error, `did you mean "string"`},
+		{"empty option", "Age int `json:\"age,\"`", -1, ""},
+		{"syntax", "Age int `json:age`", Error, "bad syntax"},
+		{"no space", "Age int `json:\"age\"xml:\"age\"`", Error, "missing space"},
+		{"duplicate key", "Age int `json:\"age\" json:\"years\"`", Error, "duplicate tag key json"},
+		{"duplicate name", "Age int `json:\"name\"`", Error, `json name "name" is also used by Name`},
+		{"untagged duplicate", "Name2 int `json:\"Name3\"`\n\tName3 int", Error, `json name "Name3" is also used by Name2`},
+		{"folded name", "Age int `json:\"NAME\"`", Warning, "differs from Name only by case"},
+		{"unexported", "age int `json:\"age\"`", Warning, "unexported field"},
+		{"invalid name", "Age int `json:\"a\\\\b\"`", Warning, "is invalid"},
+		{"string on slice", "Age []int `json:\"age,string\"`", Warning, "string option applies only to"},
+		{"string on pointer", "Age *int `json:\"age,string\"`", -1, ""},
+		{"ignored", "Age int `json:\"-\"`\n\tAge2 int `json:\"name\"`", Error, "also used by Name"},
+		{"valid", "Age int `json:\"age,omitempty,string\" xml:\"age\"`", -1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package p\n\ntype T struct {\n\tName string `json:\"name\"`\n\t" + tt.field + "\n}\n"
+			dir := writePackage(t, map[string]string{"t.go": src})
+			issues, err := Lint(dir, "t.go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.severity < 0 {
+				if len(issues) != 0 {
+					t.Errorf("issues = %v, want none", issues)
+				}
+				return
+			}
+			if len(issues) != 1 || issues[0].Severity != tt.severity || !strings.Contains(issues[0].Message, tt.message) {
+				t.Fatalf("issues = %v, want a %s with %q", issues, tt.severity, tt.message)
+			}
+			if issues[0].Pos.Line < 5 || !strings.HasPrefix(issues[0].Field, "T.") {
+				t.Errorf("issue at %s of %s, want a field of T", issues[0].Pos, issues[0].Field)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"json", "json", 0},
+		{"josn", "json", 1},
+		{"jsn", "json", 1},
+		{"jsonn", "json", 1},
+		{"omitemty", "omitempty", 1},
+		{"omitempyt", "omitempty", 1},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	只用 go/ast 讀 package 的原始碼，不需要編譯或 go/types 的型別檢查：
+	* 同一個 package 的型別宣告與 method 都收集起來，用來判斷欄位的型別
+	  （type Level int 的底層型別、有沒有自己的 MarshalJSON）
+	* jsoncodec 自己產生的檔案略過，否則第二次產生時會看到上一次產生的 MarshalJSON
+	欄位的規則跟 encoding/json 一樣：
+	* 沒有 tag 用欄位名稱，json:"-" 略過，小寫開頭的欄位略過
+	* 內嵌（embedded）的 struct 欄位會被提升到外層，名稱衝突時深度淺的優先，
+	  同深度只有一個有 tag 時取有 tag 的，否則全部略過
+*/
+
+// generatedPrefix starts the first line of the files jsoncodec generates
+const generatedPrefix = "// Code generated by jsoncodec"
+
+// Kind of a Type, how the generated code encodes it.
+type Kind int
+
+const (
+	// Other is encoded by encoding/json, like time.Time or a type with a MarshalJSON method
+	Other Kind = iota
+	String
+	Bool
+	Int
+	Uint
+	Float
+	Bytes
+	Slice
+	Map
+	Pointer
+	Struct
+	Interface
+)
+
+var kindNames = [...]string{"other", "string", "bool", "int", "uint", "float", "bytes", "slice", "map", "pointer", "struct", "interface"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Type of a field.
+type Type struct {
+	Kind Kind
+	// Expr is the Go type, like []string or Level
+	Expr string
+	// Basic is the underlying type of String, Bool, Int, Uint and Float, like int8 or float64
+	Basic string
+	// Elem of Slice, Map and Pointer
+	Elem *Type
+	// Key of Map
+	Key *Type
+	// Struct is the name of a Struct generated in the same file
+	Struct string
+}
+
+// Field is a field encoded in the JSON object of a struct.
+type Field struct {
+	// Path is the Go selector from the struct, like Name or BasicInfo.Name for a promoted field
+	Path string
+	JSON string
+	Type *Type
+	// the options of the json tag
+	OmitEmpty bool
+	OmitZero  bool
+	Quoted    bool
+	Pos       token.Position
+
+	tagged bool
+	depth  int
+}
+
+// StructType is a struct the codec is generated for.
+type StructType struct {
+	Name   string
+	Fields []Field
+	Pos    token.Position
+}
+
+// pkg is the source of a package
+type pkg struct {
+	name  string
+	dir   string
+	fset  *token.FileSet
+	files map[string]*ast.File
+	types map[string]*ast.TypeSpec
+	// methods of each type, value and pointer receivers
+	methods map[string]map[string]bool
+	// targets are the struct names generated
+	targets map[string]bool
+}
+
+// loadPackage parses the go files of dir, except the tests, skip and the files jsoncodec generated.
+func loadPackage(dir, skip string) (*pkg, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := &pkg{dir: dir, fset: token.NewFileSet(), files: map[string]*ast.File{},
+		types: map[string]*ast.TypeSpec{}, methods: map[string]map[string]bool{}, targets: map[string]bool{}}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == skip {
+			continue
+		}
+		f, err := parser.ParseFile(p.fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if isGenerated(f) {
+			continue
+		}
+		if p.name == "" {
+			p.name = f.Name.Name
+		} else if p.name != f.Name.Name {
+			return nil, fmt.Errorf("%s: package %s, want %s", name, f.Name.Name, p.name)
+		}
+		p.files[name] = f
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						p.types[ts.Name.Name] = ts
+					}
+				}
+			case *ast.FuncDecl:
+				if d.Recv == nil || len(d.Recv.List) == 0 {
+					continue
+				}
+				recv := d.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if ident, ok := recv.(*ast.Ident); ok {
+					if p.methods[ident.Name] == nil {
+						p.methods[ident.Name] = map[string]bool{}
+					}
+					p.methods[ident.Name][d.Name.Name] = true
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+func isGenerated(f *ast.File) bool {
+	for _, c := range f.Comments {
+		if c.Pos() > f.Package {
+			break
+		}
+		for _, line := range c.List {
+			if strings.HasPrefix(line.Text, generatedPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// structsOf are the struct types declared in the file, in the order of the source
+func (p *pkg) structsOf(file string) ([]*ast.TypeSpec, error) {
+	f, ok := p.files[file]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a go file of package %s in %s", file, p.name, p.dir)
+	}
+	var specs []*ast.TypeSpec
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.TYPE {
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if _, ok := ts.Type.(*ast.StructType); ok && ts.TypeParams == nil {
+					specs = append(specs, ts)
+				}
+			}
+		}
+	}
+	return specs, nil
+}
+
+// hasCustomJSON is true when the type has its own JSON or text marshaling, encoding/json uses them
+func (p *pkg) hasCustomJSON(name string) bool {
+	m := p.methods[name]
+	return m["MarshalJSON"] || m["UnmarshalJSON"] || m["MarshalText"] || m["UnmarshalText"]
+}
+
+var basicKinds = map[string]Kind{
+	"string": String, "bool": Bool,
+	"int": Int, "int8": Int, "int16": Int, "int32": Int, "int64": Int, "rune": Int,
+	"uint": Uint, "uint8": Uint, "uint16": Uint, "uint32": Uint, "uint64": Uint, "uintptr": Uint, "byte": Uint,
+	"float32": Float, "float64": Float,
+}
+
+// resolve is the Type of expr, seen guards against the recursive type declarations.
+func (p *pkg) resolve(expr ast.Expr, seen map[string]bool) *Type {
+	t := &Type{Expr: types.ExprString(expr)}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if kind, ok := basicKinds[e.Name]; ok && p.types[e.Name] == nil {
+			t.Kind, t.Basic = kind, e.Name
+			switch e.Name {
+			case "rune":
+				t.Basic = "int32"
+			case "byte":
+				t.Basic = "uint8"
+			}
+			return t
+		}
+		if e.Name == "any" && p.types[e.Name] == nil {
+			t.Kind = Interface
+			return t
+		}
+		ts := p.types[e.Name]
+		if ts == nil || ts.TypeParams != nil || seen[e.Name] || p.hasCustomJSON(e.Name) {
+			return t
+		}
+		if _, ok := ts.Type.(*ast.StructType); ok {
+			if p.targets[e.Name] {
+				t.Kind, t.Struct = Struct, e.Name
+			}
+			return t
+		}
+		// a named type is encoded like its underlying type, converted to the name
+		seen[e.Name] = true
+		under := p.resolve(ts.Type, seen)
+		delete(seen, e.Name)
+		if under.Kind == Other || under.Kind == Interface {
+			return t
+		}
+		under.Expr = t.Expr
+		return under
+	case *ast.StarExpr:
+		t.Kind, t.Elem = Pointer, p.resolve(e.X, seen)
+	case *ast.ArrayType:
+		if e.Len != nil {
+			return t
+		}
+		if ident, ok := e.Elt.(*ast.Ident); ok && (ident.Name == "byte" || ident.Name == "uint8") && p.types[ident.Name] == nil {
+			t.Kind = Bytes
+			return t
+		}
+		t.Kind, t.Elem = Slice, p.resolve(e.Elt, seen)
+	case *ast.MapType:
+		if key := p.resolve(e.Key, seen); key.Kind == String {
+			t.Kind, t.Key, t.Elem = Map, key, p.resolve(e.Value, seen)
+		}
+	case *ast.InterfaceType:
+		if len(e.Methods.List) == 0 {
+			t.Kind = Interface
+		}
+	case *ast.ParenExpr:
+		return p.resolve(e.X, seen)
+	}
+	return t
+}
+
+// parseTag splits a json tag into the name and the options
+func parseTag(tag string) (string, []string) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+func fieldTag(f *ast.Field) reflect.StructTag {
+	if f.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag)
+}
+
+// structType collects the JSON fields of the struct spec.
+func (p *pkg) structType(ts *ast.TypeSpec) (*StructType, error) {
+	st := &StructType{Name: ts.Name.Name, Pos: p.fset.Position(ts.Pos())}
+	fields, err := p.fields(ts.Type.(*ast.StructType), "", 0, map[string]bool{ts.Name.Name: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", st.Pos, st.Name, err)
+	}
+	st.Fields = dominantFields(fields)
+	return st, nil
+}
+
+func (p *pkg) fields(st *ast.StructType, prefix string, depth int, visiting map[string]bool) ([]Field, error) {
+	var fields []Field
+	for _, f := range st.Fields.List {
+		tag := fieldTag(f).Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		if !validName(name) {
+			name = ""
+		}
+		names := make([]string, 0, len(f.Names))
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		if len(f.Names) == 0 {
+			embedded := f.Type
+			star, isPointer := embedded.(*ast.StarExpr)
+			if isPointer {
+				embedded = star.X
+			}
+			typeName := types.ExprString(embedded)
+			if sel, ok := embedded.(*ast.SelectorExpr); ok {
+				typeName = sel.Sel.Name
+			}
+			ident, isIdent := embedded.(*ast.Ident)
+			isStruct := false
+			if isIdent && p.types[ident.Name] != nil {
+				_, isStruct = p.types[ident.Name].Type.(*ast.StructType)
+			}
+			switch {
+			case name != "":
+				// a tagged embedded field is a field named by the tag
+			case isStruct && !isPointer && !p.hasCustomJSON(ident.Name):
+				if visiting[ident.Name] {
+					return nil, fmt.Errorf("%s embeds itself", ident.Name)
+				}
+				visiting[ident.Name] = true
+				promoted, err := p.fields(p.types[ident.Name].Type.(*ast.StructType), prefix+ident.Name+".", depth+1, visiting)
+				delete(visiting, ident.Name)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, promoted...)
+				continue
+			case !ast.IsExported(typeName):
+				continue
+			case isStruct || !isIdent:
+				// the fields or the methods of the embedded type would be promoted
+				return nil, fmt.Errorf("embedded %s is not supported, give it a json name or make it a named field", types.ExprString(f.Type))
+			}
+			names = append(names, typeName)
+		}
+		for _, n := range names {
+			if !ast.IsExported(n) {
+				continue
+			}
+			field := Field{
+				Path: prefix + n, JSON: name, Type: p.resolve(f.Type, map[string]bool{}),
+				Pos: p.fset.Position(f.Pos()), tagged: name != "", depth: depth,
+			}
+			if field.JSON == "" {
+				field.JSON = n
+			}
+			for _, opt := range opts {
+				switch opt {
+				case "omitempty":
+					field.OmitEmpty = true
+				case "omitzero":
+					field.OmitZero = true
+				case "string":
+					field.Quoted = quotable(field.Type)
+				}
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// quotable are the types the ,string option applies to, a pointer to them too
+func quotable(t *Type) bool {
+	if t.Kind == Pointer {
+		t = t.Elem
+	}
+	switch t.Kind {
+	case String, Bool, Int, Uint, Float:
+		return true
+	}
+	return false
+}
+
+// dominantFields keeps the field of each JSON name encoding/json uses, in the order of the fields.
+func dominantFields(fields []Field) []Field {
+	byName := map[string][]int{}
+	for i, f := range fields {
+		byName[f.JSON] = append(byName[f.JSON], i)
+	}
+	keep := map[int]bool{}
+	for _, indexes := range byName {
+		sort.SliceStable(indexes, func(a, b int) bool { return fields[indexes[a]].depth < fields[indexes[b]].depth })
+		top := fields[indexes[0]].depth
+		var candidates []int
+		for _, i := range indexes {
+			if fields[i].depth == top {
+				candidates = append(candidates, i)
+			}
+		}
+		if len(candidates) == 1 {
+			keep[candidates[0]] = true
+			continue
+		}
+		var tagged []int
+		for _, i := range candidates {
+			if fields[i].tagged {
+				tagged = append(tagged, i)
+			}
+		}
+		if len(tagged) == 1 {
+			keep[tagged[0]] = true
+		}
+	}
+	var kept []Field
+	for i, f := range fields {
+		if keep[i] {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// validName is the check of encoding/json, an invalid name is ignored
+func validName(name string) bool {
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c > 127):
+			return false
+		}
+	}
+	return true
+}
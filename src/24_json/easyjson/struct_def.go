@@ -1,8 +1,10 @@
 package easyjson
 
+//go:generate go run go_learning/src/24_json/codegen/cmd/jsoncodec struct_def.go
+
 type BasicInfo struct {
 	Name string `json:"name"`
-	Age  int    `josn:"age"`
+	Age  int    `json:"age"`
 }
 type JobInfo struct {
 	Skills []string `json:"skills"`
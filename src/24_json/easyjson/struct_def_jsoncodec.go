@@ -0,0 +1,237 @@
+// Code generated by jsoncodec struct_def.go; DO NOT EDIT.
+
+package easyjson
+
+import (
+	"strings"
+
+	"go_learning/src/24_json/codegen/codec"
+
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// MarshalJSON supports json.Marshaler interface
+func (v BasicInfo) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	jsoncodecEncodeBasicInfo(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *BasicInfo) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	jsoncodecDecodeBasicInfo(&r, v)
+	r.Consumed()
+	return codec.Error(&r)
+}
+
+func jsoncodecEncodeBasicInfo(out *jwriter.Writer, in BasicInfo) {
+	out.RawByte('{')
+	out.RawString("\"name\":")
+	out.String(in.Name)
+	out.RawString(",\"age\":")
+	out.Int(in.Age)
+	out.RawByte('}')
+}
+
+func jsoncodecDecodeBasicInfo(in *jlexer.Lexer, out *BasicInfo) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch jsoncodecFieldBasicInfo(key) {
+		case 0:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Name = in.String()
+			}
+		case 1:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Age = in.Int()
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+// jsoncodecFieldBasicInfo is the index of the field of the key, -1 for an unknown key
+func jsoncodecFieldBasicInfo(key string) int {
+	switch key {
+	case "name":
+		return 0
+	case "age":
+		return 1
+	}
+	switch {
+	case strings.EqualFold(key, "name"):
+		return 0
+	case strings.EqualFold(key, "age"):
+		return 1
+	}
+	return -1
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v JobInfo) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	jsoncodecEncodeJobInfo(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *JobInfo) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	jsoncodecDecodeJobInfo(&r, v)
+	r.Consumed()
+	return codec.Error(&r)
+}
+
+func jsoncodecEncodeJobInfo(out *jwriter.Writer, in JobInfo) {
+	out.RawByte('{')
+	out.RawString("\"skills\":")
+	if in.Skills == nil {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for i1, v1 := range in.Skills {
+			if i1 > 0 {
+				out.RawByte(',')
+			}
+			out.String(v1)
+		}
+		out.RawByte(']')
+	}
+	out.RawByte('}')
+}
+
+func jsoncodecDecodeJobInfo(in *jlexer.Lexer, out *JobInfo) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch jsoncodecFieldJobInfo(key) {
+		case 0:
+			if in.IsNull() {
+				in.Skip()
+				out.Skills = nil
+			} else {
+				in.Delim('[')
+				if out.Skills == nil {
+					if !in.IsDelim(']') {
+						out.Skills = make([]string, 0, 4)
+					} else {
+						out.Skills = []string{}
+					}
+				} else {
+					out.Skills = out.Skills[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v1 = in.String()
+					}
+					out.Skills = append(out.Skills, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+// jsoncodecFieldJobInfo is the index of the field of the key, -1 for an unknown key
+func jsoncodecFieldJobInfo(key string) int {
+	switch key {
+	case "skills":
+		return 0
+	}
+	switch {
+	case strings.EqualFold(key, "skills"):
+		return 0
+	}
+	return -1
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Employee) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	jsoncodecEncodeEmployee(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Employee) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	jsoncodecDecodeEmployee(&r, v)
+	r.Consumed()
+	return codec.Error(&r)
+}
+
+func jsoncodecEncodeEmployee(out *jwriter.Writer, in Employee) {
+	out.RawByte('{')
+	out.RawString("\"basic_info\":")
+	jsoncodecEncodeBasicInfo(out, in.BasicInfo)
+	out.RawString(",\"job_info\":")
+	jsoncodecEncodeJobInfo(out, in.JobInfo)
+	out.RawByte('}')
+}
+
+func jsoncodecDecodeEmployee(in *jlexer.Lexer, out *Employee) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch jsoncodecFieldEmployee(key) {
+		case 0:
+			jsoncodecDecodeBasicInfo(in, &out.BasicInfo)
+		case 1:
+			jsoncodecDecodeJobInfo(in, &out.JobInfo)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+// jsoncodecFieldEmployee is the index of the field of the key, -1 for an unknown key
+func jsoncodecFieldEmployee(key string) int {
+	switch key {
+	case "basic_info":
+		return 0
+	case "job_info":
+		return 1
+	}
+	switch {
+	case strings.EqualFold(key, "basic_info"):
+		return 0
+	case strings.EqualFold(key, "job_info"):
+		return 1
+	}
+	return -1
+}
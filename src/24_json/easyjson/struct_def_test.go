@@ -0,0 +1,37 @@
+package easyjson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go_learning/src/24_json/codegen"
+)
+
+func TestEmployeeJSON(t *testing.T) {
+	e := Employee{BasicInfo{Name: "Mike", Age: 30}, JobInfo{Skills: []string{"Java", "Go", "C"}}}
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"basic_info":{"name":"Mike","age":30},"job_info":{"skills":["Java","Go","C"]}}`
+	if string(data) != want {
+		t.Errorf("json = %s, want %s", data, want)
+	}
+	var got Employee
+	if err := got.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.BasicInfo != e.BasicInfo || len(got.JobInfo.Skills) != 3 {
+		t.Errorf("got %+v, want %+v", got, e)
+	}
+}
+
+func TestGeneratedCodeIsUpToDate(t *testing.T) {
+	issues, err := codegen.CheckDirectives(".")
+	for _, issue := range issues {
+		t.Log(issue)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
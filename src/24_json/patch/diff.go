@@ -0,0 +1,231 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+	Diff 比較兩份 JSON 文件的結構，列出每個不同的值：
+		a := Book2{Title: "Learning Go", Author: Author{Sales: 3, Age: 25, Developer: true}}
+		b := Book2{Title: "Learning Go", Author: Author{Sales: 4, Age: 26, Developer: true}}
+		changes, _ := patch.DiffValues(a, b)
+		fmt.Println(changes)
+		// ~ /author/age: 25 -> 26
+		// ~ /author/book_sales: 3 -> 4
+	* Go 的值先用 encoding/json 編碼再比較，路徑是 JSON 的名稱（book_sales 而不是 Sales），
+	  json:"-" 的欄位不比較，omitempty 的空值是不存在的 key
+	* 物件依 key 排序比較，數字比較數值
+	* 陣列依 index 比較，多出來的元素是 add，少掉的元素從最後一個開始 remove，
+	  所以 Changes.Patch() 依序套用就是正確的 JSON Patch
+	* 型別不同（物件變成數字）是整個值取代，不再往下比較
+	Change 的 String 是一行給人看的紀錄（audit log 用），值太長會截斷：
+		+ /tags/2: "go"
+		- /nickname: "Gopher"
+		~ /age: 25 -> 26
+*/
+
+// Kind of a change.
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Replaced
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return "replaced"
+}
+
+// Change is a value which differs between two documents.
+type Change struct {
+	Path Pointer
+	Kind Kind
+	// Old is the value of Removed and Replaced, New of Added and Replaced
+	Old, New interface{}
+}
+
+func (c Change) String() string {
+	path := display(c.Path.String())
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", path, truncate(encode(c.New)))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", path, truncate(encode(c.Old)))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", path, truncate(encode(c.Old)), truncate(encode(c.New)))
+}
+
+// Changes is the result of a diff.
+type Changes []Change
+
+// String is the changes one per line.
+func (c Changes) String() string {
+	lines := make([]string, len(c))
+	for i, change := range c {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Patch is the JSON patch which applies the changes in order.
+func (c Changes) Patch() Patch {
+	p := make(Patch, 0, len(c))
+	for _, change := range c {
+		o := Operation{Path: change.Path.String()}
+		switch change.Kind {
+		case Added:
+			o.Op, o.Value = "add", json.RawMessage(encode(change.New))
+		case Removed:
+			o.Op = "remove"
+		default:
+			o.Op, o.Value = "replace", json.RawMessage(encode(change.New))
+		}
+		p = append(p, o)
+	}
+	return p
+}
+
+// Diff compares two documents decoded by encoding/json.
+func Diff(a, b interface{}) Changes {
+	var changes Changes
+	diff(a, b, Pointer{}, &changes)
+	return changes
+}
+
+// DiffJSON compares two JSON documents.
+func DiffJSON(a, b []byte) (Changes, error) {
+	x, err := decode(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(x, y), nil
+}
+
+// DiffValues compares the JSON encodings of two Go values, so the json tags decide the names and the skipped fields.
+func DiffValues(a, b interface{}) (Changes, error) {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	return DiffJSON(x, y)
+}
+
+// CreatePatch is the JSON patch from the JSON document a to b.
+func CreatePatch(a, b []byte) (Patch, error) {
+	changes, err := DiffJSON(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return changes.Patch(), nil
+}
+
+func diff(a, b interface{}, path Pointer, changes *Changes) {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		if y, ok := b.(map[string]interface{}); ok {
+			diffObjects(x, y, path, changes)
+			return
+		}
+	case []interface{}:
+		if y, ok := b.([]interface{}); ok {
+			diffArrays(x, y, path, changes)
+			return
+		}
+	}
+	if !Equal(a, b) {
+		*changes = append(*changes, Change{Path: path, Kind: Replaced, Old: a, New: b})
+	}
+}
+
+func diffObjects(a, b map[string]interface{}, path Pointer, changes *Changes) {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		x, inA := a[k]
+		y, inB := b[k]
+		switch {
+		case !inB:
+			*changes = append(*changes, Change{Path: path.Append(k), Kind: Removed, Old: x})
+		case !inA:
+			*changes = append(*changes, Change{Path: path.Append(k), Kind: Added, New: y})
+		default:
+			diff(x, y, path.Append(k), changes)
+		}
+	}
+}
+
+func diffArrays(a, b []interface{}, path Pointer, changes *Changes) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		diff(a[i], b[i], path.Append(strconv.Itoa(i)), changes)
+	}
+	for i := n; i < len(b); i++ {
+		*changes = append(*changes, Change{Path: path.Append(strconv.Itoa(i)), Kind: Added, New: b[i]})
+	}
+	for i := len(a) - 1; i >= n; i-- {
+		*changes = append(*changes, Change{Path: path.Append(strconv.Itoa(i)), Kind: Removed, Old: a[i]})
+	}
+}
+
+// encode is the compact JSON of a decoded value
+func encode(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// the longest value of a change line
+const maxDisplay = 60
+
+// truncate shortens long values of the change lines
+func truncate(s string) string {
+	if len(s) <= maxDisplay {
+		return s
+	}
+	cut := maxDisplay
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// display is the pointer of a change line, the whole document is shown as (root)
+func display(pointer string) string {
+	if pointer == "" {
+		return "(root)"
+	}
+	return pointer
+}
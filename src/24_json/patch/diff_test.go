@@ -0,0 +1,110 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+// Book and Book2 are the structs of the examples of src/24_json/json
+type Book struct {
+	BookID        uint   `json:"-"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	Name          string `json:"name,omitempty"`
+	Age           uint   `json:",omitempty"`
+	Price         uint   `json:"_,"`
+	Configuration string `json:"configuration,string"`
+}
+
+type Book2 struct {
+	Title  string `json:"title"`
+	Author Author `json:"author"`
+}
+
+type Author struct {
+	Sales     int  `json:"book_sales"`
+	Age       int  `json:"age"`
+	Developer bool `json:"is_developer"`
+}
+
+func TestDiffValues(t *testing.T) {
+	a := Book{BookID: 1, Title: "Learning Go", Author: "Gopher", Price: 31900}
+	b := Book{BookID: 2, Title: "Learning Go", Author: "Gopher", Name: "second edition", Age: 3, Price: 35900}
+	changes, err := DiffValues(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// BookID is json:"-", Age is "Age" and appears only when it's not empty
+	want := `+ /Age: 3
+~ /_: 31900 -> 35900
++ /name: "second edition"`
+	if got := changes.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	book1 := Book2{Title: "Learning Go", Author: Author{Sales: 3, Age: 25, Developer: true}}
+	book2 := Book2{Title: "Learning Go", Author: Author{Sales: 4, Age: 26, Developer: true}}
+	changes, err = DiffValues(book1, book2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `~ /author/age: 25 -> 26
+~ /author/book_sales: 3 -> 4`
+	if got := changes.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if changes, _ := DiffValues(book1, book1); len(changes) != 0 {
+		t.Errorf("equal values differ: %s", changes)
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	a := `{"name":"Ann","age":25,"tags":["a","b","c","d"],"meta":{"x":1},"nick":"A/~"}`
+	b := `{"name":"Ann","age":25.0,"tags":["a","B"],"meta":[1],"new":{"deep":[true,null]},"nick~":"` + strings.Repeat("x", 100) + `"}`
+	changes, err := DiffJSON([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `~ /meta: {"x":1} -> [1]
++ /new: {"deep":[true,null]}
+- /nick: "A/~"
++ /nick~0: "` + strings.Repeat("x", 59) + `...
+~ /tags/1: "b" -> "B"
+- /tags/3: "d"
+- /tags/2: "c"`
+	if got := changes.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	root, _ := DiffJSON([]byte(`1`), []byte(`"1"`))
+	if got := root.String(); got != `~ (root): 1 -> "1"` {
+		t.Errorf("root change = %s", got)
+	}
+}
+
+// Apply(CreatePatch(a, b), a) is b
+func TestCreatePatch(t *testing.T) {
+	docs := []string{
+		`{}`, `[]`, `null`, `1`, `"s"`,
+		`{"a":1,"b":[1,2,3],"c":{"d":{"e":null}}}`,
+		`{"a":2,"b":[1],"c":{"d":[]},"f":"g"}`,
+		`{"b":[3,2,1,0,{"x":1}],"c":{"d":{"e":false,"f":[]}}}`,
+		`[[1,2],[3],{"a":[]}]`,
+		`[[1],[3,4,5]]`,
+		`{"a/b":{"m~n":[1,2]},"":0}`,
+	}
+	for _, a := range docs {
+		for _, b := range docs {
+			p, err := CreatePatch([]byte(a), []byte(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Apply([]byte(a))
+			if err != nil {
+				t.Fatalf("%s -> %s: %v\n%s", a, b, err, p)
+			}
+			if string(got) != compact(t, b) {
+				t.Errorf("%s -> %s: got %s with\n%s", a, b, got, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/*
+	Merge Patch（RFC 7386）是一份長得像結果的文件，只寫要改的部分：
+		文件  {"name": "Ann", "age": 25, "tags": ["a"], "author": {"age": 1, "sales": 3}}
+		patch {"age": 26, "tags": ["b"], "author": {"sales": null}}
+		結果  {"name": "Ann", "age": 26, "tags": ["b"], "author": {"age": 1}}
+	* 物件逐個 key 合併，null 是刪除那個 key
+	* 其他的值（包括陣列）整個取代，陣列無法只改一個元素
+	* patch 不是物件就是整份文件的新值
+	因為 null 代表刪除，Merge Patch 無法把一個值設成 null，
+	CreateMergePatch 遇到這種情況回傳 NullValueError，改用 JSON Patch。
+*/
+
+var NullValueError = errors.New("merge patch cannot set a value to null")
+
+// MergePatch applies the merge patch to the JSON document doc.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(MergeValue(target, p))
+}
+
+// MergeValue applies the merge patch to a document decoded by encoding/json, it returns a merged copy.
+func MergeValue(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return deepCopy(patch)
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	merged := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		merged[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = MergeValue(merged[k], v)
+		}
+	}
+	return deepCopy(merged)
+}
+
+// MergePatchTo applies the merge patch to the JSON encoding of the value pointed by v, like Patch.ApplyTo.
+func MergePatchTo(v interface{}, patch []byte) error {
+	return applyTo(v, func(doc []byte) ([]byte, error) {
+		return MergePatch(doc, patch)
+	})
+}
+
+// CreateMergePatch is the merge patch from the JSON document a to b.
+func CreateMergePatch(a, b []byte) ([]byte, error) {
+	x, err := decode(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	p, err := mergePatchOf(x, y, Pointer{})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(p)
+}
+
+// mergePatchOf is the merge patch from a to b, path is the pointer of b in the document
+func mergePatchOf(a, b interface{}, path Pointer) (interface{}, error) {
+	bm, ok := b.(map[string]interface{})
+	if !ok {
+		return b, nil
+	}
+	am, ok := a.(map[string]interface{})
+	if !ok {
+		// b replaces a, merged into an empty object
+		return bm, checkNoNull(bm, path)
+	}
+	p := map[string]interface{}{}
+	for k := range am {
+		if _, ok := bm[k]; !ok {
+			p[k] = nil
+		}
+	}
+	keys := make([]string, 0, len(bm))
+	for k := range bm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		old, ok := am[k]
+		if ok && Equal(old, bm[k]) {
+			continue
+		}
+		if bm[k] == nil {
+			return nil, fmt.Errorf("%w: %s", NullValueError, path.Append(k))
+		}
+		v, err := mergePatchOf(old, bm[k], path.Append(k))
+		if err != nil {
+			return nil, err
+		}
+		p[k] = v
+	}
+	return p, nil
+}
+
+// checkNoNull fails when an object of v has a null member, the merge would remove it
+func checkNoNull(v map[string]interface{}, path Pointer) error {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch e := v[k].(type) {
+		case nil:
+			return fmt.Errorf("%w: %s", NullValueError, path.Append(k))
+		case map[string]interface{}:
+			if err := checkNoNull(e, path.Append(k)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
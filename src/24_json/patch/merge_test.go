@@ -0,0 +1,88 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+)
+
+// the examples of the appendix A of RFC 7386
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("%s + %s: %v", tt.doc, tt.patch, err)
+		}
+		if string(got) != compact(t, tt.want) {
+			t.Errorf("%s + %s = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{`{"a":"b","c":{"d":1,"e":2}}`, `{"a":"b","c":{"d":1,"e":3,"f":[1]}}`, `{"c":{"e":3,"f":[1]}}`},
+		{`{"a":1,"b":2}`, `{"a":1}`, `{"b":null}`},
+		{`{"a":[1,2]}`, `{"a":[1,3]}`, `{"a":[1,3]}`},
+		{`{"a":1}`, `{"a":1.0}`, `{}`},
+		{`{"a":1}`, `[null]`, `[null]`},
+		{`{"a":1}`, `{"a":{"b":[null]}}`, `{"a":{"b":[null]}}`},
+	}
+	for _, tt := range tests {
+		p, err := CreateMergePatch([]byte(tt.a), []byte(tt.b))
+		if err != nil {
+			t.Fatalf("%s -> %s: %v", tt.a, tt.b, err)
+		}
+		if string(p) != compact(t, tt.want) {
+			t.Errorf("%s -> %s = %s, want %s", tt.a, tt.b, p, tt.want)
+		}
+		got, err := MergePatch([]byte(tt.a), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, _ := decode(got)
+		y, _ := decode([]byte(tt.b))
+		if !Equal(x, y) {
+			t.Errorf("%s + %s = %s, want %s", tt.a, p, got, tt.b)
+		}
+	}
+	for _, tt := range []struct{ a, b string }{
+		{`{"a":1}`, `{"a":null}`},
+		{`{}`, `{"a":{"b":null}}`},
+		{`[1]`, `{"a":null}`},
+	} {
+		if _, err := CreateMergePatch([]byte(tt.a), []byte(tt.b)); !errors.Is(err, NullValueError) {
+			t.Errorf("%s -> %s: %v, want NullValueError", tt.a, tt.b, err)
+		}
+	}
+}
+
+func TestMergePatchTo(t *testing.T) {
+	e := employee{ID: 1, Name: "Ann", Age: 25}
+	if err := MergePatchTo(&e, []byte(`{"age":26,"name":null}`)); err != nil {
+		t.Fatal(err)
+	}
+	if want := (employee{ID: 1, Age: 26}); e != want {
+		t.Errorf("employee = %+v, want %+v", e, want)
+	}
+}
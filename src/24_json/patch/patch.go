@@ -0,0 +1,406 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+/*
+	JSON Patch（RFC 6902）是一串依序執行的操作：
+		[
+		  {"op": "test",    "path": "/age", "value": 25},
+		  {"op": "replace", "path": "/age", "value": 26},
+		  {"op": "add",     "path": "/tags/-", "value": "go"},
+		  {"op": "remove",  "path": "/nickname"},
+		  {"op": "move",    "from": "/name", "path": "/full_name"},
+		  {"op": "copy",    "from": "/full_name", "path": "/display_name"}
+		]
+	* add：物件是新增或取代 key，陣列是插入到 index 之前（- 是加到結尾），父節點一定要存在
+	* remove、replace：path 一定要存在
+	* move：先 remove from 再 add 到 path，from 不能是 path 的上層（不能搬進自己裡面）
+	* test：值相等才繼續，數字比較數值（1 與 1.0 相等），物件不管 key 的順序
+	Apply 是全有或全無：先複製整份文件再套用，任何一個操作失敗都回傳錯誤，原本的文件不變。
+	數字用 json.Number 解碼，大整數與小數在套用後不會失去精度。
+
+	產生 Patch 用 CreatePatch(a, b)（見 diff.go），Apply(CreatePatch(a, b), a) 等於 b。
+	Go 的 struct 用 ApplyTo 套用：先用 encoding/json 編碼，套用後再解碼，json tag 的規則都一樣。
+*/
+
+var (
+	InvalidPatchError = errors.New("invalid json patch")
+	PathNotFoundError = errors.New("path not found")
+	TestFailedError   = errors.New("test failed")
+)
+
+// Operation is an operation of a JSON patch.
+type Operation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	// From is the source of move and copy
+	From string `json:"from,omitempty"`
+	// Value of add, replace and test, nil when missing and null for a JSON null
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func (o Operation) String() string {
+	switch o.Op {
+	case "move", "copy":
+		return fmt.Sprintf("%s %s -> %s", o.Op, display(o.From), display(o.Path))
+	case "remove":
+		return fmt.Sprintf("%s %s", o.Op, display(o.Path))
+	}
+	return fmt.Sprintf("%s %s: %s", o.Op, display(o.Path), truncate(string(o.Value)))
+}
+
+// OperationError is the error of the operation at Index of a patch.
+type OperationError struct {
+	Index int
+	Op    Operation
+	Err   error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d (%s): %v", e.Index, e.Op, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// Patch is a JSON patch, the operations are applied in order.
+type Patch []Operation
+
+// DecodePatch decodes a JSON patch document.
+func DecodePatch(data []byte) (Patch, error) {
+	var p Patch
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidPatchError, err)
+	}
+	return p, nil
+}
+
+// String is the operations one per line.
+func (p Patch) String() string {
+	lines := make([]string, len(p))
+	for i, o := range p {
+		lines[i] = o.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Apply applies the patch to the JSON document doc, doc is unchanged when an operation fails.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	v, err = p.ApplyValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// ApplyValue applies the patch to a document decoded by encoding/json, it returns a patched copy.
+func (p Patch) ApplyValue(doc interface{}) (interface{}, error) {
+	doc = deepCopy(doc)
+	for i, o := range p {
+		var err error
+		if doc, err = o.apply(doc); err != nil {
+			return nil, &OperationError{Index: i, Op: o, Err: err}
+		}
+	}
+	return doc, nil
+}
+
+// ApplyTo applies the patch to the JSON encoding of the value pointed by v.
+// The patched document is decoded into a new value, fields without JSON encoding are zero,
+// and members the type doesn't have are errors.
+func (p Patch) ApplyTo(v interface{}) error {
+	return applyTo(v, p.Apply)
+}
+
+// applyTo encodes the value pointed by v, patches it and decodes it back
+func applyTo(v interface{}, apply func([]byte) ([]byte, error)) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("patch: %T is not a non nil pointer", v)
+	}
+	doc, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	patched, err := apply(doc)
+	if err != nil {
+		return err
+	}
+	fresh := reflect.New(rv.Elem().Type())
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(fresh.Interface()); err != nil {
+		return fmt.Errorf("patched document: %w", err)
+	}
+	rv.Elem().Set(fresh.Elem())
+	return nil
+}
+
+func (o Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := ParsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case "add", "replace", "test":
+		if o.Value == nil {
+			return nil, fmt.Errorf("%w: %s without value", InvalidPatchError, o.Op)
+		}
+		value, err := decode(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch o.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		}
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !Equal(actual, value) {
+			return nil, fmt.Errorf("%w: %s is %s", TestFailedError, display(o.Path), truncate(encode(actual)))
+		}
+		return doc, nil
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := ParsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		if o.Op == "copy" {
+			value, err := get(doc, from)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, deepCopy(value))
+		}
+		if len(from) < len(path) && from.isPrefixOf(path) {
+			return nil, fmt.Errorf("%w: cannot move %s into itself", InvalidPatchError, display(o.From))
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", InvalidPatchError, o.Op)
+}
+
+// child is the value of the token in the container, path is the pointer of the value
+func child(container interface{}, token string, path Pointer) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if v, ok := c[token]; ok {
+			return v, nil
+		}
+	case []interface{}:
+		i, err := index(token, len(c))
+		if err != nil {
+			return nil, err
+		}
+		if i < len(c) {
+			return c[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", PathNotFoundError, display(path.String()))
+}
+
+func get(doc interface{}, path Pointer) (interface{}, error) {
+	for i, token := range path {
+		var err error
+		if doc, err = child(doc, token, path[:i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// modify calls fn with the container of the last token of path, the container fn returns replaces the old one
+func modify(doc interface{}, path Pointer, depth int, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	token := path[depth]
+	if depth == len(path)-1 {
+		return fn(doc, token)
+	}
+	v, err := child(doc, token, path[:depth+1])
+	if err != nil {
+		return nil, err
+	}
+	if v, err = modify(v, path, depth+1, fn); err != nil {
+		return nil, err
+	}
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		c[token] = v
+	case []interface{}:
+		i, _ := index(token, len(c))
+		c[i] = v
+	}
+	return doc, nil
+}
+
+func add(doc interface{}, path Pointer, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modify(doc, path, 0, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			i, err := index(token, len(c))
+			if err != nil {
+				return nil, err
+			}
+			if i > len(c) {
+				return nil, fmt.Errorf("%w: %s is after the end of the array", PathNotFoundError, display(path.String()))
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("%w: %s, the parent is not an object or array", PathNotFoundError, display(path.String()))
+	})
+}
+
+func replace(doc interface{}, path Pointer, value interface{}) (interface{}, error) {
+	if _, err := get(doc, path); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modify(doc, path, 0, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+		case []interface{}:
+			i, _ := index(token, len(c))
+			c[i] = value
+		}
+		return container, nil
+	})
+}
+
+// remove returns the document without the value at path, and the value
+func remove(doc interface{}, path Pointer) (interface{}, interface{}, error) {
+	value, err := get(doc, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", InvalidPatchError)
+	}
+	doc, err = modify(doc, path, 0, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			delete(c, token)
+		case []interface{}:
+			i, _ := index(token, len(c))
+			return append(c[:i], c[i+1:]...), nil
+		}
+		return container, nil
+	})
+	return doc, value, err
+}
+
+// decode decodes a single JSON value, the numbers are json.Number
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid character after the json value")
+	}
+	return v, nil
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = deepCopy(e)
+		}
+		return s
+	}
+	return v
+}
+
+// Equal compares two documents decoded by encoding/json, numbers are compared by value.
+func Equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !Equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !Equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x.Cmp(y) == 0
+	}
+	return a == b
+}
+
+// number is the exact value of a json.Number or float64
+func number(v interface{}) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(string(v))
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(v) == nil {
+			return nil, false
+		}
+		return r, true
+	}
+	return nil, false
+}
@@ -0,0 +1,151 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// compact reformats a JSON document so documents can be compared as strings
+func compact(t *testing.T, doc string) string {
+	t.Helper()
+	v, err := decode([]byte(doc))
+	if err != nil {
+		t.Fatalf("%s: %v", doc, err)
+	}
+	return encode(v)
+}
+
+// the examples of the appendix A of RFC 6902
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move member", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"test", `{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"add nested", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`},
+		{"ignore unknown members", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add to array", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"escaped", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"test numbers by value", `{"n":1}`, `[{"op":"test","path":"/n","value":1.0}]`, `{"n":1}`},
+		{"add null", `{}`, `[{"op":"add","path":"/n","value":null}]`, `{"n":null}`},
+		{"replace root", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/d","value":2}]`, `{"a":{"b":1},"c":{"b":1,"d":2}}`},
+		{"big numbers", `{"n":12345678901234567890}`, `[{"op":"copy","from":"/n","path":"/m"}]`, `{"m":12345678901234567890,"n":12345678901234567890}`},
+	}
+	for _, tt := range tests {
+		p, err := DecodePatch([]byte(tt.patch))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := p.Apply([]byte(tt.doc))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != compact(t, tt.want) {
+			t.Errorf("%s: %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, patch string
+		want             error
+	}{
+		{"test failed", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, TestFailedError},
+		{"test string and number", `{"n":"1"}`, `[{"op":"test","path":"/n","value":1}]`, TestFailedError},
+		{"missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, PathNotFoundError},
+		{"index out of bounds", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":"qux"}]`, PathNotFoundError},
+		{"remove missing", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, PathNotFoundError},
+		{"replace missing", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, PathNotFoundError},
+		{"leading zero", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`, InvalidPointerError},
+		{"not a pointer", `{}`, `[{"op":"add","path":"foo","value":1}]`, InvalidPointerError},
+		{"move into itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, InvalidPatchError},
+		{"unknown op", `{}`, `[{"op":"spam","path":"/a"}]`, InvalidPatchError},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, InvalidPatchError},
+		{"remove root", `{}`, `[{"op":"remove","path":""}]`, InvalidPatchError},
+	}
+	for _, tt := range tests {
+		p, err := DecodePatch([]byte(tt.patch))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		_, err = p.Apply([]byte(tt.doc))
+		var opErr *OperationError
+		if !errors.Is(err, tt.want) || !errors.As(err, &opErr) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if _, err := DecodePatch([]byte(`{"op":"add"}`)); !errors.Is(err, InvalidPatchError) {
+		t.Errorf("DecodePatch of an object = %v, want InvalidPatchError", err)
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	doc := map[string]interface{}{"a": json.Number("1"), "list": []interface{}{"x"}}
+	p := Patch{
+		{Op: "replace", Path: "/a", Value: json.RawMessage(`2`)},
+		{Op: "add", Path: "/list/0", Value: json.RawMessage(`"y"`)},
+		{Op: "test", Path: "/a", Value: json.RawMessage(`3`)},
+	}
+	_, err := p.ApplyValue(doc)
+	var opErr *OperationError
+	if !errors.As(err, &opErr) || opErr.Index != 2 {
+		t.Fatalf("err = %v, want the error of the operation 2", err)
+	}
+	if got := encode(doc); got != `{"a":1,"list":["x"]}` {
+		t.Errorf("doc = %s, the failed patch changed it", got)
+	}
+}
+
+type employee struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age,omitempty"`
+	note string
+}
+
+func TestApplyTo(t *testing.T) {
+	e := employee{ID: 1, Name: "Ann", Age: 25, note: "lost"}
+	p, _ := DecodePatch([]byte(`[{"op":"test","path":"/age","value":25},{"op":"replace","path":"/age","value":26},{"op":"remove","path":"/name"}]`))
+	if err := p.ApplyTo(&e); err != nil {
+		t.Fatal(err)
+	}
+	if want := (employee{ID: 1, Age: 26}); e != want {
+		t.Errorf("employee = %+v, want %+v", e, want)
+	}
+	p, _ = DecodePatch([]byte(`[{"op":"add","path":"/salary","value":1}]`))
+	if err := p.ApplyTo(&e); err == nil {
+		t.Error("adding a member the struct doesn't have succeeded")
+	}
+	if err := p.ApplyTo(e); err == nil {
+		t.Error("ApplyTo of a non pointer succeeded")
+	}
+}
+
+func TestOperationString(t *testing.T) {
+	p, _ := DecodePatch([]byte(`[
+		{"op":"add","path":"/tags/-","value":"go"},
+		{"op":"remove","path":"/name"},
+		{"op":"move","from":"/a","path":"/b"},
+		{"op":"replace","path":"","value":{}}
+	]`))
+	want := `add /tags/-: "go"
+remove /name
+move /a -> /b
+replace (root): {}`
+	if got := p.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
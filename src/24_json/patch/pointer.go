@@ -0,0 +1,94 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+	JSON Pointer（RFC 6901）指到文件裡的一個值，Patch 與 Diff 都用它表示位置：
+		""             整份文件
+		/author/age    {"author": {"age": 25}} 的 25
+		/tags/0        陣列的第一個元素，/tags/- 是陣列的結尾（只有 add 可以用）
+		/a~1b/m~0n     key 是 "a/b" 與 "m~n"，/ 寫成 ~1，~ 寫成 ~0
+	陣列的 index 只能是數字，不能有多餘的 0（/tags/01 是錯的）。
+*/
+
+var InvalidPointerError = errors.New("invalid json pointer")
+
+// Pointer is a JSON pointer, the reference tokens are unescaped.
+type Pointer []string
+
+var escaper = strings.NewReplacer("~", "~0", "/", "~1")
+var unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// ParsePointer parses a pointer like /author/age, the empty string is the whole document.
+func ParsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("%w: %q doesn't start with /", InvalidPointerError, s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || token[j+1] != '0' && token[j+1] != '1') {
+				return nil, fmt.Errorf("%w: %q has a ~ not followed by 0 or 1", InvalidPointerError, s)
+			}
+		}
+		tokens[i] = unescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+func (p Pointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteByte('/')
+		b.WriteString(escaper.Replace(token))
+	}
+	return b.String()
+}
+
+// Append returns a new pointer with the token added, p is unchanged.
+func (p Pointer) Append(token string) Pointer {
+	q := make(Pointer, len(p), len(p)+1)
+	copy(q, p)
+	return append(q, token)
+}
+
+// isPrefixOf is true when q is inside the value of p, or equals p
+func (p Pointer) isPrefixOf(q Pointer) bool {
+	if len(p) > len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// index parses the array index token of an array of n elements, "-" is n
+func index(token string, n int) (int, error) {
+	if token == "-" {
+		return n, nil
+	}
+	if token == "" || len(token) > 1 && token[0] == '0' {
+		return 0, fmt.Errorf("%w: invalid array index %q", InvalidPointerError, token)
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("%w: invalid array index %q", InvalidPointerError, token)
+		}
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid array index %q", InvalidPointerError, token)
+	}
+	return i, nil
+}
@@ -0,0 +1,48 @@
+package patch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Pointer
+	}{
+		{"", Pointer{}},
+		{"/", Pointer{""}},
+		{"/foo/0", Pointer{"foo", "0"}},
+		{"/a~1b", Pointer{"a/b"}},
+		{"/m~0n", Pointer{"m~n"}},
+		{"/~01", Pointer{"~1"}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePointer(tt.s)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePointer(%q) = %q, %v, want %q", tt.s, got, err, tt.want)
+		}
+		if got.String() != tt.s {
+			t.Errorf("%q.String() = %q", got, got.String())
+		}
+	}
+	for _, s := range []string{"foo", "/a~", "/a~2"} {
+		if _, err := ParsePointer(s); !errors.Is(err, InvalidPointerError) {
+			t.Errorf("ParsePointer(%q) = %v, want InvalidPointerError", s, err)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	for token, want := range map[string]int{"0": 0, "12": 12, "-": 3} {
+		if i, err := index(token, 3); err != nil || i != want {
+			t.Errorf("index(%q) = %d, %v, want %d", token, i, err, want)
+		}
+	}
+	for _, token := range []string{"", "01", "-1", "1a", "+1", "99999999999999999999"} {
+		if _, err := index(token, 3); !errors.Is(err, InvalidPointerError) {
+			t.Errorf("index(%q) = %v, want InvalidPointerError", token, err)
+		}
+	}
+}
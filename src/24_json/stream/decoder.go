@@ -0,0 +1,100 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+/*
+	Decoder 把 Scanner 讀出的每個值用 json.Unmarshal 轉成 T：
+		d := stream.NewDecoder[SensorReading](file, stream.Config{})
+		var reading SensorReading
+		for {
+			err := d.Next(&reading)
+			if err == io.EOF {
+				break
+			}
+			var decodeErr *stream.DecodeError
+			if errors.As(err, &decodeErr) && !decodeErr.Fatal {
+				log.Print(err) // line 3, column 25 (offset 120): value 2: json: cannot unmarshal string ...
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			...
+		}
+	json.Unmarshal 的錯誤是相對於這個值的 offset，換算成整個輸入的 line:column。
+	*T 有 UnmarshalJSON（例如 jsoncodec 產生的）就直接呼叫它，不經過 json.Unmarshal 的反射與語法檢查，
+	語法錯誤由 UnmarshalJSON 自己回報；它的錯誤沒有 offset，位置是這個值的開頭。
+*/
+
+// Decoder decodes the values of an array or NDJSON into T one by one.
+type Decoder[T any] struct {
+	s *Scanner
+}
+
+func NewDecoder[T any](r io.Reader, c Config) *Decoder[T] {
+	return &Decoder[T]{s: NewScanner(r, c)}
+}
+
+// Next decodes the next value into v, v is reset first. The end of the input is io.EOF.
+// The errors are *DecodeError, after an error which is not Fatal Next continues with the next value.
+func (d *Decoder[T]) Next(v *T) error {
+	data, err := d.s.Next()
+	if err != nil {
+		return err
+	}
+	var zero T
+	*v = zero
+	if u, ok := interface{}(v).(json.Unmarshaler); ok {
+		err = u.UnmarshalJSON(data)
+	} else {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return &DecodeError{Index: d.s.Index(), Pos: d.s.PosAt(errorOffset(err)), Err: err}
+	}
+	return nil
+}
+
+// Pos is the position of the last value.
+func (d *Decoder[T]) Pos() Position {
+	return d.s.Pos()
+}
+
+// errorOffset is the offset of the error in the value, the byte where json.Unmarshal stops
+func errorOffset(err error) int {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	offset := int64(0)
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset - 1
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset - 1
+	}
+	if offset < 0 {
+		return 0
+	}
+	return int(offset)
+}
+
+// Each calls fn with each value of the input until the end, an error or an error of fn.
+func Each[T any](r io.Reader, c Config, fn func(v T) error) error {
+	d := NewDecoder[T](r, c)
+	for {
+		var v T
+		err := d.Next(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
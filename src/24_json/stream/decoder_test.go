@@ -0,0 +1,213 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SensorReading struct {
+	Name     string    `json:"name"`
+	Capacity int       `json:"capacity"`
+	Time     time.Time `json:"time"`
+}
+
+func readings(n int) []SensorReading {
+	rs := make([]SensorReading, n)
+	for i := range rs {
+		rs[i] = SensorReading{Name: fmt.Sprintf("sensor %d", i), Capacity: i % 100, Time: time.Date(2019, 1, 21, 19, 7, i%60, 0, time.UTC)}
+	}
+	return rs
+}
+
+func TestDecoder(t *testing.T) {
+	want := readings(100)
+	array, _ := json.MarshalIndent(want, "", "  ")
+	var ndjson bytes.Buffer
+	for _, r := range want {
+		data, _ := json.Marshal(r)
+		ndjson.Write(data)
+		ndjson.WriteByte('\n')
+	}
+	for name, input := range map[string][]byte{"array": array, "ndjson": ndjson.Bytes()} {
+		var got []SensorReading
+		err := Each(bytes.NewReader(input), Config{BufferSize: 64}, func(r SensorReading) error {
+			got = append(got, r)
+			return nil
+		})
+		if err != nil || len(got) != len(want) {
+			t.Fatalf("%s: %d readings, %v", name, len(got), err)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s: reading %d = %+v, want %+v", name, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	input := `[
+  {"name": "a", "capacity": 1, "time": "2019-01-21T19:07:28Z"},
+  {"name": "b",
+   "capacity": "full", "time": "2019-01-21T19:07:28Z"},
+  {"name": "c", "capacity": 3, "time": "yesterday"},
+  {"name": "d" "capacity": 4},
+  {"name": "e", "capacity": 5}
+  {"name": "f"}
+]`
+	d := NewDecoder[SensorReading](strings.NewReader(input), Config{})
+	var names []string
+	var errs []*DecodeError
+	for {
+		var r SensorReading
+		err := d.Next(&r)
+		if err == io.EOF {
+			break
+		}
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) && err != nil {
+			t.Fatal(err)
+		}
+		if err != nil {
+			errs = append(errs, decodeErr)
+			if decodeErr.Fatal {
+				break
+			}
+			continue
+		}
+		names = append(names, r.Name)
+	}
+	if strings.Join(names, ",") != "a,e" || len(errs) != 4 {
+		t.Fatalf("names %v, errors %v", names, errs)
+	}
+	want := []struct {
+		index int
+		pos   Position
+		fatal bool
+	}{
+		// the end of "full", the value of the time error, the quote after "d"
+		{1, Position{102, 4, 21}, false},
+		{2, Position{140, 5, 3}, false},
+		{3, Position{206, 6, 16}, false},
+		// the missing comma ends the array
+		{5, Position{255, 8, 3}, true},
+	}
+	for i, w := range want {
+		e := errs[i]
+		if e.Index != w.index || e.Pos != w.pos || e.Fatal != w.fatal {
+			t.Errorf("error %d: %v (fatal %v), want value %d at %s", i, e, e.Fatal, w.index, w.pos)
+		}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(errs[0], &typeErr) || typeErr.Field != "capacity" {
+		t.Errorf("err = %v, want the type error of capacity", errs[0])
+	}
+}
+
+// celsius is decoded by its UnmarshalJSON, like the types of jsoncodec
+type celsius struct {
+	degrees int
+	calls   int
+}
+
+var NotCelsiusError = errors.New("not in celsius")
+
+func (c *celsius) UnmarshalJSON(data []byte) error {
+	c.calls++
+	s := string(data)
+	if !strings.HasSuffix(s, `C"`) {
+		return NotCelsiusError
+	}
+	_, err := fmt.Sscanf(s, `"%dC"`, &c.degrees)
+	return err
+}
+
+func TestDecoderUnmarshaler(t *testing.T) {
+	d := NewDecoder[celsius](strings.NewReader(`"21C"`+"\n"+`"70F"`+"\n"+`"-3C"`), Config{})
+	var got []int
+	for {
+		var c celsius
+		err := d.Next(&c)
+		if err == io.EOF {
+			break
+		}
+		if c.calls != 1 {
+			t.Fatalf("UnmarshalJSON called %d times", c.calls)
+		}
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			if !errors.Is(err, NotCelsiusError) || decodeErr.Index != 1 || decodeErr.Pos != (Position{6, 2, 1}) {
+				t.Errorf("unexpected error %v at %s", err, decodeErr.Pos)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, c.degrees)
+	}
+	if fmt.Sprint(got) != "[21 -3]" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestDecoderNDJSONErrors(t *testing.T) {
+	input := "{\"name\":\"a\"}\n{\"name\":1}\nnot json\n\n{\"name\":\"b\"}\n"
+	d := NewDecoder[SensorReading](strings.NewReader(input), Config{Format: NDJSON})
+	var lines []int
+	var names []string
+	for {
+		var r SensorReading
+		err := d.Next(&r)
+		if err == io.EOF {
+			break
+		}
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			if decodeErr.Fatal {
+				t.Fatal(err)
+			}
+			lines = append(lines, decodeErr.Pos.Line)
+			continue
+		}
+		names = append(names, r.Name)
+	}
+	if fmt.Sprint(lines) != "[2 3]" || strings.Join(names, ",") != "a,b" {
+		t.Errorf("error lines %v, names %v", lines, names)
+	}
+
+	stop := errors.New("stop")
+	if err := Each(strings.NewReader(input), Config{}, func(SensorReading) error { return stop }); err != stop {
+		t.Errorf("err = %v, want the error of fn", err)
+	}
+}
+
+func BenchmarkDecodeArray(b *testing.B) {
+	data, _ := json.Marshal(readings(10000))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		err := Each(bytes.NewReader(data), Config{Format: Array}, func(SensorReading) error { return nil })
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalArray(b *testing.B) {
+	data, _ := json.Marshal(readings(10000))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var rs []SensorReading
+		if err := json.Unmarshal(data, &rs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*
+	json.Unmarshal 要先把整份文件讀進記憶體，幾 GB 的 sensor reading 陣列放不下：
+		[{"name":"battery sensor","capacity":40,"time":"2019-01-21T19:07:28Z"}, ...]
+	Scanner 一次只讀出一個元素（或 NDJSON 的一行），記憶體只跟最大的元素有關，與輸入的大小無關：
+	* 從 io.Reader 讀進 buffer，已經處理過的部分丟掉，剩下的搬到 buffer 開頭
+	* 元素的邊界只靠括號的深度與字串找出來，內容交給 json.Unmarshal 檢查
+	* 元素超過 MaxValueSize 是 ValueTooLargeError，buffer 最多是 MaxValueSize 的兩倍
+	* 讀過的位元組邊走邊數換行，錯誤的位置是 offset 與 line:column
+
+	兩種輸入格式：
+		Array：[ {...}, {...} ]，逗號、括號的錯誤無法回復，之後的 Next 都回傳同一個錯誤
+		NDJSON：一行一個值，空白行略過，每一行的錯誤都可以略過，繼續讀下一行
+		Auto：第一個字元是 [ 就是 Array，否則是 NDJSON
+*/
+
+var (
+	ValueTooLargeError = errors.New("json value too large")
+	MalformedError     = errors.New("malformed json")
+)
+
+// Format of the input.
+type Format int
+
+const (
+	// Auto is Array when the input starts with [, else NDJSON
+	Auto Format = iota
+	// Array is a JSON array, each element is a value
+	Array
+	// NDJSON is a value per line, newline delimited JSON
+	NDJSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case Array:
+		return "array"
+	case NDJSON:
+		return "ndjson"
+	}
+	return "auto"
+}
+
+type Config struct {
+	Format Format
+	// MaxValueSize is the largest value in bytes, 16MB by default
+	MaxValueSize int
+	// BufferSize is the size of each read, 64KB by default
+	BufferSize int
+}
+
+// Position of a byte in the input.
+type Position struct {
+	// Offset of the byte from 0
+	Offset int64
+	// Line and Column from 1, the column counts the bytes
+	Line, Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d (offset %d)", p.Line, p.Column, p.Offset)
+}
+
+// DecodeError is the error of a value of the input.
+type DecodeError struct {
+	// Index of the value from 0
+	Index int
+	Pos   Position
+	// Fatal errors end the input, the value after the others can still be read
+	Fatal bool
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s: value %d: %v", e.Pos, e.Index, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// states of an array
+const (
+	beforeArray = iota
+	firstElement
+	nextElement
+	afterArray
+)
+
+// Scanner reads the values of an array or NDJSON one by one.
+type Scanner struct {
+	rd     io.Reader
+	format Format
+	max    int
+	size   int
+
+	// buf[r:w] is the input not consumed, base is the offset of buf[0]
+	buf  []byte
+	r, w int
+	base int64
+	// line of buf[r] and the offset its line starts
+	line      int
+	lineStart int64
+	readErr   error
+
+	state int
+	index int
+	// last value and its position
+	last []byte
+	pos  Position
+	// err is the fatal error
+	err error
+}
+
+func NewScanner(r io.Reader, c Config) *Scanner {
+	if c.MaxValueSize <= 0 {
+		c.MaxValueSize = 16 << 20
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = 64 << 10
+	}
+	return &Scanner{rd: r, format: c.Format, max: c.MaxValueSize, size: c.BufferSize, line: 1}
+}
+
+// Pos is the position of the last value.
+func (s *Scanner) Pos() Position {
+	return s.pos
+}
+
+// Index of the last value from 0.
+func (s *Scanner) Index() int {
+	return s.index - 1
+}
+
+// fill reads more input, keeping buf[r:w], io.EOF at the end of the input
+func (s *Scanner) fill() error {
+	if s.r > 0 {
+		n := copy(s.buf, s.buf[s.r:s.w])
+		s.base += int64(s.r)
+		s.r, s.w = 0, n
+	}
+	for {
+		if s.readErr != nil {
+			return s.readErr
+		}
+		if s.w == len(s.buf) || len(s.buf)-s.w < s.size/2 {
+			// the value doesn't fit, the size of buf is bounded by the checks of MaxValueSize
+			size := 2 * len(s.buf)
+			if size < s.size {
+				size = s.size
+			}
+			buf := make([]byte, size)
+			copy(buf, s.buf[:s.w])
+			s.buf = buf
+		}
+		n, err := s.rd.Read(s.buf[s.w:])
+		s.w += n
+		s.readErr = err
+		if n > 0 {
+			return nil
+		}
+	}
+}
+
+// advance consumes n bytes, counting the lines
+func (s *Scanner) advance(n int) {
+	for i, c := range s.buf[s.r : s.r+n] {
+		if c == '\n' {
+			s.line++
+			s.lineStart = s.base + int64(s.r+i) + 1
+		}
+	}
+	s.r += n
+}
+
+// position of the byte at the index i of buf
+func (s *Scanner) position(i int) Position {
+	offset := s.base + int64(i)
+	line, lineStart := s.line, s.lineStart
+	for j := s.r; j < i; j++ {
+		if s.buf[j] == '\n' {
+			line++
+			lineStart = s.base + int64(j) + 1
+		}
+	}
+	return Position{Offset: offset, Line: line, Column: int(offset-lineStart) + 1}
+}
+
+// PosAt is the position of the byte at the offset in the last value, the errors of its decoding use it.
+func (s *Scanner) PosAt(offset int) Position {
+	p := s.pos
+	if offset > len(s.last) {
+		offset = len(s.last)
+	}
+	for _, c := range s.last[:offset] {
+		p.Offset++
+		if c == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
+
+// skipSpace consumes the white spaces, false at the end of the input
+func (s *Scanner) skipSpace() (bool, error) {
+	for {
+		for s.r < s.w {
+			c := s.buf[s.r]
+			if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				return true, nil
+			}
+			s.advance(1)
+		}
+		if err := s.fill(); err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+}
+
+// fatal ends the input with the error at the byte i of buf
+func (s *Scanner) fatal(i int, err error) error {
+	s.err = &DecodeError{Index: s.index, Pos: s.position(i), Fatal: true, Err: err}
+	return s.err
+}
+
+// Next returns the next value, the bytes are valid until the next call. The end of the input is io.EOF.
+func (s *Scanner) Next() ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.format == NDJSON {
+		return s.nextLine()
+	}
+	for {
+		ok, err := s.skipSpace()
+		if err != nil {
+			return nil, s.fatal(s.r, err)
+		}
+		if s.format == Auto {
+			s.format = NDJSON
+			if ok && s.buf[s.r] == '[' {
+				s.format = Array
+			} else {
+				return s.nextLine()
+			}
+		}
+		if !ok {
+			if s.state == afterArray {
+				s.err = io.EOF
+				return nil, io.EOF
+			}
+			return nil, s.fatal(s.r, io.ErrUnexpectedEOF)
+		}
+		c := s.buf[s.r]
+		switch s.state {
+		case beforeArray:
+			if c != '[' {
+				return nil, s.fatal(s.r, fmt.Errorf("%w: invalid character %q, want [", MalformedError, c))
+			}
+			s.advance(1)
+			s.state = firstElement
+			continue
+		case firstElement:
+			if c == ']' {
+				s.advance(1)
+				s.state = afterArray
+				continue
+			}
+		case nextElement:
+			switch c {
+			case ']':
+				s.advance(1)
+				s.state = afterArray
+				continue
+			case ',':
+				s.advance(1)
+				if ok, err := s.skipSpace(); err != nil {
+					return nil, s.fatal(s.r, err)
+				} else if !ok {
+					return nil, s.fatal(s.r, io.ErrUnexpectedEOF)
+				}
+			default:
+				return nil, s.fatal(s.r, fmt.Errorf("%w: invalid character %q after an element, want , or ]", MalformedError, c))
+			}
+		case afterArray:
+			return nil, s.fatal(s.r, fmt.Errorf("%w: invalid character %q after the array", MalformedError, c))
+		}
+		n, err := s.scanValue()
+		if err != nil {
+			return nil, s.fatal(s.r+n, err)
+		}
+		s.state = nextElement
+		return s.value(n), nil
+	}
+}
+
+// value consumes the next n bytes as a value
+func (s *Scanner) value(n int) []byte {
+	s.pos = s.position(s.r)
+	s.index++
+	s.last = s.buf[s.r : s.r+n]
+	s.advance(n)
+	return s.last
+}
+
+// scanValue finds the end of the value at buf[r], an object, an array, a string or a literal like a number
+func (s *Scanner) scanValue() (int, error) {
+	first := s.buf[s.r]
+	switch first {
+	case ',', ':', ']', '}':
+		return 0, fmt.Errorf("%w: invalid character %q, want a value", MalformedError, first)
+	}
+	depth, inString, escaped := 0, false, false
+	for n := 0; ; n++ {
+		if n > s.max {
+			return 0, ValueTooLargeError
+		}
+		if s.r+n == s.w {
+			err := s.fill()
+			if err == io.EOF && !inString && depth == 0 && first != '"' {
+				// a literal ends at the end of the input
+				return n, nil
+			}
+			if err == io.EOF {
+				return n, io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return n, err
+			}
+		}
+		c := s.buf[s.r+n]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if depth == 0 {
+					return n + 1, nil
+				}
+			}
+		case c == '"':
+			if n > 0 && first != '{' && first != '[' {
+				// a literal followed by a string
+				return n, nil
+			}
+			inString = true
+		case c == '{' || c == '[':
+			if n > 0 && depth == 0 {
+				return n, nil
+			}
+			depth++
+		case c == '}' || c == ']':
+			if depth == 0 {
+				return n, nil
+			}
+			depth--
+			if depth == 0 {
+				return n + 1, nil
+			}
+		case depth == 0 && (c == ',' || c == ' ' || c == '\t' || c == '\r' || c == '\n'):
+			return n, nil
+		}
+	}
+}
+
+// nextLine returns the next non blank line of NDJSON, an error of a line doesn't end the input
+func (s *Scanner) nextLine() ([]byte, error) {
+	ok, err := s.skipSpace()
+	if err != nil {
+		return nil, s.fatal(s.r, err)
+	}
+	if !ok {
+		s.err = io.EOF
+		return nil, io.EOF
+	}
+	for n := 0; ; n++ {
+		if n > s.max {
+			err := &DecodeError{Index: s.index, Pos: s.position(s.r), Err: ValueTooLargeError}
+			s.index++
+			return nil, s.skipLine(err)
+		}
+		if s.r+n == s.w {
+			if err := s.fill(); err == io.EOF {
+				return s.trimmed(n), nil
+			} else if err != nil {
+				return nil, s.fatal(s.r+n, err)
+			}
+		}
+		if s.buf[s.r+n] == '\n' {
+			return s.trimmed(n), nil
+		}
+	}
+}
+
+// trimmed consumes the line of n bytes, the value is the line without the white spaces at the end
+func (s *Scanner) trimmed(n int) []byte {
+	end := n
+	for end > 0 && (s.buf[s.r+end-1] == ' ' || s.buf[s.r+end-1] == '\t' || s.buf[s.r+end-1] == '\r') {
+		end--
+	}
+	v := s.value(end)
+	s.advance(n - end)
+	return v
+}
+
+// skipLine consumes the input until the next line, returning err
+func (s *Scanner) skipLine(err error) error {
+	for {
+		for i := s.r; i < s.w; i++ {
+			if s.buf[i] == '\n' {
+				s.advance(i - s.r)
+				return err
+			}
+		}
+		s.advance(s.w - s.r)
+		if fillErr := s.fill(); fillErr == io.EOF {
+			return err
+		} else if fillErr != nil {
+			return s.fatal(s.r, fillErr)
+		}
+	}
+}
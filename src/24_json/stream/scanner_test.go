@@ -0,0 +1,195 @@
+package stream
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// values reads all the values of the input, stopping at the first fatal error
+func values(t *testing.T, r io.Reader, c Config) ([]string, []error) {
+	t.Helper()
+	s := NewScanner(r, c)
+	var got []string
+	var errs []error
+	for {
+		v, err := s.Next()
+		if err == io.EOF {
+			return got, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) || decodeErr.Fatal {
+				return got, errs
+			}
+			continue
+		}
+		got = append(got, string(v))
+	}
+}
+
+func TestScanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   []string
+	}{
+		{"array", Array, `[{"a":1},{"b":[1,2,{"c":"]}"}]},"s\"]",1.5e3,true,null,[]]`,
+			[]string{`{"a":1}`, `{"b":[1,2,{"c":"]}"}]}`, `"s\"]"`, `1.5e3`, `true`, `null`, `[]`}},
+		{"spaces", Array, " \n[ \r\n\t{ \"a\" : 1 } ,\n -2 \n]\n ", []string{`{ "a" : 1 }`, `-2`}},
+		{"empty array", Array, ` [ ] `, nil},
+		{"auto array", Auto, "\n[1,2]", []string{"1", "2"}},
+		{"ndjson", NDJSON, "{\"a\":1}\n\n  {\"b\":2}  \r\n[1,2]\n\"x\"", []string{`{"a":1}`, `{"b":2}`, `[1,2]`, `"x"`}},
+		{"auto ndjson", Auto, "{\"a\":1}\n{\"b\":2}\n", []string{`{"a":1}`, `{"b":2}`}},
+		{"empty", Auto, "", nil},
+		{"blank", NDJSON, " \n\n ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the values are the same whatever the reads are split
+			for _, size := range []int{1, 3, 16, 0} {
+				for _, oneByte := range []bool{false, true} {
+					var r io.Reader = strings.NewReader(tt.input)
+					if oneByte {
+						r = iotest.OneByteReader(r)
+					}
+					got, errs := values(t, r, Config{Format: tt.format, BufferSize: size})
+					if len(errs) > 0 || strings.Join(got, "|") != strings.Join(tt.want, "|") {
+						t.Fatalf("buffer %d: got %q, %v, want %q", size, got, errs, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestScannerFatalErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   error
+		pos   Position
+		// the values before the error
+		values int
+	}{
+		{"not an array", `{"a":1}`, MalformedError, Position{0, 1, 1}, 0},
+		{"missing comma", "[1,\n 2 3]", MalformedError, Position{7, 2, 4}, 2},
+		{"trailing comma", "[1,2,]", MalformedError, Position{5, 1, 6}, 2},
+		{"leading comma", "[,1]", MalformedError, Position{1, 1, 2}, 0},
+		{"data after", "[1]\n[2]", MalformedError, Position{4, 2, 1}, 1},
+		{"truncated", "[1,\n{\"a\":[1,", io.ErrUnexpectedEOF, Position{12, 2, 9}, 1},
+		{"truncated string", `["ab`, io.ErrUnexpectedEOF, Position{4, 1, 5}, 0},
+		{"unterminated", "[1,2", io.ErrUnexpectedEOF, Position{4, 1, 5}, 2},
+		{"too large", `[1,"abcdefghijklmnop",2]`, ValueTooLargeError, Position{3, 1, 4}, 1},
+		// the reader fails before the literal 2 is known to be complete, it isn't a value
+		{"read error", "[1,2", iotest.ErrTimeout, Position{4, 1, 5}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r io.Reader = strings.NewReader(tt.input)
+			if tt.err == iotest.ErrTimeout {
+				r = io.MultiReader(strings.NewReader(tt.input), iotest.ErrReader(iotest.ErrTimeout))
+			}
+			s := NewScanner(r, Config{Format: Array, MaxValueSize: 8, BufferSize: 4})
+			n := 0
+			var err error
+			for {
+				if _, err = s.Next(); err != nil {
+					break
+				}
+				n++
+			}
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) || !decodeErr.Fatal || !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want a fatal %v", err, tt.err)
+			}
+			if decodeErr.Pos != tt.pos || n != tt.values || decodeErr.Index != n {
+				t.Errorf("error at %s of value %d after %d values, want %s after %d", decodeErr.Pos, decodeErr.Index, n, tt.pos, tt.values)
+			}
+			if _, again := s.Next(); again != err {
+				t.Errorf("next err = %v, want the same error", again)
+			}
+		})
+	}
+}
+
+func TestScannerNDJSONErrors(t *testing.T) {
+	input := "{\"a\":1}\n{\"long\":\"abcdefghijklmnopqrstuvwxyz\"}\n\n{\"b\":2}\n{\"long\":\"abcdefghijklmnopqrstuvwxyz\"}"
+	got, errs := values(t, strings.NewReader(input), Config{Format: NDJSON, MaxValueSize: 16, BufferSize: 4})
+	if strings.Join(got, "|") != `{"a":1}|{"b":2}` || len(errs) != 2 {
+		t.Fatalf("got %q, %v", got, errs)
+	}
+	var decodeErr *DecodeError
+	if !errors.As(errs[0], &decodeErr) || !errors.Is(errs[0], ValueTooLargeError) || decodeErr.Pos != (Position{8, 2, 1}) || decodeErr.Index != 1 {
+		t.Errorf("err = %v, want too large at line 2 of value 1", errs[0])
+	}
+	if !errors.As(errs[1], &decodeErr) || decodeErr.Pos.Line != 5 || decodeErr.Index != 3 {
+		t.Errorf("err = %v, want too large at line 5 of value 3", errs[1])
+	}
+}
+
+// generator reads n generated lines without keeping them in memory
+type generator struct {
+	n, i int
+	line func(i int) string
+	rest string
+}
+
+func (g *generator) Read(p []byte) (int, error) {
+	for g.rest == "" {
+		if g.i == g.n {
+			return 0, io.EOF
+		}
+		g.rest = g.line(g.i)
+		g.i++
+	}
+	n := copy(p, g.rest)
+	g.rest = g.rest[n:]
+	return n, nil
+}
+
+func TestScannerBoundedBuffer(t *testing.T) {
+	const n = 200000
+	r := &generator{n: n + 2, line: func(i int) string {
+		sep := ",\n"
+		switch i {
+		case 0:
+			return "["
+		case 1:
+			sep = "\n"
+		case n + 1:
+			return "\n]\n"
+		}
+		if i == n/2 {
+			// a value larger than the buffer
+			return sep + `{"big":"` + strings.Repeat("x", 10000) + `"}`
+		}
+		return sep + `{"name":"battery sensor","capacity":40,"time":"2019-01-21T19:07:28Z"}`
+	}}
+	s := NewScanner(r, Config{BufferSize: 1024})
+	count := 0
+	for {
+		v, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count == n/2-1 && len(v) != 10010 {
+			t.Errorf("big value of %d bytes", len(v))
+		}
+		count++
+	}
+	if count != n || s.Pos().Line != n+1 {
+		t.Errorf("%d values, the last at %s, want %d at line %d", count, s.Pos(), n, n+1)
+	}
+	// the input is about 15MB, the buffer grows only for the largest value
+	if cap(s.buf) > 4*10010 {
+		t.Errorf("buffer of %d bytes", cap(s.buf))
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys authenticates the X-API-Key header against static keys.
+// Only the SHA-256 of a key is kept, keys are random and long so no salt or KDF is needed.
+type APIKeys struct {
+	keys map[string]*Principal
+}
+
+func NewAPIKeys() *APIKeys {
+	return &APIKeys{keys: map[string]*Principal{}}
+}
+
+// HashAPIKey returns the hex SHA-256 of key, the form kept in an API key file.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Add registers key for subject, it is not safe to call while serving.
+func (a *APIKeys) Add(key, subject string, roles ...string) {
+	a.addHashed(HashAPIKey(key), subject, roles)
+}
+
+func (a *APIKeys) addHashed(hash, subject string, roles []string) {
+	a.keys[strings.ToLower(hash)] = &Principal{Subject: subject, Roles: roles, Method: "api_key"}
+}
+
+// LoadAPIKeysFile reads lines of "subject:sha256-hex-of-key:role1,role2".
+func LoadAPIKeysFile(path string) (*APIKeys, error) {
+	creds, err := readCredentialsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a := NewAPIKeys()
+	for _, c := range creds {
+		if b, err := hex.DecodeString(c.hash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%s: the key of %s is not a hex SHA-256", path, c.subject)
+		}
+		a.addHashed(c.hash, c.subject, c.roles)
+	}
+	return a, nil
+}
+
+func (a *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, NoCredentialsError
+	}
+	// the lookup is by hash, so its timing tells nothing about the key
+	p, ok := a.keys[HashAPIKey(key)]
+	if !ok {
+		return nil, InvalidCredentialsError
+	}
+	copied := *p
+	return &copied, nil
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go_learning/src/25_http/middleware"
+	"go_learning/src/25_http/response"
+)
+
+/*
+	Authentication（你是誰）與 Authorization（你可以做什麼）分成兩步：
+
+	1. Authenticator 從 request 取出 credentials，驗證後回傳 Principal（subject + roles）
+		* APIKeys：X-API-Key header
+		* Basic：Authorization: Basic ...，帳號密碼來自 credentials file，密碼以 PBKDF2-SHA256 hash 保存
+		* JWT：Authorization: Bearer ...，HMAC（HS256/HS384/HS512）簽章在本地驗證，不需要呼叫其他服務
+		request 沒有某種 credentials 時回傳 NoCredentialsError，Chain 會改試下一個 Authenticator
+
+	2. Require 檢查 Principal 是否有 route 需要的 role，例如 employees:read、employees:write
+
+	router.GET("/employees", middleware.Handle(h.ListEmployees, auth.Require(authn, "employees:read")))
+
+	失敗時的 error 沿用 08_error/error_design 的 authorizationError 設計：
+	包住原始錯誤（operation + err），並透過 Cause()（causer interface）與 Unwrap() 暴露原始錯誤，
+	呼叫端可以用 errors.Is(err, auth.TokenExpiredError) 判斷原因。
+	沒有或無效的 credentials 回 401（附上 WWW-Authenticate），role 不足回 403。
+*/
+
+var (
+	NoCredentialsError      = errors.New("no credentials")
+	InvalidCredentialsError = errors.New("invalid credentials")
+	InvalidTokenError       = errors.New("invalid token")
+	TokenExpiredError       = errors.New("token expired")
+	ForbiddenError          = errors.New("missing role")
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Subject string
+	Roles   []string
+	// Method is "api_key", "basic" or "jwt"
+	Method string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator verifies the credentials of a request.
+// It returns NoCredentialsError when the request doesn't carry its kind of credentials.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Challenger is implemented by authenticators that want a WWW-Authenticate header on 401.
+type Challenger interface {
+	Challenge() string
+}
+
+type static Principal
+
+// Static authenticates every request as p, for development without credentials.
+func Static(p Principal) Authenticator {
+	return static(p)
+}
+
+func (s static) Authenticate(r *http.Request) (*Principal, error) {
+	p := Principal(s)
+	return &p, nil
+}
+
+type chain []Authenticator
+
+// Chain tries authns in order, the first one finding credentials decides.
+func Chain(authns ...Authenticator) Authenticator {
+	return chain(authns)
+}
+
+func (c chain) Authenticate(r *http.Request) (*Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if errors.Is(err, NoCredentialsError) {
+			continue
+		}
+		return p, err
+	}
+	return nil, NoCredentialsError
+}
+
+func challenges(a Authenticator) []string {
+	var result []string
+	if c, ok := a.(chain); ok {
+		for _, a := range c {
+			result = append(result, challenges(a)...)
+		}
+		return result
+	}
+	if c, ok := a.(Challenger); ok {
+		result = append(result, c.Challenge())
+	}
+	return result
+}
+
+// AuthorizationError wraps the original error, like the authorizationError in 08_error/error_design.
+type AuthorizationError struct {
+	// Operation is the method and path of the request
+	Operation string
+	Err       error
+}
+
+func (e *AuthorizationError) Error() string {
+	return fmt.Sprintf("authorization failed during %s: %v", e.Operation, e.Err)
+}
+
+func (e *AuthorizationError) Cause() error {
+	return e.Err
+}
+
+func (e *AuthorizationError) Unwrap() error {
+	return e.Err
+}
+
+// Status is 403 when the caller lacks a role, otherwise 401.
+func (e *AuthorizationError) Status() int {
+	if errors.Is(e.Err, ForbiddenError) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+var errorMapper = response.NewMapper(func(err error) *response.Error {
+	var ae *AuthorizationError
+	if !errors.As(err, &ae) {
+		return nil
+	}
+	e := response.NewError(ae.Status(), ae.Error())
+	e.Err = err
+	return e
+})
+
+type principalKey struct{}
+
+func withPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+}
+
+// PrincipalFromContext returns the caller authenticated by Authenticate or Require, nil for anonymous.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+func fail(w http.ResponseWriter, r *http.Request, authn Authenticator, err error) {
+	ae := &AuthorizationError{Operation: r.Method + " " + r.URL.Path, Err: err}
+	if ae.Status() == http.StatusUnauthorized && authn != nil {
+		for _, c := range challenges(authn) {
+			w.Header().Add("WWW-Authenticate", c)
+		}
+	}
+	errorMapper.WriteError(w, r, ae)
+}
+
+// Authenticate puts the Principal into the request context when the request has credentials,
+// requests without credentials go on anonymously, invalid credentials are rejected with 401.
+func Authenticate(authn Authenticator) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := authn.Authenticate(r)
+			switch {
+			case errors.Is(err, NoCredentialsError):
+			case err != nil:
+				fail(w, r, authn, err)
+				return
+			default:
+				r = withPrincipal(r, p)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Require lets the request through only if the caller has every one of roles.
+// The caller is authenticated with authn unless Authenticate already did it, authn may be nil then.
+func Require(authn Authenticator, roles ...string) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := PrincipalFromContext(r.Context())
+			if p == nil {
+				if authn == nil {
+					fail(w, r, nil, NoCredentialsError)
+					return
+				}
+				var err error
+				if p, err = authn.Authenticate(r); err != nil {
+					fail(w, r, authn, err)
+					return
+				}
+				r = withPrincipal(r, p)
+			}
+			for _, role := range roles {
+				if !p.HasRole(role) {
+					fail(w, r, authn, fmt.Errorf("%w: %s needs %s", ForbiddenError, p.Subject, role))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go_learning/src/25_http/response"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPBKDF2(t *testing.T) {
+	// test vectors of PBKDF2-HMAC-SHA256
+	for iterations, expected := range map[int]string{
+		1:    "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b",
+		2:    "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43",
+		4096: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a",
+	} {
+		if got := hex.EncodeToString(pbkdf2SHA256([]byte("password"), []byte("salt"), iterations, 32)); got != expected {
+			t.Errorf("%d iterations: expected %s, got %s", iterations, expected, got)
+		}
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("secret", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ph, err := parsePasswordHash(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ph.matches("secret") || ph.matches("Secret") {
+		t.Error("only the right password should match")
+	}
+	if other, _ := HashPassword("secret", 1000); other == hash {
+		t.Error("the salt should be random")
+	}
+	for _, bad := range []string{"", "plain", "pbkdf2-sha256$x$c2FsdA$aGFzaA", "md5$1$c2FsdA$aGFzaA"} {
+		if _, err := parsePasswordHash(bad); !errors.Is(err, InvalidPasswordHashError) {
+			t.Errorf("%q: expected InvalidPasswordHashError, got %v", bad, err)
+		}
+	}
+}
+
+func newBasic(t *testing.T) *Basic {
+	t.Helper()
+	mike, _ := HashPassword("mike-password", 1000)
+	rose, _ := HashPassword("rose-password", 1000)
+	path := writeFile(t, "# user:hash:roles\n\nmike:"+mike+":employees:read, employees:write\nrose:"+rose+":employees:read\n")
+	b, err := LoadBasicFile(path, "employees")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBasic(t *testing.T) {
+	b := newBasic(t)
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := b.Authenticate(req); !errors.Is(err, NoCredentialsError) {
+		t.Errorf("expected NoCredentialsError, got %v", err)
+	}
+
+	req.SetBasicAuth("mike", "mike-password")
+	p, err := b.Authenticate(req)
+	if err != nil || p.Subject != "mike" || !p.HasRole("employees:write") || p.Method != "basic" {
+		t.Errorf("unexpected %+v (%v)", p, err)
+	}
+
+	for _, user := range [][2]string{{"mike", "rose-password"}, {"nobody", "mike-password"}} {
+		req.SetBasicAuth(user[0], user[1])
+		if _, err := b.Authenticate(req); !errors.Is(err, InvalidCredentialsError) {
+			t.Errorf("%v: expected InvalidCredentialsError, got %v", user, err)
+		}
+	}
+}
+
+func TestLoadBasicFileErrors(t *testing.T) {
+	for _, content := range []string{"mike\n", "mike:plain-password:employees:read\n", "mike::x\n"} {
+		if _, err := LoadBasicFile(writeFile(t, content), "employees"); err == nil {
+			t.Errorf("%q should be rejected", content)
+		}
+	}
+	hash, _ := HashPassword("x", 1)
+	if _, err := LoadBasicFile(writeFile(t, "mike:"+hash+":\nmike:"+hash+":\n"), "employees"); err == nil ||
+		!strings.Contains(err.Error(), ":2:") {
+		t.Errorf("expected an error at line 2, got %v", err)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	path := writeFile(t, "ci:"+HashAPIKey("ci-key")+":employees:read\n")
+	keys, err := LoadAPIKeysFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.Add("admin-key", "admin", "employees:read", "employees:write")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := keys.Authenticate(req); !errors.Is(err, NoCredentialsError) {
+		t.Errorf("expected NoCredentialsError, got %v", err)
+	}
+	req.Header.Set(APIKeyHeader, "ci-key")
+	if p, err := keys.Authenticate(req); err != nil || p.Subject != "ci" || p.HasRole("employees:write") {
+		t.Errorf("unexpected %+v (%v)", p, err)
+	}
+	req.Header.Set(APIKeyHeader, "admin-key")
+	if p, err := keys.Authenticate(req); err != nil || !p.HasRole("employees:write") {
+		t.Errorf("unexpected %+v (%v)", p, err)
+	}
+	req.Header.Set(APIKeyHeader, "guess")
+	if _, err := keys.Authenticate(req); !errors.Is(err, InvalidCredentialsError) {
+		t.Errorf("expected InvalidCredentialsError, got %v", err)
+	}
+
+	if _, err := LoadAPIKeysFile(writeFile(t, "ci:not-a-hash:employees:read\n")); err == nil {
+		t.Error("a key that isn't a SHA-256 should be rejected")
+	}
+}
+
+func newJWT(t *testing.T, now time.Time) *JWT {
+	t.Helper()
+	j, err := NewJWT(JWTConfig{Secret: secret, Issuer: "hr", Audience: "employees", Leeway: time.Minute,
+		Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func TestJWT(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	j := newJWT(t, now)
+	valid := Claims{Subject: "mike", Issuer: "hr", Audience: Audience{"employees"},
+		ExpiresAt: now.Add(time.Hour).Unix(), Roles: []string{"employees:read"}, Scope: "employees:write"}
+	token, err := j.Sign(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	p, err := j.Authenticate(req)
+	if err != nil || p.Subject != "mike" || !p.HasRole("employees:read") || !p.HasRole("employees:write") {
+		t.Errorf("unexpected %+v (%v)", p, err)
+	}
+
+	sign := func(modify func(c *Claims)) string {
+		c := valid
+		modify(&c)
+		token, _ := j.Sign(c)
+		return token
+	}
+	tests := map[string]struct {
+		token string
+		err   error
+	}{
+		"expired":         {sign(func(c *Claims) { c.ExpiresAt = now.Add(-2 * time.Minute).Unix() }), TokenExpiredError},
+		"within leeway":   {sign(func(c *Claims) { c.ExpiresAt = now.Add(-30 * time.Second).Unix() }), nil},
+		"no exp":          {sign(func(c *Claims) { c.ExpiresAt = 0 }), InvalidTokenError},
+		"not valid yet":   {sign(func(c *Claims) { c.NotBefore = now.Add(time.Hour).Unix() }), InvalidTokenError},
+		"wrong issuer":    {sign(func(c *Claims) { c.Issuer = "evil" }), InvalidTokenError},
+		"wrong audience":  {sign(func(c *Claims) { c.Audience = Audience{"billing", "payroll"} }), InvalidTokenError},
+		"many audiences":  {sign(func(c *Claims) { c.Audience = Audience{"billing", "employees"} }), nil},
+		"no subject":      {sign(func(c *Claims) { c.Subject = "" }), InvalidTokenError},
+		"two parts":       {"a.b", InvalidTokenError},
+		"tampered claims": {tamper(token), InvalidTokenError},
+		"alg none":        {algNone(token), InvalidTokenError},
+	}
+	for name, test := range tests {
+		_, err := j.Verify(test.token)
+		if (test.err == nil && err != nil) || (test.err != nil && !errors.Is(err, test.err)) {
+			t.Errorf("%s: expected %v, got %v", name, test.err, err)
+		}
+	}
+
+	other, _ := NewJWT(JWTConfig{Secret: []byte("another secret of at least 32 bytes"), Issuer: "hr"})
+	forged, _ := other.Sign(valid)
+	if _, err := j.Verify(forged); !errors.Is(err, InvalidTokenError) {
+		t.Errorf("a token of another secret should be rejected, got %v", err)
+	}
+}
+
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	payload = []byte(strings.Replace(string(payload), `"mike"`, `"root"`, 1))
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join(parts, ".")
+}
+
+func algNone(token string) string {
+	parts := strings.Split(token, ".")
+	parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	return parts[0] + "." + parts[1] + "."
+}
+
+func TestNewJWTErrors(t *testing.T) {
+	if _, err := NewJWT(JWTConfig{Secret: []byte("short")}); err == nil {
+		t.Error("a short secret should be rejected")
+	}
+	if _, err := NewJWT(JWTConfig{Secret: secret, Algorithm: "RS256"}); err == nil {
+		t.Error("RS256 is not supported")
+	}
+}
+
+func TestAuthorizationError(t *testing.T) {
+	err := error(&AuthorizationError{Operation: "GET /employees", Err: TokenExpiredError})
+	if c, ok := err.(interface{ Cause() error }); !ok || c.Cause() != TokenExpiredError {
+		t.Error("the original error should be exposed by Cause")
+	}
+	if !errors.Is(err, TokenExpiredError) {
+		t.Error("the original error should be exposed by Unwrap")
+	}
+	if err.Error() != "authorization failed during GET /employees: token expired" {
+		t.Errorf("unexpected %q", err.Error())
+	}
+}
+
+func TestRequire(t *testing.T) {
+	keys := NewAPIKeys()
+	keys.Add("reader-key", "reader", "employees:read")
+	authn := Chain(keys, newBasic(t))
+	var got *Principal
+	h := Require(authn, "employees:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = PrincipalFromContext(r.Context())
+	}))
+
+	serve := func(setup func(r *http.Request)) (*httptest.ResponseRecorder, *response.Error) {
+		req := httptest.NewRequest("POST", "/employees", nil)
+		setup(req)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			return w, nil
+		}
+		var body response.Envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error == nil {
+			t.Fatalf("invalid envelope %q", w.Body.String())
+		}
+		return w, body.Error
+	}
+
+	w, e := serve(func(r *http.Request) {})
+	if w.Code != http.StatusUnauthorized || e.Code != "unauthorized" {
+		t.Errorf("unexpected %d %+v", w.Code, e)
+	}
+	if challenges := w.Header().Values("WWW-Authenticate"); len(challenges) != 1 || !strings.HasPrefix(challenges[0], "Basic ") {
+		t.Errorf("unexpected challenges %v", challenges)
+	}
+
+	w, e = serve(func(r *http.Request) { r.Header.Set(APIKeyHeader, "reader-key") })
+	if w.Code != http.StatusForbidden || e.Code != "forbidden" || w.Header().Get("WWW-Authenticate") != "" {
+		t.Errorf("unexpected %d %+v", w.Code, e)
+	}
+
+	w, _ = serve(func(r *http.Request) { r.SetBasicAuth("mike", "mike-password") })
+	if w.Code != http.StatusOK || got == nil || got.Subject != "mike" {
+		t.Errorf("unexpected %d %+v", w.Code, got)
+	}
+
+	w, _ = serve(func(r *http.Request) { r.SetBasicAuth("mike", "wrong") })
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := NewAPIKeys()
+	keys.Add("reader-key", "reader", "employees:read")
+	var got *Principal
+	h := Authenticate(keys)(Require(nil, "employees:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = PrincipalFromContext(r.Context())
+	})))
+
+	req := httptest.NewRequest("GET", "/employees", nil)
+	req.Header.Set(APIKeyHeader, "reader-key")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || got == nil || got.Subject != "reader" {
+		t.Errorf("unexpected %d %+v", w.Code, got)
+	}
+
+	// anonymous requests pass Authenticate and are stopped by Require
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/employees", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+
+	req.Header.Set(APIKeyHeader, "bad-key")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
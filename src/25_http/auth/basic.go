@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	passwordHashPrefix        = "pbkdf2-sha256"
+	DefaultPasswordIterations = 100000
+)
+
+var InvalidPasswordHashError = errors.New("invalid password hash")
+
+// pbkdf2SHA256 is PBKDF2 (RFC 8018) with HMAC-SHA256, written out to avoid a dependency.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+	dk := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	var counter [4]byte
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+		for i := 2; i <= iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for x := range u {
+				t[x] ^= u[x]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+// HashPassword returns "pbkdf2-sha256$iterations$salt$hash" with a random salt.
+func HashPassword(password string, iterations int) (string, error) {
+	if iterations < 1 {
+		return "", fmt.Errorf("%w: iterations should be positive", InvalidPasswordHashError)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := pbkdf2SHA256([]byte(password), salt, iterations, sha256.Size)
+	return strings.Join([]string{
+		passwordHashPrefix,
+		strconv.Itoa(iterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	}, "$"), nil
+}
+
+type passwordHash struct {
+	iterations int
+	salt, hash []byte
+}
+
+func parsePasswordHash(s string) (passwordHash, error) {
+	fields := strings.Split(s, "$")
+	if len(fields) != 4 || fields[0] != passwordHashPrefix {
+		return passwordHash{}, InvalidPasswordHashError
+	}
+	var ph passwordHash
+	var err1, err2, err3 error
+	ph.iterations, err1 = strconv.Atoi(fields[1])
+	ph.salt, err2 = base64.RawStdEncoding.DecodeString(fields[2])
+	ph.hash, err3 = base64.RawStdEncoding.DecodeString(fields[3])
+	if err1 != nil || err2 != nil || err3 != nil || ph.iterations < 1 || len(ph.hash) == 0 {
+		return passwordHash{}, InvalidPasswordHashError
+	}
+	return ph, nil
+}
+
+func (ph passwordHash) matches(password string) bool {
+	got := pbkdf2SHA256([]byte(password), ph.salt, ph.iterations, len(ph.hash))
+	return subtle.ConstantTimeCompare(got, ph.hash) == 1
+}
+
+type basicUser struct {
+	hash      passwordHash
+	principal Principal
+}
+
+// Basic authenticates HTTP Basic credentials against a credentials file.
+type Basic struct {
+	realm string
+	users map[string]basicUser
+	// checked for unknown users, so the response time doesn't tell which users exist
+	dummy passwordHash
+}
+
+// LoadBasicFile reads lines of "user:pbkdf2-sha256$iterations$salt$hash:role1,role2",
+// the hash is made by HashPassword.
+func LoadBasicFile(path, realm string) (*Basic, error) {
+	creds, err := readCredentialsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &Basic{realm: realm, users: map[string]basicUser{}}
+	for _, c := range creds {
+		ph, err := parsePasswordHash(c.hash)
+		if err != nil {
+			return nil, fmt.Errorf("%s: the password of %s: %w", path, c.subject, err)
+		}
+		b.users[c.subject] = basicUser{hash: ph, principal: Principal{Subject: c.subject, Roles: c.roles, Method: "basic"}}
+		if b.dummy.iterations < ph.iterations {
+			b.dummy = passwordHash{iterations: ph.iterations, salt: ph.salt, hash: make([]byte, len(ph.hash))}
+		}
+	}
+	return b, nil
+}
+
+func (b *Basic) Authenticate(r *http.Request) (*Principal, error) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Basic ") {
+		return nil, NoCredentialsError
+	}
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, InvalidCredentialsError
+	}
+	user, found := b.users[name]
+	if !found {
+		b.dummy.matches(password)
+		return nil, InvalidCredentialsError
+	}
+	if !user.hash.matches(password) {
+		return nil, InvalidCredentialsError
+	}
+	p := user.principal
+	return &p, nil
+}
+
+func (b *Basic) Challenge() string {
+	return fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, b.realm)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go_learning/src/25_http/auth"
+	"log"
+	"os"
+	"strings"
+)
+
+// prints a line for a credentials file, the secret is read from stdin so it doesn't end up in the shell history
+// $ echo -n 'mike-password' | go run ./src/25_http/auth/cmd/authhash -subject mike -roles employees:read,employees:write
+// $ echo -n 'some-long-random-key' | go run ./src/25_http/auth/cmd/authhash -api-key -subject ci -roles employees:read
+func main() {
+	subject := flag.String("subject", "", "user name or API key owner")
+	roles := flag.String("roles", "", "comma separated roles")
+	apiKey := flag.Bool("api-key", false, "hash an API key instead of a password")
+	iterations := flag.Int("iterations", auth.DefaultPasswordIterations, "PBKDF2 iterations of a password")
+	flag.Parse()
+	if *subject == "" || strings.Contains(*subject, ":") {
+		log.Fatal("-subject is required and can't contain ':'")
+	}
+
+	secret, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && secret == "" {
+		log.Fatal("read the secret from stdin: ", err)
+	}
+	secret = strings.TrimRight(secret, "\r\n")
+
+	var hash string
+	if *apiKey {
+		hash = auth.HashAPIKey(secret)
+	} else if hash, err = auth.HashPassword(secret, *iterations); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s:%s:%s\n", *subject, hash, *roles)
+}
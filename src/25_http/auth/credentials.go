@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// credential is one line of a credentials file: "subject:hash:role1,role2".
+// Blank lines and lines starting with # are skipped.
+type credential struct {
+	subject string
+	hash    string
+	roles   []string
+}
+
+func readCredentialsFile(path string) ([]credential, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []credential
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, ":", 3)
+		if len(fields) != 3 || fields[0] == "" || fields[1] == "" {
+			return nil, fmt.Errorf("%s:%d: expected subject:hash:roles", path, line)
+		}
+		if seen[fields[0]] {
+			return nil, fmt.Errorf("%s:%d: duplicated subject %q", path, line, fields[0])
+		}
+		seen[fields[0]] = true
+		c := credential{subject: fields[0], hash: fields[1]}
+		for _, role := range strings.Split(fields[2], ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				c.roles = append(c.roles, role)
+			}
+		}
+		result = append(result, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+	JWT = base64url(header) + "." + base64url(claims) + "." + base64url(signature)
+
+	只支援 HMAC（HS256/HS384/HS512）：簽發與驗證用同一把 secret，所以可以在本地驗證。
+	驗證時只接受設定好的那一種 alg，header 寫 "none" 或其他 alg 的 token 一律拒絕，
+	避免攻擊者自己挑選演算法（alg confusion）。
+*/
+
+var algorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// Audience is the "aud" claim, a string or an array of strings.
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Claims are the registered claims plus the roles, times are Unix seconds.
+type Claims struct {
+	Subject   string   `json:"sub,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	// Scope is an OAuth2 style space separated list, its entries are roles too
+	Scope string `json:"scope,omitempty"`
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// JWTConfig configures JWT, only Secret is required.
+type JWTConfig struct {
+	Secret []byte
+	// HS256 by default
+	Algorithm string
+	// when set, the iss claim must be equal and the aud claim must contain Audience
+	Issuer   string
+	Audience string
+	// tolerated clock skew of exp and nbf
+	Leeway time.Duration
+	Realm  string
+	Now    func() time.Time
+}
+
+// JWT verifies HMAC signed bearer tokens.
+type JWT struct {
+	config JWTConfig
+	newMAC func() hash.Hash
+}
+
+func NewJWT(config JWTConfig) (*JWT, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = "HS256"
+	}
+	newMAC, ok := algorithms[config.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", config.Algorithm)
+	}
+	if len(config.Secret) < 32 {
+		return nil, errors.New("the JWT secret should have at least 32 bytes")
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &JWT{config: config, newMAC: newMAC}, nil
+}
+
+func (j *JWT) sign(signingInput string) []byte {
+	mac := hmac.New(j.newMAC, j.config.Secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+// Sign returns a token of claims, for tests and tools issuing tokens.
+func (j *JWT) Sign(claims Claims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: j.config.Algorithm, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + base64.RawURLEncoding.EncodeToString(j.sign(input)), nil
+}
+
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", InvalidTokenError, reason)
+}
+
+// Verify checks the signature and the claims of token.
+func (j *JWT) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("expected 3 parts")
+	}
+	headerJSON, err1 := base64.RawURLEncoding.DecodeString(parts[0])
+	payload, err2 := base64.RawURLEncoding.DecodeString(parts[1])
+	signature, err3 := base64.RawURLEncoding.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, invalidToken("malformed base64")
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	if header.Algorithm != j.config.Algorithm {
+		return nil, invalidToken(fmt.Sprintf("unexpected algorithm %q", header.Algorithm))
+	}
+	if !hmac.Equal(signature, j.sign(parts[0]+"."+parts[1])) {
+		return nil, invalidToken("bad signature")
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	now := j.config.Now()
+	leeway := j.config.Leeway
+	if claims.ExpiresAt == 0 {
+		return nil, invalidToken("no exp claim")
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(leeway)) {
+		return nil, TokenExpiredError
+	}
+	if claims.NotBefore != 0 && now.Add(leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, invalidToken("not valid yet")
+	}
+	if j.config.Issuer != "" && claims.Issuer != j.config.Issuer {
+		return nil, invalidToken("unexpected issuer")
+	}
+	if j.config.Audience != "" && !contains(claims.Audience, j.config.Audience) {
+		return nil, invalidToken("unexpected audience")
+	}
+	if claims.Subject == "" {
+		return nil, invalidToken("no sub claim")
+	}
+	return &claims, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return nil, NoCredentialsError
+	}
+	claims, err := j.Verify(strings.TrimSpace(header[7:]))
+	if err != nil {
+		return nil, err
+	}
+	roles := append([]string(nil), claims.Roles...)
+	roles = append(roles, strings.Fields(claims.Scope)...)
+	return &Principal{Subject: claims.Subject, Roles: roles, Method: "jwt"}, nil
+}
+
+func (j *JWT) Challenge() string {
+	if j.config.Realm == "" {
+		return "Bearer"
+	}
+	return fmt.Sprintf("Bearer realm=%q", j.config.Realm)
+}
@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+	a, b := ETag([]byte("a")), ETag([]byte("b"))
+	if a == b || a != ETag([]byte("a")) || !strings.HasPrefix(a, `"`) || len(a) != 34 {
+		t.Errorf("unexpected %s %s", a, b)
+	}
+}
+
+func TestNoneMatch(t *testing.T) {
+	for header, want := range map[string]bool{
+		``:                  false,
+		`"abc"`:             true,
+		`W/"abc"`:           true,
+		`"x", "abc"`:        true,
+		`*`:                 true,
+		`"abcd"`:            false,
+		`"x",W/"abc" , "y"`: true,
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("If-None-Match", header)
+		if got := noneMatch(r, `"abc"`); got != want {
+			t.Errorf("%q: expected %v", header, want)
+		}
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	for _, tt := range []struct {
+		c    CacheControl
+		want string
+	}{
+		{CacheControl{MaxAge: time.Minute, Public: true}, "public, max-age=60"},
+		{CacheControl{Private: true, NoCache: true}, "private, no-cache"},
+		{CacheControl{NoStore: true}, "no-store"},
+		{CacheControl{MaxAge: 10 * time.Second, MustRevalidate: true}, "max-age=10, must-revalidate"},
+	} {
+		if got := tt.c.String(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+	ds := directives(`no-cache, Max-Age="0", private`)
+	if _, ok := ds["no-cache"]; !ok || ds["max-age"] != "0" || len(ds) != 3 {
+		t.Errorf("unexpected %v", ds)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	c := NewLRU(2, 0)
+	c.Add("a", &Entry{Body: []byte("a")}, "x")
+	c.Add("b", &Entry{Body: []byte("b")}, "x", "y")
+	c.Get("a")
+	c.Add("c", &Entry{Body: []byte("c")})
+	if _, ok := c.Get("b"); ok {
+		t.Error("b is the least recently used, it should be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected a")
+	}
+	if n := c.Invalidate("x"); n != 1 {
+		t.Errorf("expected to drop a, dropped %d", n)
+	}
+	if s := c.Stats(); s.Entries != 1 || s.Evictions != 1 || s.Hits != 2 || s.Misses != 1 {
+		t.Errorf("unexpected %+v", s)
+	}
+
+	g := c.Generation()
+	c.Invalidate("nothing")
+	if c.AddIfGeneration(g, "d", &Entry{}) {
+		t.Error("an entry read before an invalidation should not be added")
+	}
+	if !c.AddIfGeneration(c.Generation(), "d", &Entry{}) {
+		t.Error("expected d to be added")
+	}
+
+	small := NewLRU(0, 10)
+	small.Add("a", &Entry{Body: []byte("123456")})
+	small.Add("b", &Entry{Body: []byte("123456")})
+	if s := small.Stats(); s.Entries != 1 || s.Bytes != 6 {
+		t.Errorf("expected the byte limit to keep one entry, got %+v", s)
+	}
+	small.Add("big", &Entry{Body: make([]byte, 11)})
+	if _, ok := small.Get("big"); ok {
+		t.Error("an entry larger than the limit should not be kept")
+	}
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func get(url string, header ...string) *http.Request {
+	r := httptest.NewRequest("GET", url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	return r
+}
+
+func TestConditional(t *testing.T) {
+	var calls int32
+	body := `{"name":"Mike"}`
+	h := Conditional(Config{CacheControl: "private, no-cache"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+
+	w := serve(h, get("/"))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != ETag([]byte(body)) || w.Body.String() != body {
+		t.Fatalf("unexpected %d %v %q", w.Code, w.Header(), w.Body)
+	}
+	if w.Header().Get("Cache-Control") != "private, no-cache" || w.Header().Get("Vary") != "Accept" || w.Header().Get(CacheStatusHeader) != "" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+
+	w = serve(h, get("/", "If-None-Match", etag))
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag || w.Header().Get("Content-Type") != "" {
+		t.Errorf("expected 304, got %d %v %q", w.Code, w.Header(), w.Body)
+	}
+	body = `{"name":"Michael"}`
+	if w := serve(h, get("/", "If-None-Match", etag)); w.Code != http.StatusOK || w.Body.String() != body {
+		t.Errorf("a changed body should be sent, got %d %q", w.Code, w.Body)
+	}
+	if calls != 3 {
+		t.Errorf("without an LRU every request calls the handler, got %d calls", calls)
+	}
+}
+
+func TestConditionalSkips(t *testing.T) {
+	h := Conditional(Config{Cache: NewLRU(10, 0)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "no", http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/etag" {
+			w.Header().Set("ETag", `"v1"`)
+		}
+		io.WriteString(w, "ok")
+	}))
+	if w := serve(h, get("/missing")); w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" {
+		t.Errorf("errors should pass through, got %d %v", w.Code, w.Header())
+	}
+	if w := serve(h, httptest.NewRequest("POST", "/", nil)); w.Header().Get("ETag") != "" {
+		t.Errorf("POST should pass through, got %v", w.Header())
+	}
+	if w := serve(h, get("/etag", "If-None-Match", `"v1"`)); w.Code != http.StatusNotModified {
+		t.Errorf("the ETag of the handler should be kept, got %d", w.Code)
+	}
+}
+
+func TestConditionalStreamsLargeBodies(t *testing.T) {
+	h := Conditional(Config{Cache: NewLRU(10, 0), MaxBodyBytes: 10})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			io.WriteString(w, "12345")
+		}
+	}))
+	w := serve(h, get("/"))
+	if w.Body.String() != strings.Repeat("12345", 5) || w.Header().Get("ETag") != "" {
+		t.Errorf("unexpected %v %q", w.Header(), w.Body)
+	}
+}
+
+func TestConditionalLRU(t *testing.T) {
+	lru := NewLRU(10, 0)
+	var calls int32
+	version := 1
+	h := Conditional(Config{
+		Cache: lru,
+		Tags:  func(r *http.Request) []string { return []string{r.URL.Path} },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path":%q,"version":%d}`, r.URL.Path, version)
+	}))
+	outer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// like middleware.RequestID, set outside of the cache
+		w.Header().Set("X-Request-ID", r.Header.Get("X-Request-ID"))
+		h.ServeHTTP(w, r)
+	})
+
+	first := serve(outer, get("/a", "X-Request-ID", "1"))
+	second := serve(outer, get("/a", "X-Request-ID", "2"))
+	if calls != 1 || second.Body.String() != first.Body.String() || second.Header().Get(CacheStatusHeader) != "HIT" || first.Header().Get(CacheStatusHeader) != "MISS" {
+		t.Fatalf("expected a hit, got %d calls %v", calls, second.Header())
+	}
+	if second.Header().Get("X-Request-ID") != "2" || second.Header().Get("Content-Type") != "application/json" || second.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Errorf("unexpected headers of the hit %v", second.Header())
+	}
+	if w := serve(outer, get("/a", "If-None-Match", first.Header().Get("ETag"))); w.Code != http.StatusNotModified || calls != 1 {
+		t.Errorf("expected 304 from the LRU, got %d", w.Code)
+	}
+	// another Accept is another entry
+	serve(outer, get("/a", "Accept", "application/x-ndjson"))
+	if calls != 2 {
+		t.Errorf("expected a miss, got %d calls", calls)
+	}
+	// no-cache revalidates, no-store doesn't store
+	serve(outer, get("/a", "Cache-Control", "no-cache"))
+	serve(outer, get("/b", "Cache-Control", "no-store"))
+	serve(outer, get("/b"))
+	if calls != 5 {
+		t.Errorf("expected 5 calls, got %d", calls)
+	}
+
+	version = 2
+	lru.Invalidate("/a")
+	w := serve(outer, get("/a", "If-None-Match", first.Header().Get("ETag")))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"version":2`) {
+		t.Errorf("expected the new version, got %d %q", w.Code, w.Body)
+	}
+}
+
+func TestConditionalHitKeepsOuterHeaders(t *testing.T) {
+	h := Conditional(Config{Cache: NewLRU(10, 0)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", "Mon, 19 Oct 2026 00:00:00 GMT")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{}`)
+	}))
+	outer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// like middleware.CORS and middleware.RequestID
+		if r.Header.Get("Origin") != "" {
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("X-Request-ID", r.Header.Get("X-Request-ID"))
+		h.ServeHTTP(w, r)
+	})
+
+	serve(outer, get("/", "X-Request-ID", "1"))
+	w := serve(outer, get("/", "X-Request-ID", "2", "Origin", "https://example.com"))
+	if w.Header().Get(CacheStatusHeader) != "HIT" {
+		t.Fatalf("expected a hit, got %v", w.Header())
+	}
+	if vary := strings.Join(w.Header().Values("Vary"), ","); vary != "Origin,Accept" {
+		t.Errorf("the Vary of the hit should be merged, got %q", vary)
+	}
+	if w.Header().Get("X-Request-ID") != "2" || w.Header().Get("Date") != "" || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers of the hit %v", w.Header())
+	}
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETag is the strong entity tag of body, the quoted hex of the first 16 bytes of its SHA-256.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// opaque strips the W/ of a weak tag, If-None-Match uses the weak comparison (RFC 7232 2.3.2).
+func opaque(tag string) string {
+	return strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+}
+
+// noneMatch reports whether etag matches the If-None-Match header of r.
+func noneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if opaque(tag) == opaque(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheControl builds the Cache-Control header of a response.
+type CacheControl struct {
+	// MaxAge is how long a client may use the response without asking again
+	MaxAge time.Duration
+	// Private responses are kept by browsers, never by shared caches such as a CDN
+	Private bool
+	Public  bool
+	// NoCache responses may be kept, but are revalidated with If-None-Match every time
+	NoCache bool
+	// NoStore responses are kept nowhere, not even by the LRU of Conditional
+	NoStore        bool
+	MustRevalidate bool
+}
+
+func (c CacheControl) String() string {
+	var directives []string
+	if c.Public {
+		directives = append(directives, "public")
+	}
+	if c.Private {
+		directives = append(directives, "private")
+	}
+	if c.NoCache {
+		directives = append(directives, "no-cache")
+	}
+	if c.NoStore {
+		directives = append(directives, "no-store")
+	}
+	if c.MaxAge > 0 || !c.NoCache && !c.NoStore {
+		directives = append(directives, "max-age="+strconv.FormatInt(int64(c.MaxAge/time.Second), 10))
+	}
+	if c.MustRevalidate {
+		directives = append(directives, "must-revalidate")
+	}
+	return strings.Join(directives, ", ")
+}
+
+// directives parses a Cache-Control header into its lower case directives and their values.
+func directives(header string) map[string]string {
+	ds := map[string]string{}
+	for _, d := range strings.Split(header, ",") {
+		name, value := strings.TrimSpace(d), ""
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			name, value = strings.TrimSpace(name[:eq]), strings.Trim(strings.TrimSpace(name[eq+1:]), `"`)
+		}
+		if name != "" {
+			ds[strings.ToLower(name)] = value
+		}
+	}
+	return ds
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+)
+
+// Entry is a cached 200 response.
+type Entry struct {
+	// Header holds only the headers set by the handler, not the ones of the outer middlewares
+	Header http.Header
+	Body   []byte
+	ETag   string
+}
+
+func (e *Entry) size() int64 {
+	n := int64(len(e.Body))
+	for k, vs := range e.Header {
+		for _, v := range vs {
+			n += int64(len(k) + len(v))
+		}
+	}
+	return n
+}
+
+type lruItem struct {
+	key   string
+	entry *Entry
+	tags  []string
+	size  int64
+}
+
+// Stats counts the lookups of an LRU.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+// LRU keeps the responses most recently used, up to maxEntries and maxBytes.
+//
+// Each entry has tags, names of the resources it was made from, Invalidate drops every entry of a tag:
+//
+//	GET /employees/Mike → tag "employees/Mike"
+//	GET /employees      → tag "employees"
+//	PATCH Mike          → Invalidate("employees/Mike", "employees")
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	ll         *list.List // front is the most recently used
+	items      map[string]*list.Element
+	tags       map[string]map[*list.Element]struct{}
+	bytes      int64
+	// generation grows on every Invalidate, see Generation
+	generation uint64
+	stats      Stats
+}
+
+// NewLRU returns an LRU of at most maxEntries entries and maxBytes bytes, 0 means no limit.
+func NewLRU(maxEntries int, maxBytes int64) *LRU {
+	return &LRU{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+		tags:       map[string]map[*list.Element]struct{}{},
+	}
+}
+
+func (c *LRU) Get(key string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.ll.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+// Generation changes whenever entries are invalidated.
+// A response read from the store before an Invalidate may be stale, so it is only added
+// when the generation is still the one taken before reading, see AddIfGeneration.
+func (c *LRU) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Add keeps entry under key, replacing the entry already there.
+func (c *LRU) Add(key string, entry *Entry, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(key, entry, tags)
+}
+
+// AddIfGeneration adds entry only when nothing was invalidated since Generation returned generation.
+func (c *LRU) AddIfGeneration(generation uint64, key string, entry *Entry, tags ...string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return false
+	}
+	c.add(key, entry, tags)
+	return true
+}
+
+func (c *LRU) add(key string, entry *Entry, tags []string) {
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	item := &lruItem{key: key, entry: entry, tags: tags, size: entry.size()}
+	if c.maxBytes > 0 && item.size > c.maxBytes {
+		return
+	}
+	el := c.ll.PushFront(item)
+	c.items[key] = el
+	c.bytes += item.size
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = map[*list.Element]struct{}{}
+		}
+		c.tags[tag][el] = struct{}{}
+	}
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *LRU) remove(el *list.Element) {
+	item := el.Value.(*lruItem)
+	c.ll.Remove(el)
+	delete(c.items, item.key)
+	c.bytes -= item.size
+	for _, tag := range item.tags {
+		delete(c.tags[tag], el)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// Invalidate drops the entries of the tags and returns how many were dropped.
+func (c *LRU) Invalidate(tags ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	n := 0
+	for _, tag := range tags {
+		for el := range c.tags[tag] {
+			c.remove(el)
+			n++
+		}
+	}
+	return n
+}
+
+// Purge drops every entry.
+func (c *LRU) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+	c.tags = map[string]map[*list.Element]struct{}{}
+	c.bytes = 0
+}
+
+func (c *LRU) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.ll.Len()
+	s.Bytes = c.bytes
+	return s
+}
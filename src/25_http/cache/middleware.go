@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go_learning/src/25_http/middleware"
+)
+
+/*
+	Conditional 讓 GET 的回應可以被重複使用，handler 不用改：
+
+	1. ETag：把 handler 的 200 回應先 buffer 起來，用 body 的 SHA-256 算出 strong ETag
+	2. If-None-Match：client 帶著上次的 ETag 來問，內容沒變就回 304，不送 body
+	   （JSON 還是會 marshal 一次，省的是頻寬；要連 marshal 都省掉就要用 LRU）
+	3. Cache-Control：handler 沒有設定時，加上 Config.CacheControl
+	4. LRU（選用）：把回應留在 process 裡，下一個相同的 request 直接回傳，不會呼叫 handler
+
+	LRU 的 key 是 path、query 與 Vary 的 request header（預設 Accept）。
+	server 端的 cache 不知道誰在問，所以：
+	* 要放在 authentication 之後，沒有權限的 request 不會讀到 cache
+	* 依使用者而不同的回應要加上 Vary: Authorization，或是 Cache-Control: no-store
+
+	資料改變時呼叫 LRU.Invalidate(tags...) 丟掉相關的 entry，Config.Tags 決定每個回應屬於哪些 tag。
+	request 的 Cache-Control: no-cache 會略過 LRU 重新產生回應，no-store 則不讀也不寫 LRU。
+*/
+
+const (
+	// CacheStatusHeader is HIT when the response comes from the LRU, MISS otherwise
+	CacheStatusHeader = "X-Cache"
+
+	DefaultMaxBodyBytes = 1 << 20
+)
+
+type Config struct {
+	// CacheControl is set on the 200 responses without one, like CacheControl{Private: true, NoCache: true}.String()
+	CacheControl string
+	// Cache keeps the responses in process when not nil
+	Cache *LRU
+	// Tags names the resources a response is made from, for LRU.Invalidate
+	Tags func(r *http.Request) []string
+	// Vary are the request headers the response depends on, Accept by default
+	Vary []string
+	// MaxBodyBytes is the largest body buffered, larger ones are streamed without an ETag
+	MaxBodyBytes int
+}
+
+// Conditional adds ETags to the responses of GET, answers If-None-Match and optionally caches.
+func Conditional(c Config) middleware.Middleware {
+	if c.Vary == nil {
+		c.Vary = []string{"Accept"}
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			request := directives(r.Header.Get("Cache-Control"))
+			_, noStore := request["no-store"]
+			_, noCache := request["no-cache"]
+			if r.Header.Get("Pragma") == "no-cache" || request["max-age"] == "0" {
+				noCache = true
+			}
+			useCache := c.Cache != nil && !noStore
+			key := c.key(r)
+			if useCache && !noCache {
+				if e, ok := c.Cache.Get(key); ok {
+					restore(w.Header(), e.Header)
+					w.Header().Set(CacheStatusHeader, "HIT")
+					writeBody(w, r, e.ETag, e.Body)
+					return
+				}
+			}
+
+			var generation uint64
+			if useCache {
+				generation = c.Cache.Generation()
+			}
+			before := w.Header().Clone()
+			bw := &bufferedWriter{ResponseWriter: w, status: http.StatusOK, max: c.MaxBodyBytes}
+			next.ServeHTTP(bw, r)
+			if bw.streaming {
+				return
+			}
+			if bw.status != http.StatusOK {
+				bw.flush()
+				return
+			}
+
+			h := w.Header()
+			etag := h.Get("ETag")
+			if etag == "" {
+				etag = ETag(bw.buf.Bytes())
+				h.Set("ETag", etag)
+			}
+			if h.Get("Cache-Control") == "" && c.CacheControl != "" {
+				h.Set("Cache-Control", c.CacheControl)
+			}
+			for _, name := range c.Vary {
+				h.Add("Vary", name)
+			}
+			response := directives(h.Get("Cache-Control"))
+			_, responseNoStore := response["no-store"]
+			if useCache && !responseNoStore && h.Get("Set-Cookie") == "" {
+				var tags []string
+				if c.Tags != nil {
+					tags = c.Tags(r)
+				}
+				entry := &Entry{Header: changed(before, h), Body: append([]byte(nil), bw.buf.Bytes()...), ETag: etag}
+				c.Cache.AddIfGeneration(generation, key, entry, tags...)
+			}
+			if c.Cache != nil {
+				h.Set(CacheStatusHeader, "MISS")
+			}
+			writeBody(w, r, etag, bw.buf.Bytes())
+		})
+	}
+}
+
+func (c *Config) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.URL.RequestURI())
+	for _, name := range c.Vary {
+		b.WriteByte(0)
+		b.WriteString(r.Header.Get(name))
+	}
+	return b.String()
+}
+
+// requestScoped are the headers of one response which are never replayed from the LRU:
+// the hop-by-hop headers, and the ones middlewares set for each request.
+var requestScoped = map[string]bool{
+	"Connection":               true,
+	"Keep-Alive":               true,
+	"Proxy-Connection":         true,
+	"Te":                       true,
+	"Trailer":                  true,
+	"Transfer-Encoding":        true,
+	"Upgrade":                  true,
+	"Date":                     true,
+	"Set-Cookie":               true,
+	middleware.RequestIDHeader: true,
+	CacheStatusHeader:          true,
+}
+
+// changed returns the headers of after that aren't the same in before.
+func changed(before, after http.Header) http.Header {
+	h := http.Header{}
+	for k, vs := range after {
+		if requestScoped[k] {
+			continue
+		}
+		if old, ok := before[k]; !ok || strings.Join(old, "\n") != strings.Join(vs, "\n") {
+			h[k] = append([]string(nil), vs...)
+		}
+	}
+	return h
+}
+
+// restore copies the headers of a cached response into h without overwriting the ones
+// set by the outer middlewares for this request, Vary is merged.
+func restore(h, cached http.Header) {
+	for k, vs := range cached {
+		switch {
+		case k == "Vary":
+			addVary(h, vs)
+		case requestScoped[k], len(h[k]) > 0:
+		default:
+			h[k] = append([]string(nil), vs...)
+		}
+	}
+}
+
+// addVary adds the names of values which aren't in the Vary of h yet.
+func addVary(h http.Header, values []string) {
+	seen := map[string]bool{}
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			seen[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name != "" && !seen[name] {
+				seen[name] = true
+				h.Add("Vary", name)
+			}
+		}
+	}
+}
+
+// writeBody answers 304 when the ETag matches If-None-Match, and 200 with body otherwise.
+func writeBody(w http.ResponseWriter, r *http.Request, etag string, body []byte) {
+	h := w.Header()
+	if noneMatch(r, etag) {
+		// a 304 keeps ETag, Cache-Control and Vary, and drops what describes the body
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// bufferedWriter keeps the body until the handler returns, or streams once it exceeds max.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	max         int
+	streaming   bool
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) > w.max {
+		w.flush()
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// flush writes the status and the buffered body, and streams the rest.
+func (w *bufferedWriter) flush() {
+	if w.streaming {
+		return
+	}
+	w.streaming = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+	}
+}
+
+// Flush is a no-op while buffering, the NDJSON of a page is small enough to wait.
+func (w *bufferedWriter) Flush() {
+	if !w.streaming {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -1,26 +1,46 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"go_learning/src/25_http/response"
+	"go_learning/src/25_http/server"
+	"log"
 	"net/http"
 	"time"
 )
 
 // http://localhost:8080/
 // http://localhost:8080/time
+// curl -H 'Accept: text/plain' http://localhost:8080/time
+// http://localhost:8080/healthz
+// $ go run . -addr :9090 or PORT=9090 go run .
 func main() {
+	cfg := server.DefaultConfig(":8080")
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Hello World!")
 	})
 	http.HandleFunc("/time", func(w http.ResponseWriter, r *http.Request) {
 		t := time.Now()
-		timeStr := fmt.Sprintf("{\"time\":\"%s\"}", t)
-		w.Write([]byte(timeStr))
+		switch response.Negotiate(r, response.ContentTypeJSON, response.ContentTypeText) {
+		case response.ContentTypeJSON:
+			// time.Time is encoded as RFC 3339, fmt.Sprintf could produce invalid JSON
+			response.JSON(w, http.StatusOK, map[string]time.Time{"time": t})
+		case response.ContentTypeText:
+			w.Header().Set("Content-Type", response.ContentTypeText)
+			fmt.Fprintln(w, t.Format(time.RFC3339Nano))
+		default:
+			response.WriteError(w, r, response.NotAcceptable(response.ContentTypeJSON, response.ContentTypeText))
+		}
 	})
 	http.HandleFunc("/test/", func(w http.ResponseWriter, r *http.Request) {
-		t := time.Now()
-		timeStr := fmt.Sprintf("{\"test\":\"%s\"}", t)
-		w.Write([]byte(timeStr))
+		response.JSON(w, http.StatusOK, map[string]time.Time{"test": time.Now()})
 	})
-	http.ListenAndServe(":8080", nil)
-}
\ No newline at end of file
+	if err := server.Run(context.Background(), cfg, http.DefaultServeMux, server.NewHealth()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AccessLogEntry is one line of the access log.
+type AccessLogEntry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	Status     int       `json:"status"`
+	Bytes      int       `json:"bytes"`
+	DurationMS float64   `json:"duration_ms"`
+	RemoteAddr string    `json:"remote_addr"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// AccessLog writes one JSON line per request to out.
+// Put it after RequestID to log the request ID.
+func AccessLog(out io.Writer) Middleware {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(out)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newResponseRecorder(w)
+			defer func() {
+				entry := AccessLogEntry{
+					Time:       start,
+					RequestID:  RequestIDFromContext(r.Context()),
+					Method:     r.Method,
+					Path:       r.URL.Path,
+					Query:      r.URL.RawQuery,
+					Status:     rec.status,
+					Bytes:      rec.bytes,
+					DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+					RemoteAddr: r.RemoteAddr,
+					UserAgent:  r.UserAgent(),
+				}
+				mu.Lock()
+				encoder.Encode(&entry)
+				mu.Unlock()
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
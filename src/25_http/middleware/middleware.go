@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+/*
+	Middleware 是把 http.Handler 包成另一個 http.Handler 的 function，用來處理與業務無關的 cross-cutting 行為。
+
+	* 包住整個 server（所有 route 共用）：
+		handler := middleware.Chain(middleware.RequestID(), middleware.AccessLog(os.Stdout))(router)
+		http.ListenAndServe(":8080", handler)
+
+	* 只包住某一個 httprouter route：
+		router.GET("/employees/:name", middleware.Handle(h.GetEmployeeByName, middleware.Timeout(time.Second)))
+		httprouter 的 Params 會先放進 request context（httprouter.ParamsKey），最後再取出來交給原本的 Handle
+
+	Chain(a, b, c)(h) 等於 a(b(c(h)))，request 會依照 a → b → c → h 的順序經過。
+*/
+
+type Middleware func(next http.Handler) http.Handler
+
+// Chain composes mws, the first one is the outermost.
+func Chain(mws ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+// Handle applies mws to a httprouter.Handle.
+func Handle(h httprouter.Handle, mws ...Middleware) httprouter.Handle {
+	wrapped := Chain(mws...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h(w, r, httprouter.ParamsFromContext(r.Context()))
+	}))
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := context.WithValue(r.Context(), httprouter.ParamsKey, ps)
+		wrapped.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// responseRecorder remembers the status code and the size of the body written through it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		r.wroteHeader = true
+		f.Flush()
+	}
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("middleware: the ResponseWriter does not support Hijack")
+}
@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"go_learning/src/25_http/response"
+)
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func tag(name string, trace *[]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*trace = append(*trace, name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var trace []string
+	h := Chain(tag("a", &trace), tag("b", &trace), tag("c", &trace))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace = append(trace, "handler")
+	}))
+	serve(h, httptest.NewRequest("GET", "/", nil))
+	if strings.Join(trace, ",") != "a,b,c,handler" {
+		t.Errorf("unexpected order %v", trace)
+	}
+}
+
+func TestHandleKeepsParams(t *testing.T) {
+	var trace []string
+	router := httprouter.New()
+	router.GET("/hello/:name", Handle(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		io.WriteString(w, "hello, "+ps.ByName("name"))
+	}, tag("a", &trace), RequestID()))
+
+	w := serve(router, httptest.NewRequest("GET", "/hello/Mike", nil))
+	if w.Body.String() != "hello, Mike" || len(trace) != 1 {
+		t.Errorf("unexpected %q %v", w.Body.String(), trace)
+	}
+	if w.Header().Get(RequestIDHeader) == "" {
+		t.Error("expected a request ID")
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFromContext(r.Context())
+	}))
+
+	w := serve(h, httptest.NewRequest("GET", "/", nil))
+	if got == "" || w.Header().Get(RequestIDHeader) != got {
+		t.Errorf("expected a generated ID, got %q and header %q", got, w.Header().Get(RequestIDHeader))
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	serve(h, req)
+	if got != "abc-123" {
+		t.Errorf("expected the ID of the client, got %q", got)
+	}
+
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	serve(h, req)
+	if got == "bad id\n" || got == "" {
+		t.Errorf("an invalid ID should be replaced, got %q", got)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	h := Chain(RequestID(), AccessLog(&out))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "12345")
+	}))
+	req := httptest.NewRequest("GET", "/tea?sugar=1", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	serve(h, req)
+
+	var entry AccessLogEntry
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log line %q: %v", out.String(), err)
+	}
+	if entry.Status != http.StatusTeapot || entry.Bytes != 5 || entry.Path != "/tea" ||
+		entry.Query != "sugar=1" || entry.RequestID != "req-1" || entry.Method != "GET" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var logOut bytes.Buffer
+	h := Chain(RequestID(), Recover(&logOut))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("something wrong")
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "req-2")
+	w := serve(h, req)
+
+	var body response.Envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error == nil {
+		t.Fatalf("invalid envelope %q: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusInternalServerError || body.Error.RequestID != "req-2" || body.Error.Code != "internal_server_error" ||
+		w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected %d %+v", w.Code, body.Error)
+	}
+	if strings.Contains(w.Body.String(), "something wrong") {
+		t.Error("the panic value should not be sent to the client")
+	}
+	if !strings.Contains(logOut.String(), "something wrong") {
+		t.Errorf("the panic should be logged, got %q", logOut.String())
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	h := Recover(io.Discard)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("expected ErrAbortHandler, got %v", v)
+		}
+	}()
+	serve(h, httptest.NewRequest("GET", "/", nil))
+}
+
+func TestGzip(t *testing.T) {
+	payload := strings.Repeat(`{"name":"Mike","age":35}`, 100)
+	h := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "2400")
+		io.WriteString(w, payload)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate, gzip")
+	w := serve(h, req)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected Vary, got %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != payload {
+		t.Errorf("unexpected body %q", b)
+	}
+
+	for _, accept := range []string{"", "deflate", "gzip;q=0"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		if w := serve(h, req); w.Header().Get("Content-Encoding") != "" || w.Body.String() != payload {
+			t.Errorf("Accept-Encoding %q should not be compressed", accept)
+		}
+	}
+}
+
+func TestGzipNoContent(t *testing.T) {
+	h := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	req := httptest.NewRequest("DELETE", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if w := serve(h, req); w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+		t.Errorf("204 should have no body, got %v %q", w.Header(), w.Body.Bytes())
+	}
+}
+
+func TestGzipWeakensETag(t *testing.T) {
+	h := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		if r.Header.Get("If-None-Match") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "hello")
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if w := serve(h, req); w.Header().Get("ETag") != `W/"abc"` {
+		t.Errorf("expected a weak ETag, got %v", w.Header())
+	}
+	req.Header.Set("If-None-Match", `W/"abc"`)
+	if w := serve(h, req); w.Code != http.StatusNotModified || w.Header().Get("ETag") != `W/"abc"` {
+		t.Errorf("expected a 304 with the weak ETag, got %d %v", w.Code, w.Header())
+	}
+	if w := serve(h, httptest.NewRequest("GET", "/", nil)); w.Header().Get("ETag") != `"abc"` {
+		t.Errorf("an uncompressed response should keep the strong ETag, got %v", w.Header())
+	}
+}
+
+func TestCORS(t *testing.T) {
+	called := false
+	h := CORS(CORSConfig{
+		AllowedOrigins: []string{"https://example.com"},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         10 * time.Minute,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("OPTIONS", "/employees", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := serve(h, req)
+	if w.Code != http.StatusNoContent || called {
+		t.Errorf("a preflight should be answered directly, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://example.com" ||
+		w.Header().Get("Access-Control-Max-Age") != "600" ||
+		!strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), "POST") {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+
+	req = httptest.NewRequest("GET", "/employees", nil)
+	req.Header.Set("Origin", "https://example.com")
+	w = serve(h, req)
+	if !called || w.Header().Get("Access-Control-Allow-Origin") != "https://example.com" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/employees", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	if w := serve(h, req); w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("unexpected %d %v", w.Code, w.Header())
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	h := CORS(CORSConfig{AllowedOrigins: []string{"*"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest("GET", "/employees", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	w := serve(h, req)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("the wildcard should be answered with a literal *, got %v", w.Header())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for the wildcard with credentials")
+		}
+	}()
+	CORS(CORSConfig{AllowedOrigins: []string{"https://example.com", "*"}, AllowCredentials: true})
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	w := serve(Timeout(10*time.Millisecond)(slow), httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected %d %v", w.Code, w.Header())
+	}
+	var body response.Envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error == nil || body.Error.Code != "timeout" {
+		t.Errorf("invalid envelope %q: %v", w.Body.String(), err)
+	}
+
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "ok")
+	})
+	w = serve(Timeout(time.Second)(fast), httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" || w.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("unexpected %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
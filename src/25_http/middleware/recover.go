@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+
+	"go_learning/src/25_http/response"
+)
+
+// Recover turns a panic of the handler into a 500 response.Envelope and writes the stack to logOut.
+// http.ErrAbortHandler is panicked again, net/http uses it to abort the response on purpose.
+func Recover(logOut io.Writer) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := newResponseRecorder(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				id := RequestIDFromContext(r.Context())
+				fmt.Fprintf(logOut, "panic serving %s %s (request_id=%s): %v\n%s", r.Method, r.URL.Path, id, v, debug.Stack())
+				if rec.wroteHeader {
+					// too late to change the status, drop the connection so the client sees a broken response
+					panic(http.ErrAbortHandler)
+				}
+				response.WriteError(w, r, response.NewError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)))
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go_learning/src/25_http/response"
+)
+
+var timeoutBody = func() string {
+	e := response.NewError(http.StatusServiceUnavailable, "request timeout")
+	e.Code = "timeout"
+	b, _ := json.Marshal(response.Envelope{Error: e})
+	return string(b)
+}()
+
+// timeoutJSONWriter labels the 503 body of http.TimeoutHandler as JSON,
+// TimeoutHandler copies the headers set by the handler before WriteHeader, so a handler's own Content-Type wins.
+type timeoutJSONWriter struct {
+	http.ResponseWriter
+}
+
+func (w timeoutJSONWriter) WriteHeader(status int) {
+	if status == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", response.ContentTypeJSON)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Timeout answers 503 with a response.Envelope when the handler doesn't finish within d,
+// the context of the request is cancelled at the same time.
+//
+// It is built on http.TimeoutHandler, which buffers the response and doesn't support Flush or Hijack.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		th := http.TimeoutHandler(next, d, timeoutBody)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			th.ServeHTTP(timeoutJSONWriter{w}, r)
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+
+	"go_learning/src/25_http/middleware"
+	"go_learning/src/25_http/response"
+)
+
+/*
+	註冊 route 時一併寫下它的 metadata，API 會：
+	1. 用 metadata 產生 OpenAPI 3 文件，放在 GET /openapi.json
+	2. validate 為 true 時，在呼叫 handler 之前先檢查 path、query 參數與 JSON body
+
+	api := openapi.New(router, openapi.Info{Title: "Employees", Version: "1.0.0"}, true)
+	api.Handle(openapi.Route{
+		Method:  "POST",
+		Path:    "/employees",
+		Summary: "Create an employee",
+		Request: Employee{},
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: Employee{}}},
+		Handle:  h.CreateEmployee,
+	})
+
+	驗證失敗時回傳 response.Envelope：
+	* JSON 語法錯誤、型別不對、缺少 required 欄位、未知的欄位、參數錯誤 → 400 bad_request
+	* 格式正確但違反限制（pattern、minimum、maxLength…）→ 422 validation_failed
+	details 是每個欄位（例如 "items[0].name"）的錯誤原因。
+
+	handler 讀到的 r.Body 仍是原本的內容，所以 handler 自己的 decode 與驗證不需要改。
+*/
+
+const (
+	DocumentPath = "/openapi.json"
+
+	DefaultMaxBodyBytes = 1 << 20
+)
+
+// Param describes a path or query parameter.
+type Param struct {
+	Name string
+	// In is "path" or "query"
+	In          string
+	Description string
+	// Required is implied for path parameters
+	Required bool
+	// Schema is a string without constraints when nil
+	Schema *Schema
+}
+
+// Reply describes one response of a route.
+type Reply struct {
+	Status      int
+	Description string
+	// Body is a value of the type of the JSON body, nil for a response without a body
+	Body interface{}
+}
+
+// Route is a httprouter route with its metadata.
+type Route struct {
+	Method string
+	// Path uses the httprouter syntax, /employees/:name
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	// Params are declared automatically for the path parameters without a Param
+	Params []Param
+	// Request is a value of the type of the JSON body, nil for a request without a body
+	Request interface{}
+	Replies []Reply
+	Handle  httprouter.Handle
+	// Middlewares run before the validation
+	Middlewares []middleware.Middleware
+}
+
+// API registers routes on a httprouter.Router and documents them.
+type API struct {
+	router   *httprouter.Router
+	validate bool
+	// MaxBodyBytes limits the bodies read by the validation
+	MaxBodyBytes int64
+
+	mu  sync.RWMutex
+	gen *generator
+	doc Document
+}
+
+// New serves the document of the routes at DocumentPath of router,
+// every route should be registered before serving.
+func New(router *httprouter.Router, info Info, validate bool) *API {
+	a := &API{
+		router:       router,
+		validate:     validate,
+		MaxBodyBytes: DefaultMaxBodyBytes,
+		gen:          newGenerator(),
+		doc:          Document{OpenAPI: "3.0.3", Info: info, Paths: map[string]PathItem{}},
+	}
+	router.Handler(http.MethodGet, DocumentPath, a.DocumentHandler())
+	return a
+}
+
+// Document returns the document of the routes registered so far.
+func (a *API) Document() *Document {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	doc := a.doc
+	doc.Components.Schemas = a.gen.schemas
+	return &doc
+}
+
+// DocumentHandler serves the document, New already registers it at DocumentPath.
+func (a *API) DocumentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, http.StatusOK, a.Document())
+	})
+}
+
+var pathParamPattern = regexp.MustCompile(`[:*]([^/]+)`)
+
+// Handle registers the route, like httprouter it panics on an invalid route.
+func (a *API) Handle(route Route) {
+	op, params, body, err := a.operation(route)
+	if err != nil {
+		panic(fmt.Sprintf("openapi: %s %s: %v", route.Method, route.Path, err))
+	}
+
+	a.mu.Lock()
+	path := pathParamPattern.ReplaceAllString(route.Path, "{$1}")
+	item := a.doc.Paths[path]
+	if item == nil {
+		item = PathItem{}
+		a.doc.Paths[path] = item
+	}
+	item[strings.ToLower(route.Method)] = op
+	a.mu.Unlock()
+
+	h := route.Handle
+	if a.validate {
+		h = a.validated(h, params, body, route.Request != nil)
+	}
+	a.router.Handle(route.Method, route.Path, middleware.Handle(h, route.Middlewares...))
+}
+
+func (a *API) operation(route Route) (*Operation, []Parameter, *Schema, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	op := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Tags:        route.Tags,
+		Responses:   map[string]Response{},
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(route.Method, route.Path)
+	}
+
+	declared := map[string]bool{}
+	for _, p := range route.Params {
+		param := Parameter{Name: p.Name, In: p.In, Description: p.Description, Required: p.Required || p.In == "path", Schema: p.Schema}
+		if p.In != "path" && p.In != "query" {
+			return nil, nil, nil, fmt.Errorf("parameter %s: unsupported location %q", p.Name, p.In)
+		}
+		if param.Schema == nil {
+			param.Schema = &Schema{Type: "string"}
+		}
+		if param.Schema.Pattern != "" && param.Schema.pattern == nil {
+			pattern, err := regexp.Compile(param.Schema.Pattern)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("parameter %s: %w", p.Name, err)
+			}
+			param.Schema.pattern = pattern
+		}
+		declared[p.In+":"+p.Name] = true
+		op.Parameters = append(op.Parameters, param)
+	}
+	for _, m := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		if !declared["path:"+m[1]] {
+			op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	body, err := a.gen.schemaOf(route.Request)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if body != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{response.ContentTypeJSON: {Schema: body}}}
+	}
+
+	replies := route.Replies
+	if len(replies) == 0 {
+		replies = []Reply{{Status: http.StatusOK}}
+	}
+	for _, reply := range replies {
+		resp := Response{Description: reply.Description}
+		if resp.Description == "" {
+			resp.Description = http.StatusText(reply.Status)
+		}
+		s, err := a.gen.schemaOf(reply.Body)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if s != nil {
+			resp.Content = map[string]MediaType{response.ContentTypeJSON: {Schema: s}}
+		}
+		op.Responses[strconv.Itoa(reply.Status)] = resp
+	}
+	envelope, err := a.gen.schemaOf(response.Envelope{})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	op.Responses["default"] = Response{Description: "Error", Content: map[string]MediaType{response.ContentTypeJSON: {Schema: envelope}}}
+	return op, op.Parameters, body, nil
+}
+
+// operationID turns GET /employees/:name into get_employees_name.
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.Split(path, "/") {
+		part = strings.TrimLeft(part, ":*")
+		if part != "" {
+			id += "_" + part
+		}
+	}
+	return id
+}
+
+// validated checks the parameters and the body before calling h.
+func (a *API) validated(h httprouter.Handle, params []Parameter, body *Schema, hasBody bool) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if err := a.validateParams(r, ps, params); err != nil {
+			response.WriteError(w, r, err)
+			return
+		}
+		if hasBody {
+			data, err := a.readBody(r)
+			if err == nil {
+				err = a.validateBody(data, body)
+			}
+			if err != nil {
+				response.WriteError(w, r, err)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(data))
+		}
+		h(w, r, ps)
+	}
+}
+
+func (a *API) validateParams(r *http.Request, ps httprouter.Params, params []Parameter) error {
+	v := validator{schemas: a.gen.schemas}
+	query := r.URL.Query()
+	for _, p := range params {
+		text := ps.ByName(p.Name)
+		if p.In == "query" {
+			text = query.Get(p.Name)
+		}
+		if text == "" {
+			if p.Required {
+				v.fail(p.Name, false, "is required")
+			}
+			continue
+		}
+		value, ok := parseParam(p.Schema, text)
+		if !ok {
+			v.fail(p.Name, false, "should be %s", article(p.Schema.Type))
+			continue
+		}
+		v.validate(p.Schema, value, p.Name)
+	}
+	if len(v.violations) == 0 {
+		return nil
+	}
+	// a parameter always gets 400, the handler never sees a request it can't route
+	ve := &ValidationError{In: "parameters", Violations: v.violations}
+	return &response.Error{Status: http.StatusBadRequest, Code: response.CodeOf(http.StatusBadRequest), Message: ve.Error(), Details: ve.Fields(), Err: ve}
+}
+
+func article(typ string) string {
+	if typ == "integer" {
+		return "an integer"
+	}
+	return "a " + typ
+}
+
+func (a *API) readBody(r *http.Request) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, a.MaxBodyBytes+1))
+	r.Body.Close()
+	if err != nil {
+		e := response.NewError(http.StatusBadRequest, "can't read the request body")
+		e.Err = err
+		return nil, e
+	}
+	if int64(len(data)) > a.MaxBodyBytes {
+		return nil, response.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("the request body is larger than %d bytes", a.MaxBodyBytes))
+	}
+	return data, nil
+}
+
+func (a *API) validateBody(data []byte, s *Schema) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return response.NewError(http.StatusBadRequest, "the request body is required")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err == nil && decoder.More() {
+		err = fmt.Errorf("unexpected data after the JSON value")
+	}
+	if err != nil {
+		e := response.NewError(http.StatusBadRequest, "malformed JSON body: "+err.Error())
+		e.Err = err
+		return e
+	}
+
+	v := validator{schemas: a.gen.schemas}
+	v.validate(s, value, "")
+	if len(v.violations) == 0 {
+		return nil
+	}
+	ve := &ValidationError{In: "body", Violations: v.violations}
+	if ve.ConstraintsOnly() {
+		return &response.Error{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Message: "validation failed", Details: ve.Fields(), Err: ve}
+	}
+	return &response.Error{Status: http.StatusBadRequest, Code: response.CodeOf(http.StatusBadRequest), Message: ve.Error(), Details: ve.Fields(), Err: ve}
+}
@@ -0,0 +1,227 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type address struct {
+	City string `json:"city" openapi:"required;minLength=1"`
+}
+
+type person struct {
+	Name     string    `json:"name" openapi:"required;pattern=^[A-Z][a-z]+$"`
+	Age      int       `json:"age,omitempty" openapi:"minimum=0;maximum=150"`
+	Role     string    `json:"role,omitempty" openapi:"enum=admin|user"`
+	Tags     []string  `json:"tags,omitempty"`
+	Address  *address  `json:"address,omitempty"`
+	Born     time.Time `json:"born,omitempty"`
+	Friends  []person  `json:"friends,omitempty"`
+	Password string    `json:"-"`
+	internal int
+}
+
+func TestSchemaOf(t *testing.T) {
+	g := newGenerator()
+	s, err := g.schemaOf(person{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Ref != "#/components/schemas/person" {
+		t.Fatalf("unexpected %+v", s)
+	}
+	p := g.schemas["person"]
+	if p.Type != "object" || p.AdditionalProperties != false || len(p.Required) != 1 || p.Required[0] != "name" {
+		t.Errorf("unexpected %+v", p)
+	}
+	if len(p.Properties) != 7 {
+		t.Errorf("expected 7 properties, got %v", p.Properties)
+	}
+	if age := p.Properties["age"]; age.Type != "integer" || *age.Minimum != 0 || *age.Maximum != 150 {
+		t.Errorf("unexpected age %+v", age)
+	}
+	if born := p.Properties["born"]; born.Type != "string" || born.Format != "date-time" {
+		t.Errorf("unexpected born %+v", born)
+	}
+	if addr := p.Properties["address"]; !addr.Nullable || addr.AllOf[0].Ref != "#/components/schemas/address" {
+		t.Errorf("unexpected address %+v", addr)
+	}
+	// recursive
+	if friends := p.Properties["friends"]; friends.Items.Ref != "#/components/schemas/person" {
+		t.Errorf("unexpected friends %+v", friends)
+	}
+
+	if _, err := g.schemaOf(struct {
+		X int `openapi:"minimum=x"`
+	}{}); err == nil {
+		t.Error("expected an error of the invalid tag")
+	}
+	if _, err := g.schemaOf(map[int]string{}); err == nil {
+		t.Error("expected an error of the int keys")
+	}
+}
+
+func newTestAPI() (*API, *httprouter.Router) {
+	router := httprouter.New()
+	api := New(router, Info{Title: "People", Version: "1.0.0"}, true)
+	ok := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}
+	api.Handle(Route{
+		Method:  "POST",
+		Path:    "/people",
+		Summary: "Create a person",
+		Request: person{},
+		Replies: []Reply{{Status: http.StatusCreated, Body: person{}}},
+		Handle:  ok,
+	})
+	api.Handle(Route{
+		Method: "GET",
+		Path:   "/people/:name",
+		Params: []Param{
+			{Name: "name", In: "path", Schema: &Schema{Type: "string", Pattern: "^[A-Z][a-z]+$"}},
+			{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Minimum: new(float64)}},
+		},
+		Replies: []Reply{{Status: http.StatusOK, Body: person{}}},
+		Handle:  ok,
+	})
+	return api, router
+}
+
+func TestDocument(t *testing.T) {
+	_, router := newTestAPI()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", DocumentPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected %d", w.Code)
+	}
+	var doc Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info.Title != "People" {
+		t.Errorf("unexpected %+v", doc)
+	}
+	create := doc.Paths["/people"]["post"]
+	if create == nil || create.Summary != "Create a person" || create.OperationID != "post_people" {
+		t.Fatalf("unexpected %+v", doc.Paths)
+	}
+	if create.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/person" {
+		t.Errorf("unexpected request body %+v", create.RequestBody)
+	}
+	if _, ok := create.Responses["201"]; !ok {
+		t.Errorf("expected 201 in %+v", create.Responses)
+	}
+	if create.Responses["default"].Content["application/json"].Schema.Ref != "#/components/schemas/Envelope" {
+		t.Errorf("expected the error envelope in %+v", create.Responses)
+	}
+	get := doc.Paths["/people/{name}"]["get"]
+	if get == nil || len(get.Parameters) != 2 || !get.Parameters[0].Required || get.Parameters[1].In != "query" {
+		t.Fatalf("unexpected %+v", get)
+	}
+	for _, name := range []string{"person", "address", "Envelope", "Error"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("expected schema %s in %v", name, doc.Components.Schemas)
+		}
+	}
+}
+
+func TestPathParamsAreDeclared(t *testing.T) {
+	api := New(httprouter.New(), Info{}, false)
+	api.Handle(Route{Method: "GET", Path: "/a/:x/b/*rest", Handle: func(http.ResponseWriter, *http.Request, httprouter.Params) {}})
+	get := api.Document().Paths["/a/{x}/b/{rest}"]["get"]
+	if get == nil || len(get.Parameters) != 2 || get.Parameters[1].Name != "rest" || !get.Parameters[1].Required {
+		t.Fatalf("unexpected %+v", api.Document().Paths)
+	}
+}
+
+type errorEnvelope struct {
+	Error struct {
+		Code    string            `json:"code"`
+		Details map[string]string `json:"details"`
+	} `json:"error"`
+}
+
+func TestValidation(t *testing.T) {
+	_, router := newTestAPI()
+	tests := []struct {
+		method, url, body string
+		status            int
+		code              string
+		fields            []string
+	}{
+		{"POST", "/people", `{"name":"Ann","age":30,"address":{"city":"Taipei"},"tags":["a"]}`, 200, "", nil},
+		{"POST", "/people", `{"name":"Ann","address":null}`, 200, "", nil},
+		{"POST", "/people", ``, 400, "bad_request", nil},
+		{"POST", "/people", `{"name":`, 400, "bad_request", nil},
+		{"POST", "/people", `{"name":"Ann"} {}`, 400, "bad_request", nil},
+		{"POST", "/people", `[]`, 400, "bad_request", []string{"body"}},
+		{"POST", "/people", `{"age":1}`, 400, "bad_request", []string{"name"}},
+		{"POST", "/people", `{"name":"Ann","salary":1}`, 400, "bad_request", []string{"salary"}},
+		{"POST", "/people", `{"name":"Ann","age":"1"}`, 400, "bad_request", []string{"age"}},
+		{"POST", "/people", `{"name":"Ann","age":1.5}`, 400, "bad_request", []string{"age"}},
+		{"POST", "/people", `{"name":"Ann","address":{}}`, 400, "bad_request", []string{"address.city"}},
+		{"POST", "/people", `{"name":"Ann","born":"yesterday"}`, 400, "bad_request", []string{"born"}},
+		{"POST", "/people", `{"name":"ann","age":200,"role":"root"}`, 422, "validation_failed", []string{"name", "age", "role"}},
+		{"POST", "/people", `{"name":"Ann","friends":[{"name":"Bob"},{"name":"x"}]}`, 422, "validation_failed", []string{"friends[1].name"}},
+		{"POST", "/people", `{"name":"Ann","address":{"city":""}}`, 422, "validation_failed", []string{"address.city"}},
+		{"GET", "/people/Ann?limit=10", ``, 200, "", nil},
+		{"GET", "/people/ann", ``, 400, "bad_request", []string{"name"}},
+		{"GET", "/people/Ann?limit=x", ``, 400, "bad_request", []string{"limit"}},
+		{"GET", "/people/Ann?limit=-1", ``, 400, "bad_request", []string{"limit"}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+		if w.Code != tt.status {
+			t.Errorf("%s %s %s: expected %d, got %d %s", tt.method, tt.url, tt.body, tt.status, w.Code, w.Body)
+			continue
+		}
+		if tt.status == http.StatusOK {
+			// the handler reads the original body
+			if w.Body.String() != tt.body {
+				t.Errorf("%s: the handler got %q", tt.body, w.Body)
+			}
+			continue
+		}
+		var body errorEnvelope
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Error.Code != tt.code {
+			t.Errorf("%s: expected %s, got %+v", tt.body, tt.code, body)
+		}
+		for _, field := range tt.fields {
+			if body.Error.Details[field] == "" {
+				t.Errorf("%s: expected error of %s in %+v", tt.body, field, body)
+			}
+		}
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	api, router := newTestAPI()
+	api.MaxBodyBytes = 16
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/people", strings.NewReader(`{"name":"Annabelle-Lee"}`)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestInvalidRoutePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	New(httprouter.New(), Info{}, true).Handle(Route{Method: "GET", Path: "/", Params: []Param{{Name: "x", In: "header"}}})
+}
@@ -0,0 +1,245 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	Go type → Schema：
+
+	string → string、int* → integer、uint* → integer（minimum 0）、float* → number、bool → boolean
+	[]T → array、[]byte → string（format byte，encoding/json 用 base64）、map[string]T → object
+	*T → T 加上 nullable、time.Time → string（format date-time）、interface{} → 任何值
+	named struct → 放進 components.schemas，用 $ref 引用，這樣遞迴的型別也沒問題
+
+	struct 的欄位名稱與是否略過依照 json tag，其他的限制寫在 openapi tag，用 ; 分隔：
+		Age int `json:"age" openapi:"minimum=16;maximum=100;description=in years"`
+	支援 required、pattern、minLength、maxLength、minimum、maximum、enum（用 | 分隔）、format、description
+*/
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// schemaOf returns the schema of the type of v, nil for a nil v.
+func (g *generator) schemaOf(v interface{}) (*Schema, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *generator) schema(t reflect.Type) (*Schema, error) {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case rawJSONType:
+		return &Schema{}, nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		s, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		if s.Ref != "" {
+			// siblings of $ref are ignored in OpenAPI 3.0, so wrap it
+			return &Schema{Nullable: true, AllOf: []*Schema{s}}, nil
+		}
+		s.Nullable = true
+		return s, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}, nil
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}, nil
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}, nil
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("openapi: the keys of %v should be strings", t)
+		}
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.ref(t)
+	}
+	return nil, fmt.Errorf("openapi: unsupported type %v", t)
+}
+
+// ref puts the schema of the named struct t into the components.
+func (g *generator) ref(t reflect.Type) (*Schema, error) {
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.schemas[name]; taken {
+			name = strings.ReplaceAll(t.String(), ".", "_")
+		}
+		g.names[t] = name
+		// a placeholder, so a recursive type refers to itself instead of looping
+		g.schemas[name] = &Schema{}
+		s, err := g.structSchema(t)
+		if err != nil {
+			delete(g.names, t)
+			delete(g.schemas, name)
+			return nil, err
+		}
+		*g.schemas[name] = *s
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}, nil
+}
+
+func (g *generator) structSchema(t reflect.Type) (*Schema, error) {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	if err := g.addFields(s, t); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (g *generator) addFields(s *Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.IndexByte(tag, ','); comma >= 0 {
+			name, options = tag[:comma], tag[comma+1:]
+		}
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := g.addFields(s, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs, err := g.schema(f.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+		if strings.Contains(","+options+",", ",string,") {
+			fs = &Schema{Type: "string", Nullable: fs.Nullable}
+		}
+		required, err := applyTag(fs, f.Tag.Get("openapi"))
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+// applyTag adds the constraints of an openapi tag to s, and reports whether the field is required.
+func applyTag(s *Schema, tag string) (bool, error) {
+	if tag == "" {
+		return false, nil
+	}
+	if s.Ref != "" {
+		return false, fmt.Errorf("openapi: constraints can't be added to a $ref")
+	}
+	required := false
+	for _, item := range strings.Split(tag, ";") {
+		key, value := item, ""
+		if eq := strings.IndexByte(item, '='); eq >= 0 {
+			key, value = item[:eq], item[eq+1:]
+		}
+		var err error
+		switch strings.TrimSpace(key) {
+		case "required":
+			required = true
+		case "description":
+			s.Description = value
+		case "format":
+			s.Format = value
+		case "pattern":
+			s.Pattern = value
+			s.pattern, err = regexp.Compile(value)
+		case "minLength":
+			s.MinLength, err = parseInt(value)
+		case "maxLength":
+			s.MaxLength, err = parseInt(value)
+		case "minimum":
+			s.Minimum, err = parseFloat(value)
+		case "maximum":
+			s.Maximum, err = parseFloat(value)
+		case "enum":
+			for _, v := range strings.Split(value, "|") {
+				s.Enum = append(s.Enum, v)
+			}
+		default:
+			err = fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			return false, fmt.Errorf("openapi tag %q: %w", tag, err)
+		}
+	}
+	return required, nil
+}
+
+func parseInt(s string) (*int, error) {
+	n, err := strconv.Atoi(s)
+	return &n, err
+}
+
+func parseFloat(s string) (*float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	return &f, err
+}
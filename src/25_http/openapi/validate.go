@@ -0,0 +1,244 @@
+package openapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Violation is one way a value doesn't match its schema.
+type Violation struct {
+	// Path is like "items[0].name", "" for the value itself
+	Path    string
+	Message string
+	// Constraint is false when the value has the wrong shape (type, required or unknown property),
+	// true when it has the right shape and breaks a constraint (pattern, minimum...)
+	Constraint bool
+}
+
+// ValidationError lists every violation of a value.
+type ValidationError struct {
+	// In is "body", "path" or "query"
+	In         string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	var strs []string
+	for _, v := range e.Violations {
+		if v.Path == "" {
+			strs = append(strs, v.Message)
+		} else {
+			strs = append(strs, v.Path+": "+v.Message)
+		}
+	}
+	return "invalid " + e.In + ": " + strings.Join(strs, "; ")
+}
+
+// Fields maps each path to its message.
+func (e *ValidationError) Fields() map[string]string {
+	fields := map[string]string{}
+	for _, v := range e.Violations {
+		path := v.Path
+		if path == "" {
+			path = e.In
+		}
+		if _, ok := fields[path]; !ok {
+			fields[path] = v.Message
+		}
+	}
+	return fields
+}
+
+// ConstraintsOnly reports whether the value had the right shape, 422 rather than 400.
+func (e *ValidationError) ConstraintsOnly() bool {
+	for _, v := range e.Violations {
+		if !v.Constraint {
+			return false
+		}
+	}
+	return true
+}
+
+type validator struct {
+	schemas    map[string]*Schema
+	violations []Violation
+}
+
+func (v *validator) fail(path string, constraint bool, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Path: path, Message: fmt.Sprintf(format, args...), Constraint: constraint})
+}
+
+func (v *validator) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = v.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// validate checks value decoded by a json.Decoder with UseNumber.
+func (v *validator) validate(s *Schema, value interface{}, path string) {
+	s = v.resolve(s)
+	if s == nil {
+		return
+	}
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			v.fail(path, false, "should not be null")
+		}
+		return
+	}
+	for _, sub := range s.AllOf {
+		v.validate(sub, value, path)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.fail(path, false, "should be an object")
+			return
+		}
+		v.validateObject(s, obj, path)
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			v.fail(path, false, "should be an array")
+			return
+		}
+		for i, item := range arr {
+			v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			v.fail(path, false, "should be a string")
+			return
+		}
+		v.validateString(s, str, path)
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			v.fail(path, false, "should be a number")
+			return
+		}
+		v.validateNumber(s, num, path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.fail(path, false, "should be a boolean")
+		}
+	}
+	if len(s.Enum) > 0 {
+		str := fmt.Sprint(value)
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == str {
+				return
+			}
+		}
+		v.fail(path, true, "should be one of %v", s.Enum)
+	}
+}
+
+func (v *validator) validateObject(s *Schema, obj map[string]interface{}, path string) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			v.fail(join(path, name), false, "is required")
+		}
+	}
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ps, ok := s.Properties[name]; ok {
+			v.validate(ps, obj[name], join(path, name))
+			continue
+		}
+		switch extra := s.AdditionalProperties.(type) {
+		case bool:
+			if !extra {
+				v.fail(join(path, name), false, "is not a known field")
+			}
+		case *Schema:
+			v.validate(extra, obj[name], join(path, name))
+		}
+	}
+}
+
+func (v *validator) validateString(s *Schema, str, path string) {
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		v.fail(path, true, "should have at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		v.fail(path, true, "should have at most %d characters", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		pattern := s.pattern
+		if pattern == nil {
+			// Schema values built by hand, compiled every time rather than stored to stay safe for concurrent use
+			pattern = regexp.MustCompile(s.Pattern)
+		}
+		if !pattern.MatchString(str) {
+			v.fail(path, true, "should match %s", s.Pattern)
+		}
+	}
+	switch s.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			v.fail(path, false, "should be an RFC 3339 date-time")
+		}
+	case "byte":
+		if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+			v.fail(path, false, "should be base64")
+		}
+	}
+}
+
+func (v *validator) validateNumber(s *Schema, num json.Number, path string) {
+	f, err := num.Float64()
+	if err != nil {
+		v.fail(path, false, "should be a number")
+		return
+	}
+	if s.Type == "integer" && (f != math.Trunc(f) || math.IsInf(f, 0)) {
+		v.fail(path, false, "should be an integer")
+		return
+	}
+	if s.Minimum != nil && f < *s.Minimum {
+		v.fail(path, true, "should be at least %v", *s.Minimum)
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		v.fail(path, true, "should be at most %v", *s.Maximum)
+	}
+}
+
+// parseParam converts the text of a path or query parameter to the JSON value of its schema.
+func parseParam(s *Schema, text string) (interface{}, bool) {
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(text), true
+	case "boolean":
+		b, err := strconv.ParseBool(text)
+		return b, err == nil
+	}
+	return text, true
+}
@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+	Token bucket：
+	* bucket 最多放 Burst 個 token，每 Per/Requests 補回一個
+	* 每個 request 拿走一個 token，拿不到就回 429，Retry-After 是補回下一個 token 需要的時間
+	* 所以短時間內最多可以連續 Burst 個 request，長時間平均不會超過 Requests/Per
+
+	Limiter 用 key（client IP 或認證後的 principal）各自一個 bucket，
+	已經補滿的 bucket 與新建的 bucket 沒有差別，所以會定期清掉，map 不會無限長大。
+*/
+
+// Clock lets tests control the refill of the buckets.
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is the Clock backed by package time.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// Rate allows Requests per Per on average and Burst at once, Burst defaults to Requests.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+func PerSecond(n int) Rate {
+	return Rate{Requests: n, Per: time.Second}
+}
+
+func PerMinute(n int) Rate {
+	return Rate{Requests: n, Per: time.Minute}
+}
+
+func (r Rate) enabled() bool {
+	return r.Requests > 0 && r.Per > 0
+}
+
+func (r Rate) burst() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Requests)
+}
+
+// interval is the time to refill one token.
+func (r Rate) interval() time.Duration {
+	return r.Per / time.Duration(r.Requests)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps a token bucket per key, it is safe for concurrent use.
+type Limiter struct {
+	rate      Rate
+	clock     Clock
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(rate Rate, clock Clock) *Limiter {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	return &Limiter{rate: rate, clock: clock, buckets: map[string]*bucket{}, lastSweep: clock.Now()}
+}
+
+// Result is the outcome of Allow.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the wait for the next token when not Allowed
+	RetryAfter time.Duration
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	elapsed := now.Sub(b.last)
+	if elapsed > 0 {
+		b.tokens = math.Min(l.rate.burst(), b.tokens+float64(elapsed)/float64(l.rate.interval()))
+		b.last = now
+	}
+}
+
+// Allow takes a token from the bucket of key.
+func (l *Limiter) Allow(key string) Result {
+	burst := l.rate.burst()
+	if !l.rate.enabled() {
+		return Result{Allowed: true}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+
+	result := Result{Limit: int(burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(l.rate.interval()))
+	}
+	result.Remaining = int(b.tokens)
+	return result
+}
+
+// sweep drops the buckets that have been refilled completely, at most once per the time to fill a bucket.
+func (l *Limiter) sweep(now time.Time) {
+	fill := time.Duration(l.rate.burst() * float64(l.rate.interval()))
+	if now.Sub(l.lastSweep) < fill {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= fill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Len is the number of tracked keys.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_learning/src/25_http/auth"
+	"go_learning/src/25_http/middleware"
+	"go_learning/src/25_http/response"
+)
+
+// KeyFunc returns the key whose bucket a request uses.
+type KeyFunc func(r *http.Request) string
+
+// ByIP uses the host of RemoteAddr.
+// Behind a reverse proxy every client has the proxy's address, the proxy should limit then.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ByPrincipal uses the caller authenticated by auth.Require or auth.Authenticate, put the limit after them.
+// Keying on a credential before authentication would let random keys bypass the limit and grow the buckets
+// without bound, so limit the unauthenticated requests ByIP in front of the authentication instead.
+// Requests without a principal, or with one not authenticated by credentials like auth.Static, use ByIP.
+func ByPrincipal(r *http.Request) string {
+	if p := auth.PrincipalFromContext(r.Context()); p != nil && p.Method != "" {
+		return "principal:" + p.Subject
+	}
+	return ByIP(r)
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
+
+func writeLimited(w http.ResponseWriter, r *http.Request, status int, retryAfter time.Duration, message string) {
+	w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+	response.WriteError(w, r, response.NewError(status, message))
+}
+
+// RateLimit answers 429 with Retry-After when the bucket of the request's key is empty.
+func RateLimit(l *Limiter, key KeyFunc) middleware.Middleware {
+	if key == nil {
+		key = ByIP
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := l.Allow(key(r))
+			if result.Limit > 0 {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			}
+			if !result.Allowed {
+				writeLimited(w, r, http.StatusTooManyRequests, result.RetryAfter, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// InFlight answers 503 with Retry-After when max requests are already being served,
+// it doesn't queue, a saturated server had better shed the load at once.
+func InFlight(max int, retryAfter time.Duration) middleware.Middleware {
+	slots := make(chan struct{}, max)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				writeLimited(w, r, http.StatusServiceUnavailable, retryAfter, "too many requests in flight")
+				return
+			}
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Policy is the limits of a route, zero values disable a limit.
+type Policy struct {
+	Rate Rate
+	// ByIP by default
+	Key         KeyFunc
+	MaxInFlight int
+	// Retry-After of the in-flight limit, 1s by default
+	RetryAfter time.Duration
+	Clock      Clock
+}
+
+// Middleware applies the rate limit first, so a rejected request never takes an in-flight slot.
+func (p Policy) Middleware() middleware.Middleware {
+	var mws []middleware.Middleware
+	if p.Rate.enabled() {
+		mws = append(mws, RateLimit(NewLimiter(p.Rate, p.Clock), p.Key))
+	}
+	if p.MaxInFlight > 0 {
+		retryAfter := p.RetryAfter
+		if retryAfter <= 0 {
+			retryAfter = time.Second
+		}
+		mws = append(mws, InFlight(p.MaxInFlight, retryAfter))
+	}
+	return middleware.Chain(mws...)
+}
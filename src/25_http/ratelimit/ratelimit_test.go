@@ -0,0 +1,260 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go_learning/src/25_http/auth"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestLimiterBurstAndRefill(t *testing.T) {
+	clock := newFakeClock()
+	l := NewLimiter(Rate{Requests: 2, Per: time.Second, Burst: 3}, clock)
+	for i := 0; i < 3; i++ {
+		if r := l.Allow("a"); !r.Allowed || r.Remaining != 2-i {
+			t.Fatalf("request %d: unexpected %+v", i, r)
+		}
+	}
+	r := l.Allow("a")
+	if r.Allowed || r.RetryAfter != 500*time.Millisecond {
+		t.Errorf("expected a 500ms wait, got %+v", r)
+	}
+	// other keys have their own bucket
+	if !l.Allow("b").Allowed {
+		t.Error("b should be allowed")
+	}
+
+	clock.Advance(250 * time.Millisecond)
+	if r := l.Allow("a"); r.Allowed || r.RetryAfter != 250*time.Millisecond {
+		t.Errorf("expected a 250ms wait, got %+v", r)
+	}
+	clock.Advance(250 * time.Millisecond)
+	if !l.Allow("a").Allowed {
+		t.Error("a token should be refilled")
+	}
+
+	// never more than the burst
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		l.Allow("a")
+	}
+	if l.Allow("a").Allowed {
+		t.Error("the bucket should hold at most 3 tokens")
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	clock := newFakeClock()
+	l := NewLimiter(PerSecond(10), clock)
+	for i := 0; i < 100; i++ {
+		l.Allow(fmt.Sprint(i))
+	}
+	if l.Len() != 100 {
+		t.Fatalf("expected 100 buckets, got %d", l.Len())
+	}
+	clock.Advance(500 * time.Millisecond)
+	l.Allow("0")
+	if l.Len() != 100 {
+		t.Errorf("buckets that aren't full should be kept, got %d", l.Len())
+	}
+	clock.Advance(time.Second)
+	l.Allow("new")
+	if l.Len() != 1 {
+		t.Errorf("full buckets should be dropped, got %d", l.Len())
+	}
+}
+
+func TestDisabledRate(t *testing.T) {
+	l := NewLimiter(Rate{}, newFakeClock())
+	for i := 0; i < 100; i++ {
+		if !l.Allow("a").Allowed {
+			t.Fatal("a zero Rate should not limit")
+		}
+	}
+}
+
+func request(remoteAddr, apiKey string) *http.Request {
+	r := httptest.NewRequest("GET", "/fb", nil)
+	r.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		r.Header.Set(auth.APIKeyHeader, apiKey)
+	}
+	return r
+}
+
+func TestKeyFuncs(t *testing.T) {
+	if ByIP(request("10.0.0.1:1234", "")) != ByIP(request("10.0.0.1:5678", "")) {
+		t.Error("the port should be ignored")
+	}
+	keys := apiKeys()
+	principal := func(authn auth.Authenticator, r *http.Request) string {
+		var key string
+		auth.Authenticate(authn)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key = ByPrincipal(r)
+		})).ServeHTTP(httptest.NewRecorder(), r)
+		return key
+	}
+	if principal(keys, request("10.0.0.1:1234", "k1")) == principal(keys, request("10.0.0.1:1234", "k2")) {
+		t.Error("different principals should have different buckets")
+	}
+	if principal(keys, request("10.0.0.1:1234", "k1")) != principal(keys, request("10.0.0.2:1234", "k1")) {
+		t.Error("a principal should have one bucket on every IP")
+	}
+	if principal(keys, request("10.0.0.1:1234", "")) != ByIP(request("10.0.0.1:1", "")) {
+		t.Error("without a principal the IP should be used")
+	}
+	static := auth.Static(auth.Principal{Subject: "anonymous"})
+	if principal(static, request("10.0.0.1:1234", "")) != ByIP(request("10.0.0.1:1", "")) {
+		t.Error("a principal without credentials should be limited by IP")
+	}
+	if ByPrincipal(request("10.0.0.1:1234", "k1")) != ByIP(request("10.0.0.1:1", "")) {
+		t.Error("an API key not authenticated yet should be ignored")
+	}
+}
+
+func apiKeys() *auth.APIKeys {
+	keys := auth.NewAPIKeys()
+	keys.Add("k1", "alice")
+	keys.Add("k2", "bob")
+	return keys
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	clock := newFakeClock()
+	h := RateLimit(NewLimiter(Rate{Requests: 1, Per: 10 * time.Second, Burst: 1}, clock), ByIP)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request("10.0.0.1:1", ""))
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request("10.0.0.1:2", ""))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "10" {
+		t.Errorf("unexpected %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request("10.0.0.2:1", ""))
+	if w.Code != http.StatusOK {
+		t.Errorf("another client should be allowed, got %d", w.Code)
+	}
+
+	clock.Advance(9500 * time.Millisecond)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request("10.0.0.1:2", ""))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After should be rounded up, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	h := InFlight(2, 3*time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, request("10.0.0.1:1", ""))
+			codes <- w.Code
+		}()
+		<-entered
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request("10.0.0.1:1", ""))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "3" {
+		t.Errorf("unexpected %d %v", w.Code, w.Header())
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("unexpected %d", code)
+		}
+	}
+
+	// the slots are released
+	release = make(chan struct{})
+	close(release)
+	go func() { <-entered }()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request("10.0.0.1:1", ""))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 after release, got %d", w.Code)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	clock := newFakeClock()
+	calls := 0
+	h := auth.Require(apiKeys())(Policy{Rate: Rate{Requests: 1, Per: time.Second}, Key: ByPrincipal, MaxInFlight: 1, Clock: clock}.Middleware()(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ })))
+	for _, key := range []string{"k1", "k1", "k2"} {
+		h.ServeHTTP(httptest.NewRecorder(), request("10.0.0.1:1", key))
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+	clock.Advance(time.Second)
+	h.ServeHTTP(httptest.NewRecorder(), request("10.0.0.1:1", "k1"))
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRandomKeysAreLimitedByIP(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLimiter(Rate{Requests: 1, Per: time.Second, Burst: 3}, clock)
+	h := RateLimit(limiter, ByIP)(auth.Require(apiKeys())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	codes := map[int]int{}
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, request("10.0.0.1:1", fmt.Sprintf("guess-%d", i)))
+		codes[w.Code]++
+	}
+	if codes[http.StatusUnauthorized] != 3 || codes[http.StatusTooManyRequests] != 97 {
+		t.Errorf("codes = %v, want 3 401 and 97 429", codes)
+	}
+	if limiter.Len() != 1 {
+		t.Errorf("%d buckets, want 1", limiter.Len())
+	}
+}
@@ -0,0 +1,92 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Rule returns the *Error for err, or nil when err isn't its concern.
+type Rule func(err error) *Error
+
+// Is matches errors.Is(err, target), the message is the text of err.
+func Is(target error, status int, code string) Rule {
+	return func(err error) *Error {
+		if !errors.Is(err, target) {
+			return nil
+		}
+		return &Error{Status: status, Code: code, Message: err.Error(), Err: err}
+	}
+}
+
+// Mapper maps Go errors to error responses, the first matching rule wins.
+//
+// *Error is always used as is, then the rules are tried, then the built-in rules:
+//
+//	context.DeadlineExceeded  → 504 timeout
+//	context.Canceled          → 503 canceled
+//	malformed JSON            → 400 bad_request
+//
+// anything else is a 500 whose message doesn't reveal the error.
+type Mapper struct {
+	rules []Rule
+}
+
+func NewMapper(rules ...Rule) *Mapper {
+	return &Mapper{rules: rules}
+}
+
+// DefaultMapper only has the built-in rules.
+var DefaultMapper = NewMapper()
+
+var builtinRules = []Rule{
+	Is(context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"),
+	Is(context.Canceled, http.StatusServiceUnavailable, "canceled"),
+	func(err error) *Error {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		// not io.EOF or io.ErrUnexpectedEOF, they come from any reader, like a dropped upstream connection,
+		// the decoders of request bodies return a 400 themselves
+		if errors.As(err, &syntaxError) || errors.As(err, &typeError) {
+			return &Error{Status: http.StatusBadRequest, Code: CodeOf(http.StatusBadRequest),
+				Message: "malformed JSON body: " + err.Error(), Err: err}
+		}
+		return nil
+	},
+}
+
+// Map returns the *Error for err.
+func (m *Mapper) Map(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		copied := *e
+		if copied.Status == 0 {
+			copied.Status = http.StatusInternalServerError
+		}
+		if copied.Code == "" {
+			copied.Code = CodeOf(copied.Status)
+		}
+		return &copied
+	}
+	for _, rules := range [][]Rule{m.rules, builtinRules} {
+		for _, rule := range rules {
+			if e := rule(err); e != nil {
+				return e
+			}
+		}
+	}
+	e = NewError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	e.Err = err
+	return e
+}
+
+// Status returns the status code for err.
+func (m *Mapper) Status(err error) int {
+	return m.Map(err).Status
+}
+
+// WriteError maps err and writes it as an Envelope, 5xx errors are logged with their cause.
+func (m *Mapper) WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, m.Map(err))
+}
@@ -0,0 +1,33 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NDJSONWriter streams one JSON value per line (application/x-ndjson),
+// each line is flushed so the client can handle it before the response ends.
+type NDJSONWriter struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	flusher http.Flusher
+}
+
+// NewNDJSONWriter writes the header with status, later errors can't change the status any more.
+func NewNDJSONWriter(w http.ResponseWriter, status int) *NDJSONWriter {
+	w.Header().Set("Content-Type", ContentTypeNDJSON)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	flusher, _ := w.(http.Flusher)
+	return &NDJSONWriter{w: w, encoder: json.NewEncoder(w), flusher: flusher}
+}
+
+func (n *NDJSONWriter) Write(v interface{}) error {
+	if err := n.encoder.Encode(v); err != nil {
+		return err
+	}
+	if n.flusher != nil {
+		n.flusher.Flush()
+	}
+	return nil
+}
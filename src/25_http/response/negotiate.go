@@ -0,0 +1,91 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		slash := strings.IndexByte(mediaType, '/')
+		if slash <= 0 || slash == len(mediaType)-1 {
+			continue
+		}
+		r := acceptRange{typ: mediaType[:slash], subtype: mediaType[slash+1:], q: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q >= 0 && q <= 1 {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// quality of offer, the most specific matching range counts, -1 when nothing matches.
+func quality(ranges []acceptRange, offer string) float64 {
+	offer = strings.ToLower(offer)
+	if i := strings.IndexByte(offer, ';'); i >= 0 {
+		offer = strings.TrimSpace(offer[:i])
+	}
+	slash := strings.IndexByte(offer, '/')
+	if slash < 0 {
+		return -1
+	}
+	typ, subtype := offer[:slash], offer[slash+1:]
+	q, specificity := -1.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// Negotiate returns the offer the Accept header of r prefers, ties go to the earlier offer.
+// A missing Accept header accepts the first offer, "" means none is acceptable (406).
+func Negotiate(r *http.Request, offers ...string) string {
+	header := r.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	ranges := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// NotAcceptable is the error for a request none of offers can answer.
+func NotAcceptable(offers ...string) *Error {
+	e := NewError(http.StatusNotAcceptable, "none of the offered media types is acceptable")
+	e.Details = map[string][]string{"offers": offers}
+	return e
+}
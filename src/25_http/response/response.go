@@ -0,0 +1,130 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+/*
+	所有 handler 回傳錯誤時都用同一個 envelope，client 只要處理一種格式：
+
+	HTTP/1.1 422 Unprocessable Entity
+	Content-Type: application/json
+
+	{"error":{"code":"validation_failed","message":"validation failed","details":{"age":"should be between 16 and 100"},"request_id":"4b1c9e0f2a7d3e65"}}
+
+	* code：給程式判斷用的固定字串，預設由 status code 產生，例如 404 → "not_found"
+	* message：給人看的說明，500 不會把內部的 error 內容送給 client
+	* details：額外的資訊，例如每個欄位的驗證錯誤
+	* request_id：從 request header 的 X-Request-ID 取得（middleware.RequestID 會先把它補上或換掉），方便對照 access log
+
+	handler 只要把 Go error 交給 WriteError，Mapper 會決定 status code 與 code。
+*/
+
+const (
+	ContentTypeJSON   = "application/json"
+	ContentTypeNDJSON = "application/x-ndjson"
+	ContentTypeText   = "text/plain; charset=utf-8"
+
+	RequestIDHeader = "X-Request-ID"
+)
+
+// Error is the body of an error response, and an error a handler can return.
+type Error struct {
+	Status    int         `json:"-"`
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	// Err is the cause, it is logged but never sent to the client
+	Err error `json:"-"`
+}
+
+// NewError returns an *Error with the code of status.
+func NewError(status int, message string) *Error {
+	return &Error{Status: status, Code: CodeOf(status), Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Envelope wraps an *Error as {"error": {...}}.
+type Envelope struct {
+	Error *Error `json:"error"`
+}
+
+// CodeOf turns the text of status into a code, 404 → "not_found".
+func CodeOf(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "unknown"
+	}
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		case r == ' ' || r == '-':
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// JSON writes v as the JSON body with status.
+// v is encoded before anything is written, so an encoding error still ends up as a 500.
+func JSON(w http.ResponseWriter, status int, v interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		e := NewError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		e.Err = err
+		writeError(w, nil, e)
+		return err
+	}
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteError writes err with DefaultMapper.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	DefaultMapper.WriteError(w, r, err)
+}
+
+// writeError takes the request ID from r, or from the response header when r is nil.
+func writeError(w http.ResponseWriter, r *http.Request, e *Error) {
+	if e.RequestID == "" && r != nil {
+		e.RequestID = r.Header.Get(RequestIDHeader)
+	}
+	if e.RequestID == "" {
+		e.RequestID = w.Header().Get(RequestIDHeader)
+	}
+	if e.Status >= http.StatusInternalServerError && e.Err != nil {
+		log.Printf("request_id=%s %v", e.RequestID, e)
+	}
+	body, err := json.Marshal(Envelope{e})
+	if err != nil {
+		// only the details can fail to encode
+		log.Printf("request_id=%s encode error details: %v", e.RequestID, err)
+		withoutDetails := *e
+		withoutDetails.Details = nil
+		body, _ = json.Marshal(Envelope{&withoutDetails})
+	}
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(e.Status)
+	w.Write(append(body, '\n'))
+}
@@ -0,0 +1,156 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decodeEnvelope(t *testing.T, w *httptest.ResponseRecorder) *Error {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeJSON {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	var body Envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error == nil {
+		t.Fatalf("invalid envelope %q: %v", w.Body.String(), err)
+	}
+	return body.Error
+}
+
+func TestCodeOf(t *testing.T) {
+	for status, code := range map[int]string{
+		http.StatusNotFound:            "not_found",
+		http.StatusUnprocessableEntity: "unprocessable_entity",
+		http.StatusTeapot:              "im_a_teapot",
+		999:                            "unknown",
+	} {
+		if got := CodeOf(status); got != code {
+			t.Errorf("CodeOf(%d) = %q, expected %q", status, got, code)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := JSON(w, http.StatusCreated, map[string]int{"age": 35}); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != ContentTypeJSON || w.Body.String() != "{\"age\":35}\n" {
+		t.Errorf("unexpected %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	// NaN can't be encoded, nothing of it should be written
+	w = httptest.NewRecorder()
+	if err := JSON(w, http.StatusOK, math.NaN()); err == nil {
+		t.Error("expected an encoding error")
+	}
+	if e := decodeEnvelope(t, w); w.Code != http.StatusInternalServerError || e.Code != "internal_server_error" {
+		t.Errorf("unexpected %d %+v", w.Code, e)
+	}
+}
+
+var errNotFound = errors.New("employee not found")
+
+func TestMapper(t *testing.T) {
+	m := NewMapper(Is(errNotFound, http.StatusNotFound, "not_found"))
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{errNotFound, http.StatusNotFound, "not_found"},
+		{fmt.Errorf("get Mike: %w", errNotFound), http.StatusNotFound, "not_found"},
+		{NewError(http.StatusConflict, "exists"), http.StatusConflict, "conflict"},
+		{fmt.Errorf("wrapped: %w", &Error{Status: http.StatusTeapot, Code: "tea"}), http.StatusTeapot, "tea"},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+		{json.Unmarshal([]byte(`{"age":`), new(interface{})), http.StatusBadRequest, "bad_request"},
+		{errors.New("disk is on fire"), http.StatusInternalServerError, "internal_server_error"},
+		{fmt.Errorf("read upstream: %w", io.ErrUnexpectedEOF), http.StatusInternalServerError, "internal_server_error"},
+		{io.EOF, http.StatusInternalServerError, "internal_server_error"},
+	}
+	for _, test := range tests {
+		e := m.Map(test.err)
+		if e.Status != test.status || e.Code != test.code {
+			t.Errorf("%v: expected %d %s, got %+v", test.err, test.status, test.code, e)
+		}
+	}
+	if e := m.Map(errors.New("disk is on fire")); strings.Contains(e.Message, "fire") {
+		t.Errorf("a 500 should not reveal the error, got %q", e.Message)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	r := httptest.NewRequest("GET", "/employees/Mike", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Length", "100")
+	e := NewError(http.StatusUnprocessableEntity, "validation failed")
+	e.Details = map[string]string{"age": "too old"}
+	WriteError(w, r, e)
+
+	got := decodeEnvelope(t, w)
+	if w.Code != http.StatusUnprocessableEntity || got.RequestID != "req-1" || got.Message != "validation failed" ||
+		got.Details.(map[string]interface{})["age"] != "too old" {
+		t.Errorf("unexpected %d %+v", w.Code, got)
+	}
+	if w.Header().Get("Content-Length") != "" {
+		t.Error("Content-Length should be removed")
+	}
+
+	// details that can't be encoded are dropped
+	w = httptest.NewRecorder()
+	e = NewError(http.StatusBadRequest, "bad")
+	e.Details = math.Inf(1)
+	WriteError(w, r, e)
+	if got := decodeEnvelope(t, w); got.Details != nil || got.Message != "bad" {
+		t.Errorf("unexpected %+v", got)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{ContentTypeJSON, ContentTypeNDJSON}
+	tests := []struct {
+		accept, expected string
+	}{
+		{"", ContentTypeJSON},
+		{"*/*", ContentTypeJSON},
+		{"application/x-ndjson", ContentTypeNDJSON},
+		{"application/json;q=0.5, application/x-ndjson", ContentTypeNDJSON},
+		{"application/*;q=0.2, application/json;q=0.1", ContentTypeNDJSON},
+		{"application/*", ContentTypeJSON},
+		{"text/html, */*;q=0.1", ContentTypeJSON},
+		{"text/html", ""},
+		{"application/json;q=0, */*", ContentTypeNDJSON},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", test.accept)
+		if got := Negotiate(r, offers...); got != test.expected {
+			t.Errorf("Accept %q: expected %q, got %q", test.accept, test.expected, got)
+		}
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	nd := NewNDJSONWriter(w, http.StatusOK)
+	for i := 1; i <= 3; i++ {
+		if err := nd.Write(map[string]int{"n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.Header().Get("Content-Type") != ContentTypeNDJSON || !w.Flushed {
+		t.Errorf("unexpected %v flushed=%v", w.Header(), w.Flushed)
+	}
+	if w.Body.String() != "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
@@ -0,0 +1,359 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_learning/src/25_http/roa/store"
+)
+
+/*
+	roa employee API 的 client，呼叫端不用自己組 URL、解析 JSON 與錯誤：
+
+	c, err := client.New(client.Config{BaseURL: "http://localhost:8080", APIKey: key})
+	e, err := c.GetEmployee(ctx, "Mike")
+	if errors.Is(err, client.NotFoundError) { ... }
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) { fmt.Println(apiErr.Details) }
+
+	* timeout：每次呼叫（包含所有重試）最多 Config.Timeout，c.WithTimeout(d) 回傳使用另一個 timeout 的 client；
+	  ctx 自己的 deadline 比較早的話以 ctx 為準
+	* 重試：連線錯誤與 5xx 會以 exponential backoff（加上 jitter）重試，503 的 Retry-After 比 backoff 長時等 Retry-After；
+	  POST 不是 idempotent，只在確定 server 沒有處理時重試：連不上（dial error）或 503（in-flight 限制、shutting down）
+	* 同一次呼叫的每次重試都帶同一個 X-Request-ID，方便在 server 的 access log 找到
+*/
+
+const (
+	requestIDHeader = "X-Request-ID"
+	apiKeyHeader    = "X-API-Key"
+
+	DefaultTimeout    = 10 * time.Second
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 2 * time.Second
+)
+
+type (
+	Employee      = store.Employee
+	EmployeePatch = store.EmployeePatch
+)
+
+type EmployeePage struct {
+	Items  []Employee `json:"items"`
+	Total  int        `json:"total"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+}
+
+// ListOptions filters and pages ListEmployees, zero values are left out.
+type ListOptions struct {
+	MinAge int
+	MaxAge int
+	Age    int
+	Offset int
+	// the server uses 20 when it is 0 and at most 100
+	Limit int
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	for key, n := range map[string]int{"min_age": o.MinAge, "max_age": o.MaxAge, "age": o.Age, "offset": o.Offset, "limit": o.Limit} {
+		if n != 0 {
+			q.Set(key, strconv.Itoa(n))
+		}
+	}
+	return q
+}
+
+// Config of a Client, only BaseURL is required.
+type Config struct {
+	// BaseURL is like http://localhost:8080
+	BaseURL string
+	// APIKey or BearerToken authenticates the requests
+	APIKey      string
+	BearerToken string
+	// HTTPClient is http.DefaultClient when nil, its own Timeout should be left 0
+	HTTPClient *http.Client
+	// Timeout of a call including the retries, DefaultTimeout when 0
+	Timeout time.Duration
+	// MaxRetries is DefaultMaxRetries when 0, negative disables the retries
+	MaxRetries int
+	// the backoff doubles from MinBackoff up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	UserAgent  string
+}
+
+type Client struct {
+	baseURL *url.URL
+	config  Config
+	// jitter of the backoff, shared with the copies of WithTimeout
+	jitter *jitter
+}
+
+// jitter is a seeded math/rand, the global one of go 1.18 starts with the same seed in every process
+type jitter struct {
+	mu  sync.Mutex
+	rnd *mathrand.Rand
+}
+
+func (j *jitter) Int63n(n int64) int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.rnd.Int63n(n)
+}
+
+func New(c Config) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(c.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("roa: invalid base URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" {
+		return nil, fmt.Errorf("roa: invalid base URL %q, should be like http://localhost:8080", c.BaseURL)
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultMaxRetries
+	} else if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = DefaultMinBackoff
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = DefaultMaxBackoff
+		if c.MaxBackoff < c.MinBackoff {
+			c.MaxBackoff = c.MinBackoff
+		}
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = "go_learning-roa-client"
+	}
+	return &Client{
+		baseURL: base,
+		config:  c,
+		jitter:  &jitter{rnd: mathrand.New(mathrand.NewSource(time.Now().UnixNano()))},
+	}, nil
+}
+
+// WithTimeout returns a copy of c whose calls time out after d.
+func (c *Client) WithTimeout(d time.Duration) *Client {
+	copied := *c
+	copied.config.Timeout = d
+	return &copied
+}
+
+// employeePath is the escaped path of the employee, "a b/c" is /employees/a%20b%2Fc
+func employeePath(name string) string {
+	return "/employees/" + url.PathEscape(name)
+}
+
+func (c *Client) GetEmployee(ctx context.Context, name string) (Employee, error) {
+	var e Employee
+	err := c.do(ctx, http.MethodGet, employeePath(name), nil, nil, &e)
+	return e, err
+}
+
+func (c *Client) ListEmployees(ctx context.Context, opts ListOptions) (*EmployeePage, error) {
+	var page EmployeePage
+	if err := c.do(ctx, http.MethodGet, "/employees", opts.query(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// CreateEmployee returns the employee with the assigned id.
+func (c *Client) CreateEmployee(ctx context.Context, e Employee) (Employee, error) {
+	var created Employee
+	err := c.do(ctx, http.MethodPost, "/employees", nil, e, &created)
+	return created, err
+}
+
+// ReplaceEmployee replaces the employee called name, the name can't be changed.
+func (c *Client) ReplaceEmployee(ctx context.Context, name string, e Employee) (Employee, error) {
+	var replaced Employee
+	err := c.do(ctx, http.MethodPut, employeePath(name), nil, e, &replaced)
+	return replaced, err
+}
+
+// PatchEmployee changes the non-nil fields of patch.
+func (c *Client) PatchEmployee(ctx context.Context, name string, patch EmployeePatch) (Employee, error) {
+	var patched Employee
+	err := c.do(ctx, http.MethodPatch, employeePath(name), nil, patch, &patched)
+	return patched, err
+}
+
+func (c *Client) DeleteEmployee(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, employeePath(name), nil, nil, nil)
+}
+
+// do sends the request with retries and decodes the response into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("roa: encode request: %w", err)
+		}
+	}
+	// path is escaped, RawPath keeps it as is so the %2F of a name is not taken for a /
+	u := *c.baseURL
+	u.RawPath = c.baseURL.EscapedPath() + path
+	var err error
+	if u.Path, err = url.PathUnescape(u.RawPath); err != nil {
+		return fmt.Errorf("roa: invalid path %q: %w", path, err)
+	}
+	u.RawQuery = query.Encode()
+	requestID := newRequestID()
+
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, u.String(), requestID, body, out)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.config.MaxRetries || !retryable(method, err) {
+			return err
+		}
+		wait := c.backoff(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// the call would time out while waiting, the last error says more than the deadline
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once.
+func (c *Client) attempt(ctx context.Context, method, url, requestID string, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("roa: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	req.Header.Set(requestIDHeader, requestID)
+	if c.config.APIKey != "" {
+		req.Header.Set(apiKeyHeader, c.config.APIKey)
+	} else if c.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.BearerToken)
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return &connError{method: method, err: err}
+	}
+	defer resp.Body.Close()
+	// the error bodies are small, a large body of a proxy is cut
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return &connError{method: method, err: err, sent: true}
+	}
+	if resp.StatusCode >= 400 {
+		return newAPIError(resp, data)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("roa: decode %s response: %w", method, err)
+	}
+	return nil
+}
+
+// connError is an error of the connection, no response was received.
+type connError struct {
+	method string
+	err    error
+	// sent is true when the response broke after the status line
+	sent bool
+}
+
+func (e *connError) Error() string {
+	return "roa: " + e.err.Error()
+}
+
+func (e *connError) Unwrap() error {
+	return e.err
+}
+
+func retryable(method string, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	idempotent := method != http.MethodPost
+	var ce *connError
+	if errors.As(err, &ce) {
+		if idempotent {
+			return true
+		}
+		// only a connection never made guarantees the server didn't create the employee
+		var opErr *net.OpError
+		return !ce.sent && errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if idempotent {
+			return apiErr.StatusCode >= 500
+		}
+		return apiErr.StatusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+// backoff is MinBackoff*2^attempt up to MaxBackoff, with a random half taken off
+// so that clients failed at the same time don't retry at the same time.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.config.MaxBackoff
+	if attempt < 30 {
+		if exp := c.config.MinBackoff << uint(attempt); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	return d/2 + time.Duration(c.jitter.Int63n(int64(d/2)+1))
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_learning/src/25_http/auth"
+	"go_learning/src/25_http/roa/handler"
+	"go_learning/src/25_http/roa/store"
+)
+
+const (
+	adminKey  = "admin-key"
+	readerKey = "reader-key"
+)
+
+// newServer runs the real handlers, wrap can put a fault in front of them.
+func newServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	s := store.NewMemoryStore()
+	if err := handler.Seed(s); err != nil {
+		t.Fatal(err)
+	}
+	keys := auth.NewAPIKeys()
+	keys.Add(adminKey, "admin", handler.RoleRead, handler.RoleWrite)
+	keys.Add(readerKey, "reader", handler.RoleRead)
+	var h http.Handler = handler.NewServerHandler(handler.NewRouter(s, keys), ioutil.Discard, ioutil.Discard)
+	if wrap != nil {
+		h = wrap(h)
+	}
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(t *testing.T, server *httptest.Server, apiKey string) *Client {
+	t.Helper()
+	c, err := New(Config{BaseURL: server.URL, APIKey: apiKey, HTTPClient: server.Client(), MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEmployees(t *testing.T) {
+	c := newClient(t, newServer(t, nil), adminKey)
+	ctx := context.Background()
+
+	e, err := c.GetEmployee(ctx, "Mike")
+	if err != nil || e != (Employee{ID: "e-1", Name: "Mike", Age: 35}) {
+		t.Errorf("unexpected %+v %v", e, err)
+	}
+	created, err := c.CreateEmployee(ctx, Employee{Name: "Jack", Age: 28})
+	if err != nil || created != (Employee{ID: "e-3", Name: "Jack", Age: 28}) {
+		t.Errorf("unexpected %+v %v", created, err)
+	}
+	page, err := c.ListEmployees(ctx, ListOptions{MinAge: 30, Limit: 10})
+	if err != nil || page.Total != 2 || len(page.Items) != 2 || page.Limit != 10 {
+		t.Errorf("unexpected %+v %v", page, err)
+	}
+	replaced, err := c.ReplaceEmployee(ctx, "Jack", Employee{Name: "Jack", Age: 29})
+	if err != nil || replaced.Age != 29 || replaced.ID != "e-3" {
+		t.Errorf("unexpected %+v %v", replaced, err)
+	}
+	age := 30
+	patched, err := c.PatchEmployee(ctx, "Jack", EmployeePatch{Age: &age})
+	if err != nil || patched.Age != 30 {
+		t.Errorf("unexpected %+v %v", patched, err)
+	}
+	if err := c.DeleteEmployee(ctx, "Jack"); err != nil {
+		t.Error(err)
+	}
+	if _, err := c.GetEmployee(ctx, "Jack"); !errors.Is(err, NotFoundError) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+}
+
+func TestEscapedNames(t *testing.T) {
+	var uris []string
+	record := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uris = append(uris, r.RequestURI)
+			next.ServeHTTP(w, r)
+		})
+	}
+	server := newServer(t, record)
+	ctx := context.Background()
+	if _, err := newClient(t, server, adminKey).GetEmployee(ctx, "a b/c"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+	prefixed, err := New(Config{BaseURL: server.URL + "/api v1/", HTTPClient: server.Client(), MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixed.GetEmployee(ctx, "a b/c")
+	want := []string{"/employees/a%20b%2Fc", "/api%20v1/employees/a%20b%2Fc"}
+	if len(uris) != len(want) || uris[0] != want[0] || uris[1] != want[1] {
+		t.Errorf("request URIs = %q, want %q", uris, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server := newServer(t, nil)
+	c := newClient(t, server, adminKey)
+	ctx := context.Background()
+
+	_, err := c.GetEmployee(ctx, "Nobody")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 404 || apiErr.Code != "not_found" || apiErr.RequestID == "" {
+		t.Errorf("unexpected %#v", err)
+	}
+	if _, err := c.CreateEmployee(ctx, Employee{Name: "Mike", Age: 30}); !errors.Is(err, AlreadyExistsError) {
+		t.Errorf("expected AlreadyExistsError, got %v", err)
+	}
+	_, err = c.CreateEmployee(ctx, Employee{Name: "1Jack", Age: 200})
+	if !errors.Is(err, InvalidEmployeeError) || !errors.As(err, &apiErr) || apiErr.Details["name"] == "" || apiErr.Details["age"] == "" {
+		t.Errorf("expected the invalid fields, got %v", err)
+	}
+	if _, err := c.GetEmployee(ctx, "1Mike"); !errors.Is(err, BadRequestError) {
+		t.Errorf("expected BadRequestError, got %v", err)
+	}
+
+	if _, err := newClient(t, server, "").GetEmployee(ctx, "Mike"); !errors.Is(err, UnauthorizedError) {
+		t.Errorf("expected UnauthorizedError, got %v", err)
+	}
+	if err := newClient(t, server, readerKey).DeleteEmployee(ctx, "Mike"); !errors.Is(err, ForbiddenError) {
+		t.Errorf("expected ForbiddenError, got %v", err)
+	}
+	if _, err := New(Config{BaseURL: "localhost:8080"}); err == nil {
+		t.Error("expected an error of the base URL without a scheme")
+	}
+}
+
+// failFirst answers the first n requests with fail, and counts the requests.
+func failFirst(n int32, count *int32, fail http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(count, 1) <= n {
+				fail(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func status(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}
+}
+
+// hangUp closes the connection without a response.
+func hangUp(w http.ResponseWriter, r *http.Request) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		panic(err)
+	}
+	conn.Close()
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		failures int32
+		fail     http.HandlerFunc
+		call     func(c *Client) error
+		requests int32
+		ok       bool
+	}{
+		{"5xx", 2, status(http.StatusBadGateway), func(c *Client) error { _, err := c.GetEmployee(ctx, "Mike"); return err }, 3, true},
+		{"connection error", 1, hangUp, func(c *Client) error { _, err := c.ListEmployees(ctx, ListOptions{}); return err }, 2, true},
+		{"too many failures", 10, status(http.StatusInternalServerError), func(c *Client) error { _, err := c.GetEmployee(ctx, "Mike"); return err }, 4, false},
+		{"4xx isn't retried", 10, status(http.StatusConflict), func(c *Client) error { _, err := c.GetEmployee(ctx, "Mike"); return err }, 1, false},
+		{"POST after 503", 1, status(http.StatusServiceUnavailable), func(c *Client) error {
+			_, err := c.CreateEmployee(ctx, Employee{Name: "Jack", Age: 28})
+			return err
+		}, 2, true},
+		// the employee may have been created
+		{"POST after 500", 1, status(http.StatusInternalServerError), func(c *Client) error {
+			_, err := c.CreateEmployee(ctx, Employee{Name: "Jack", Age: 28})
+			return err
+		}, 1, false},
+		{"POST after a broken connection", 1, hangUp, func(c *Client) error {
+			_, err := c.CreateEmployee(ctx, Employee{Name: "Jack", Age: 28})
+			return err
+		}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var count int32
+			c := newClient(t, newServer(t, failFirst(tt.failures, &count, tt.fail)), adminKey)
+			err := tt.call(c)
+			if (err == nil) != tt.ok {
+				t.Errorf("unexpected error %v", err)
+			}
+			if got := atomic.LoadInt32(&count); got != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, got)
+			}
+		})
+	}
+}
+
+func TestRetryDialError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	c, err := New(Config{BaseURL: "http://" + addr, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opErr *net.OpError
+	if _, err := c.CreateEmployee(context.Background(), Employee{Name: "Jack", Age: 28}); !errors.As(err, &opErr) || !retryable(http.MethodPost, err) {
+		t.Errorf("expected a retryable dial error, got %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	var count int32
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}
+	c := newClient(t, newServer(t, failFirst(1, &count, slow)), adminKey)
+
+	start := time.Now()
+	_, err := c.WithTimeout(50*time.Millisecond).GetEmployee(context.Background(), "Mike")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the call took %v", elapsed)
+	}
+	// the timeout of c is unchanged
+	if _, err := c.GetEmployee(context.Background(), "Mike"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var count int32
+	c := newClient(t, newServer(t, failFirst(1, &count, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})), adminKey)
+	// waiting 5s would outlast the timeout, so the 503 is returned at once
+	_, err := c.WithTimeout(time.Second).GetEmployee(context.Background(), "Mike")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 5*time.Second || !errors.Is(err, ServerError) {
+		t.Errorf("expected the 503, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinels of the errors of the API, an *APIError matches one of them with errors.Is:
+//
+//	if errors.Is(err, client.NotFoundError) { ... }
+var (
+	BadRequestError      = errors.New("bad request")
+	UnauthorizedError    = errors.New("unauthorized")
+	ForbiddenError       = errors.New("forbidden")
+	NotFoundError        = errors.New("employee not found")
+	AlreadyExistsError   = errors.New("employee already exists")
+	InvalidEmployeeError = errors.New("invalid employee")
+	TooManyRequestsError = errors.New("too many requests")
+	ServerError          = errors.New("server error")
+)
+
+// APIError is an error response of the API.
+type APIError struct {
+	StatusCode int
+	// Code is the code of the error envelope, like "not_found"
+	Code    string
+	Message string
+	// Details maps each invalid field to the reason for "validation_failed" and "bad_request"
+	Details   map[string]string
+	RequestID string
+	// RetryAfter is the Retry-After header of 429 and 503
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "roa: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	if len(e.Details) > 0 {
+		fmt.Fprintf(&b, " %v", e.Details)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request %s)", e.RequestID)
+	}
+	return b.String()
+}
+
+// Is matches the sentinel of the status code.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case BadRequestError:
+		return e.StatusCode == http.StatusBadRequest
+	case UnauthorizedError:
+		return e.StatusCode == http.StatusUnauthorized
+	case ForbiddenError:
+		return e.StatusCode == http.StatusForbidden
+	case NotFoundError:
+		return e.StatusCode == http.StatusNotFound
+	case AlreadyExistsError:
+		return e.StatusCode == http.StatusConflict
+	case InvalidEmployeeError:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	case TooManyRequestsError:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ServerError:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+type errorEnvelope struct {
+	Error *struct {
+		Code      string          `json:"code"`
+		Message   string          `json:"message"`
+		Details   json.RawMessage `json:"details"`
+		RequestID string          `json:"request_id"`
+	} `json:"error"`
+}
+
+// newAPIError reads the error envelope of resp, and copes with the bodies of proxies that aren't JSON.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(requestIDHeader),
+		RetryAfter: retryAfter(resp.Header),
+	}
+	var envelope errorEnvelope
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error != nil {
+		e.Code = envelope.Error.Code
+		e.Message = envelope.Error.Message
+		if envelope.Error.RequestID != "" {
+			e.RequestID = envelope.Error.RequestID
+		}
+		// other details are left out, only the field errors are documented
+		json.Unmarshal(envelope.Error.Details, &e.Details)
+		return e
+	}
+	e.Code = "unknown"
+	e.Message = http.StatusText(resp.StatusCode)
+	if text := strings.TrimSpace(string(body)); text != "" {
+		if len(text) > 200 {
+			text = text[:200] + "..."
+		}
+		e.Message += ": " + text
+	}
+	return e
+}
+
+// retryAfter reads the seconds of the Retry-After header, the HTTP date form isn't sent by the API.
+func retryAfter(h http.Header) time.Duration {
+	seconds, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
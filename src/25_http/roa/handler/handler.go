@@ -0,0 +1,432 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"go_learning/src/25_http/auth"
+	"go_learning/src/25_http/cache"
+	"go_learning/src/25_http/middleware"
+	"go_learning/src/25_http/openapi"
+	"go_learning/src/25_http/ratelimit"
+	"go_learning/src/25_http/response"
+	"go_learning/src/25_http/roa/store"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	readTimeout  = 2 * time.Second
+	writeTimeout = 5 * time.Second
+
+	RoleRead  = "employees:read"
+	RoleWrite = "employees:write"
+
+	maxInFlight = 256
+
+	cacheEntries = 1000
+	cacheBytes   = 8 << 20
+)
+
+var (
+	// ipRate limits the requests of an IP before authentication, above the rates of a client
+	ipRate    = ratelimit.Rate{Requests: 50, Per: time.Second, Burst: 100}
+	readRate  = ratelimit.Rate{Requests: 20, Per: time.Second, Burst: 40}
+	writeRate = ratelimit.Rate{Requests: 5, Per: time.Second, Burst: 10}
+)
+
+type Employee = store.Employee
+
+// Seed fills a newly created store with the sample data.
+// Don't seed a store just because it is empty, its employees may have been deleted.
+func Seed(s store.EmployeeStore) error {
+	for _, e := range []Employee{{ID: "e-1", Name: "Mike", Age: 35}, {ID: "e-2", Name: "Rose", Age: 45}} {
+		if _, err := s.Create(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmployeeHandler serves the employee resource from an EmployeeStore.
+type EmployeeHandler struct {
+	store store.EmployeeStore
+}
+
+type EmployeePage struct {
+	Items  []Employee `json:"items"`
+	Total  int        `json:"total"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+}
+
+// errorMapper adds the errors of the store to the built-in rules of response.Mapper.
+var errorMapper = response.NewMapper(
+	response.Is(store.NotFoundError, http.StatusNotFound, "not_found"),
+	response.Is(store.AlreadyExistsError, http.StatusConflict, "already_exists"),
+	func(err error) *response.Error {
+		var ve *store.ValidationError
+		if !errors.As(err, &ve) {
+			return nil
+		}
+		return &response.Error{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    "validation_failed",
+			Message: "validation failed",
+			Details: ve.Fields,
+			Err:     err,
+		}
+	},
+)
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	if err := response.JSON(w, status, v); err != nil {
+		log.Println("write response:", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	errorMapper.WriteError(w, r, err)
+}
+
+func badRequest(err error) error {
+	e := response.NewError(http.StatusBadRequest, err.Error())
+	e.Err = err
+	return e
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return badRequest(fmt.Errorf("malformed JSON body: %w", err))
+	}
+	return nil
+}
+
+// http://localhost:8080/
+func Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	fmt.Fprint(w, "Welcome!\n")
+}
+
+// queryReader keeps the first error, like the Reader in 08_error/error_design
+type queryReader struct {
+	r   *http.Request
+	err error
+}
+
+// int returns def when key is not in the query.
+func (q *queryReader) int(key string, def int) int {
+	if q.err != nil {
+		return 0
+	}
+	str := q.r.URL.Query().Get(key)
+	if str == "" {
+		return def
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil || n < 0 {
+		q.err = fmt.Errorf("query parameter %s should be a non-negative integer", key)
+	}
+	return n
+}
+
+// http://localhost:8080/employees?min_age=30&max_age=40&offset=0&limit=10
+// curl -H 'Accept: application/x-ndjson' http://localhost:8080/employees
+// streams one employee per line, the total is in the X-Total-Count header
+func (h *EmployeeHandler) ListEmployees(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	contentType := response.Negotiate(r, response.ContentTypeJSON, response.ContentTypeNDJSON)
+	if contentType == "" {
+		writeError(w, r, response.NotAcceptable(response.ContentTypeJSON, response.ContentTypeNDJSON))
+		return
+	}
+	q := queryReader{r: r}
+	minAge := q.int("min_age", 0)
+	maxAge := q.int("max_age", 0)
+	age := q.int("age", 0)
+	offset := q.int("offset", 0)
+	limit := q.int("limit", defaultPageSize)
+	if q.err != nil {
+		writeError(w, r, badRequest(q.err))
+		return
+	}
+	if limit == 0 || limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	all, err := h.store.List()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	matched := []Employee{}
+	for _, e := range all {
+		if (age > 0 && e.Age != age) || (minAge > 0 && e.Age < minAge) || (maxAge > 0 && e.Age > maxAge) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+
+	page := EmployeePage{Items: []Employee{}, Total: len(matched), Offset: offset, Limit: limit}
+	if offset < len(matched) {
+		end := offset + limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		page.Items = matched[offset:end]
+	}
+	if contentType == response.ContentTypeNDJSON {
+		w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+		nd := response.NewNDJSONWriter(w, http.StatusOK)
+		for _, e := range page.Items {
+			if err := nd.Write(e); err != nil {
+				log.Println("write response:", err)
+				return
+			}
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// http://localhost:8080/employees/Mike
+// http://localhost:8080/employees/Rose
+func (h *EmployeeHandler) GetEmployeeByName(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	info, err := h.store.Get(ps.ByName("name"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &info)
+}
+
+// curl -X POST -d '{"name":"Jack","age":28}' http://localhost:8080/employees
+func (h *EmployeeHandler) CreateEmployee(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var e Employee
+	if err := decodeBody(r, &e); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := e.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	e, err := h.store.Create(e)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/employees/"+e.Name)
+	writeJSON(w, http.StatusCreated, &e)
+}
+
+// curl -X PUT -d '{"id":"e-1","name":"Mike","age":36}' http://localhost:8080/employees/Mike
+func (h *EmployeeHandler) ReplaceEmployee(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	name := ps.ByName("name")
+	var e Employee
+	if err := decodeBody(r, &e); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if e.Name == "" {
+		e.Name = name
+	}
+	h.updateEmployee(w, r, name, func(old Employee) Employee {
+		if e.ID == "" {
+			e.ID = old.ID
+		}
+		return e
+	})
+}
+
+// curl -X PATCH -d '{"age":36}' http://localhost:8080/employees/Mike
+func (h *EmployeeHandler) PatchEmployee(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var patch store.EmployeePatch
+	if err := decodeBody(r, &patch); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.updateEmployee(w, r, ps.ByName("name"), patch.Apply)
+}
+
+// updateEmployee replaces the employee called name with update(old), the name can't be changed.
+func (h *EmployeeHandler) updateEmployee(w http.ResponseWriter, r *http.Request, name string, update func(old Employee) Employee) {
+	e, err := h.store.Update(name, func(old Employee) (Employee, error) {
+		e := update(old)
+		if err := e.Validate(); err != nil {
+			return e, err
+		}
+		if e.Name != name {
+			return e, &store.ValidationError{Fields: map[string]string{"name": "can't be changed"}}
+		}
+		return e, nil
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &e)
+}
+
+// curl -X DELETE http://localhost:8080/employees/Mike
+func (h *EmployeeHandler) DeleteEmployee(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.store.Delete(ps.ByName("name")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cacheTags is "employees/<name>" for an employee, "employees" for the lists.
+func cacheTags(r *http.Request) []string {
+	if name := httprouter.ParamsFromContext(r.Context()).ByName("name"); name != "" {
+		return []string{"employees/" + name}
+	}
+	return []string{"employees"}
+}
+
+// NewRouter serves the employees to callers authenticated by authn with the roles of each route,
+// the routes are documented at /openapi.json and the requests are validated against it.
+func NewRouter(s store.EmployeeStore, authn auth.Authenticator) *httprouter.Router {
+	// the responses of GET are kept until a write changes their employees, every list may change on any write
+	lru := cache.NewLRU(cacheEntries, cacheBytes)
+	s = store.Notify(s, func(names ...string) {
+		tags := []string{"employees"}
+		for _, name := range names {
+			tags = append(tags, "employees/"+name)
+		}
+		lru.Invalidate(tags...)
+	})
+	h := &EmployeeHandler{s}
+	router := httprouter.New()
+	router.GET("/", Index)
+	api := openapi.New(router, openapi.Info{Title: "Employees", Version: "1.0.0"}, true)
+	// the limit by IP comes before authentication, so guessing credentials is limited too,
+	// the limits of each client key on the authenticated principal, random credentials never get a bucket
+	byIP := ratelimit.Policy{Rate: ipRate, Key: ratelimit.ByIP}.Middleware()
+	read := []middleware.Middleware{
+		byIP,
+		auth.Require(authn, RoleRead),
+		ratelimit.Policy{Rate: readRate, Key: ratelimit.ByPrincipal}.Middleware(),
+		// after authentication, the LRU never answers a caller without the role
+		cache.Conditional(cache.Config{
+			CacheControl: cache.CacheControl{Private: true, NoCache: true}.String(),
+			Cache:        lru,
+			Tags:         cacheTags,
+		}),
+		middleware.Timeout(readTimeout),
+	}
+	write := []middleware.Middleware{
+		byIP,
+		auth.Require(authn, RoleWrite),
+		ratelimit.Policy{Rate: writeRate, Key: ratelimit.ByPrincipal}.Middleware(),
+		middleware.Timeout(writeTimeout),
+	}
+	nonNegative := func(name, description string) openapi.Param {
+		zero := 0.0
+		return openapi.Param{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "integer", Minimum: &zero}}
+	}
+	name := openapi.Param{Name: "name", In: "path", Schema: &openapi.Schema{Type: "string", Pattern: store.NamePattern}}
+	tags := []string{"employees"}
+
+	api.Handle(openapi.Route{
+		Method:  http.MethodGet,
+		Path:    "/employees",
+		Summary: "List the employees",
+		Description: "Responds with one employee per line to Accept: application/x-ndjson, " +
+			"the total is in the X-Total-Count header",
+		Tags: tags,
+		Params: []openapi.Param{
+			nonNegative("min_age", "only the employees at least this old"),
+			nonNegative("max_age", "only the employees at most this old"),
+			nonNegative("age", "only the employees of this age"),
+			nonNegative("offset", "the number of employees to skip"),
+			nonNegative("limit", fmt.Sprintf("the size of the page, %d by default and at most %d", defaultPageSize, maxPageSize)),
+		},
+		Replies:     []openapi.Reply{{Status: http.StatusOK, Body: EmployeePage{}}},
+		Handle:      h.ListEmployees,
+		Middlewares: read,
+	})
+	api.Handle(openapi.Route{
+		Method:      http.MethodPost,
+		Path:        "/employees",
+		Summary:     "Create an employee",
+		Description: "The id is assigned when it is empty",
+		Tags:        tags,
+		Request:     Employee{},
+		Replies:     []openapi.Reply{{Status: http.StatusCreated, Body: Employee{}}},
+		Handle:      h.CreateEmployee,
+		Middlewares: write,
+	})
+	api.Handle(openapi.Route{
+		Method:      http.MethodGet,
+		Path:        "/employees/:name",
+		Summary:     "Get an employee",
+		Tags:        tags,
+		Params:      []openapi.Param{name},
+		Replies:     []openapi.Reply{{Status: http.StatusOK, Body: Employee{}}},
+		Handle:      h.GetEmployeeByName,
+		Middlewares: read,
+	})
+	api.Handle(openapi.Route{
+		Method:      http.MethodPut,
+		Path:        "/employees/:name",
+		Summary:     "Replace an employee",
+		Description: "The name can't be changed, the id is kept when it is empty",
+		Tags:        tags,
+		Params:      []openapi.Param{name},
+		Request:     Employee{},
+		Replies:     []openapi.Reply{{Status: http.StatusOK, Body: Employee{}}},
+		Handle:      h.ReplaceEmployee,
+		Middlewares: write,
+	})
+	api.Handle(openapi.Route{
+		Method:      http.MethodPatch,
+		Path:        "/employees/:name",
+		Summary:     "Update some fields of an employee",
+		Tags:        tags,
+		Params:      []openapi.Param{name},
+		Request:     store.EmployeePatch{},
+		Replies:     []openapi.Reply{{Status: http.StatusOK, Body: Employee{}}},
+		Handle:      h.PatchEmployee,
+		Middlewares: write,
+	})
+	api.Handle(openapi.Route{
+		Method:      http.MethodDelete,
+		Path:        "/employees/:name",
+		Summary:     "Delete an employee",
+		Tags:        tags,
+		Params:      []openapi.Param{name},
+		Replies:     []openapi.Reply{{Status: http.StatusNoContent}},
+		Handle:      h.DeleteEmployee,
+		Middlewares: write,
+	})
+	return router
+}
+
+// NewServerHandler wraps the router with the middlewares shared by every route,
+// the access log is written to accessLog and the panics to errorLog.
+func NewServerHandler(router http.Handler, accessLog, errorLog io.Writer) http.Handler {
+	return middleware.Chain(
+		middleware.RequestID(),
+		middleware.AccessLog(accessLog),
+		middleware.Recover(errorLog),
+		ratelimit.InFlight(maxInFlight, time.Second),
+		middleware.CORS(middleware.CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", auth.APIKeyHeader, middleware.RequestIDHeader},
+			ExposedHeaders: []string{"Location", middleware.RequestIDHeader},
+			MaxAge:         10 * time.Minute,
+		}),
+		middleware.Gzip(),
+	)(router)
+}
@@ -0,0 +1,419 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_learning/src/25_http/auth"
+	"go_learning/src/25_http/roa/store"
+)
+
+var testStore store.EmployeeStore
+
+const (
+	adminKey  = "admin-key"
+	readerKey = "reader-key"
+)
+
+var testAuthn = func() auth.Authenticator {
+	keys := auth.NewAPIKeys()
+	keys.Add(adminKey, "admin", RoleRead, RoleWrite)
+	keys.Add(readerKey, "reader", RoleRead)
+	return keys
+}()
+
+// errorEnvelope is response.Envelope with the details of a validation error
+type errorEnvelope struct {
+	Error struct {
+		Code      string            `json:"code"`
+		Message   string            `json:"message"`
+		Details   map[string]string `json:"details"`
+		RequestID string            `json:"request_id"`
+	} `json:"error"`
+}
+
+func resetEmployees() {
+	testStore = store.NewMemoryStore()
+	Seed(testStore)
+}
+
+// do sends the request as admin
+func do(t *testing.T, method, url, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return doAs(t, adminKey, method, url, body)
+}
+
+func doAs(t *testing.T, apiKey, method, url, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, url, nil)
+	} else {
+		req = httptest.NewRequest(method, url, strings.NewReader(body))
+	}
+	if apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, apiKey)
+	}
+	w := httptest.NewRecorder()
+	NewRouter(testStore, testAuthn).ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+}
+
+func TestGetEmployee(t *testing.T) {
+	resetEmployees()
+	w := do(t, "GET", "/employees/Mike", "")
+	var e Employee
+	decode(t, w, &e)
+	if w.Code != http.StatusOK || e != (Employee{ID: "e-1", Name: "Mike", Age: 35}) {
+		t.Errorf("unexpected %d %+v", w.Code, e)
+	}
+
+	w = do(t, "GET", "/employees/Nobody", "")
+	var body errorEnvelope
+	decode(t, w, &body)
+	if w.Code != http.StatusNotFound || body.Error.Code != "not_found" || body.Error.Message == "" {
+		t.Errorf("unexpected %d %+v", w.Code, body)
+	}
+}
+
+func TestCreateEmployee(t *testing.T) {
+	resetEmployees()
+	w := do(t, "POST", "/employees", `{"name":"Jack","age":28}`)
+	var e Employee
+	decode(t, w, &e)
+	if w.Code != http.StatusCreated || e != (Employee{ID: "e-3", Name: "Jack", Age: 28}) {
+		t.Errorf("unexpected %d %+v", w.Code, e)
+	}
+	if loc := w.Header().Get("Location"); loc != "/employees/Jack" {
+		t.Errorf("unexpected Location %q", loc)
+	}
+	if w := do(t, "GET", "/employees/Jack", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the new employee, got %d", w.Code)
+	}
+
+	if w := do(t, "POST", "/employees", `{"name":"Jack","age":30}`); w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+	if w := do(t, "POST", "/employees", `{"name":"Jack",`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+	if w := do(t, "POST", "/employees", `{"name":"Jack","salary":1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown field, got %d", w.Code)
+	}
+}
+
+func TestCreateEmployeeValidation(t *testing.T) {
+	resetEmployees()
+	w := do(t, "POST", "/employees", `{"id":"x","name":"1Jack","age":200}`)
+	var body errorEnvelope
+	decode(t, w, &body)
+	if w.Code != http.StatusUnprocessableEntity || body.Error.Code != "validation_failed" {
+		t.Errorf("expected 422, got %d %+v", w.Code, body)
+	}
+	for _, field := range []string{"id", "name", "age"} {
+		if body.Error.Details[field] == "" {
+			t.Errorf("expected error of %s in %+v", field, body)
+		}
+	}
+}
+
+func TestReplaceEmployee(t *testing.T) {
+	resetEmployees()
+	w := do(t, "PUT", "/employees/Mike", `{"name":"Mike","age":36}`)
+	var e Employee
+	decode(t, w, &e)
+	if w.Code != http.StatusOK || e != (Employee{ID: "e-1", Name: "Mike", Age: 36}) {
+		t.Errorf("unexpected %d %+v", w.Code, e)
+	}
+	if w := do(t, "PUT", "/employees/Nobody", `{"age":36}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+	if w := do(t, "PUT", "/employees/Mike", `{"name":"Michael","age":36}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 when renaming, got %d", w.Code)
+	}
+	if w := do(t, "PUT", "/employees/Mike", `{"age":3}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", w.Code)
+	}
+}
+
+func TestPatchEmployee(t *testing.T) {
+	resetEmployees()
+	w := do(t, "PATCH", "/employees/Rose", `{"age":46}`)
+	var e Employee
+	decode(t, w, &e)
+	if w.Code != http.StatusOK || e != (Employee{ID: "e-2", Name: "Rose", Age: 46}) {
+		t.Errorf("unexpected %d %+v", w.Code, e)
+	}
+	if w := do(t, "PATCH", "/employees/Rose", `{"age":-1}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", w.Code)
+	}
+	if w := do(t, "PATCH", "/employees/Nobody", `{"age":46}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestDeleteEmployee(t *testing.T) {
+	resetEmployees()
+	if w := do(t, "DELETE", "/employees/Rose", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	if w := do(t, "GET", "/employees/Rose", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+	if w := do(t, "DELETE", "/employees/Rose", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestListEmployees(t *testing.T) {
+	resetEmployees()
+	for _, body := range []string{`{"name":"Amy","age":22}`, `{"name":"Bob","age":35}`, `{"name":"Zoe","age":60}`} {
+		if w := do(t, "POST", "/employees", body); w.Code != http.StatusCreated {
+			t.Fatalf("create %s: %d", body, w.Code)
+		}
+	}
+
+	var page EmployeePage
+	decode(t, do(t, "GET", "/employees", ""), &page)
+	if page.Total != 5 || len(page.Items) != 5 || page.Items[0].Name != "Amy" || page.Items[4].Name != "Zoe" {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	page = EmployeePage{}
+	decode(t, do(t, "GET", "/employees?offset=1&limit=2", ""), &page)
+	if page.Total != 5 || len(page.Items) != 2 || page.Items[0].Name != "Bob" || page.Items[1].Name != "Mike" {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	page = EmployeePage{}
+	decode(t, do(t, "GET", "/employees?min_age=30&max_age=50", ""), &page)
+	if page.Total != 3 {
+		t.Errorf("expected Bob, Mike and Rose, got %+v", page)
+	}
+
+	page = EmployeePage{}
+	decode(t, do(t, "GET", "/employees?age=35", ""), &page)
+	if page.Total != 2 {
+		t.Errorf("expected Bob and Mike, got %+v", page)
+	}
+
+	page = EmployeePage{}
+	decode(t, do(t, "GET", "/employees?offset=10", ""), &page)
+	if page.Total != 5 || len(page.Items) != 0 {
+		t.Errorf("expected an empty page, got %+v", page)
+	}
+
+	var body errorEnvelope
+	w := do(t, "GET", "/employees?limit=abc", "")
+	decode(t, w, &body)
+	if w.Code != http.StatusBadRequest || body.Error.Code != "bad_request" {
+		t.Errorf("expected 400, got %d %+v", w.Code, body)
+	}
+}
+
+func TestListEmployeesNDJSON(t *testing.T) {
+	resetEmployees()
+	req := httptest.NewRequest("GET", "/employees", nil)
+	req.Header.Set(auth.APIKeyHeader, readerKey)
+	req.Header.Set("Accept", "application/json;q=0.5, application/x-ndjson")
+	w := httptest.NewRecorder()
+	NewRouter(testStore, testAuthn).ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" || w.Header().Get("X-Total-Count") != "2" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	var e Employee
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[1]), &e) != nil || e.Name != "Rose" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/employees", nil)
+	req.Header.Set(auth.APIKeyHeader, readerKey)
+	req.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	NewRouter(testStore, testAuthn).ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("expected 406, got %d", w.Code)
+	}
+}
+
+func TestServerHandler(t *testing.T) {
+	resetEmployees()
+	req := httptest.NewRequest("GET", "/employees/Mike", nil)
+	req.Header.Set(auth.APIKeyHeader, readerKey)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	NewServerHandler(NewRouter(testStore, testAuthn), ioutil.Discard, ioutil.Discard).ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("X-Request-ID") != "req-1" || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("unexpected %d %v", w.Code, w.Header())
+	}
+
+	req = httptest.NewRequest("GET", "/employees/Nobody", nil)
+	req.Header.Set(auth.APIKeyHeader, readerKey)
+	req.Header.Set("X-Request-ID", "req-2")
+	w = httptest.NewRecorder()
+	NewServerHandler(NewRouter(testStore, testAuthn), ioutil.Discard, ioutil.Discard).ServeHTTP(w, req)
+	var body errorEnvelope
+	decode(t, w, &body)
+	if body.Error.RequestID != "req-2" {
+		t.Errorf("expected the request ID in the error, got %+v", body)
+	}
+}
+
+func TestEmployeeRoles(t *testing.T) {
+	resetEmployees()
+	var body errorEnvelope
+	w := doAs(t, "", "GET", "/employees/Mike", "")
+	decode(t, w, &body)
+	if w.Code != http.StatusUnauthorized || body.Error.Code != "unauthorized" {
+		t.Errorf("expected 401, got %d %+v", w.Code, body)
+	}
+	if w := doAs(t, "wrong-key", "GET", "/employees", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+	if w := doAs(t, readerKey, "GET", "/employees/Mike", ""); w.Code != http.StatusOK {
+		t.Errorf("a reader should read, got %d", w.Code)
+	}
+	for _, method := range []string{"PATCH", "DELETE"} {
+		if w := doAs(t, readerKey, method, "/employees/Mike", `{"age":36}`); w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", method, w.Code)
+		}
+	}
+	if w := doAs(t, readerKey, "POST", "/employees", `{"name":"Jack","age":28}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", w.Code)
+	}
+	if w := doAs(t, "", "GET", "/", ""); w.Code != http.StatusOK {
+		t.Errorf("the index should be public, got %d", w.Code)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	resetEmployees()
+	router := NewRouter(testStore, testAuthn)
+	codes := map[int]int{}
+	for i := 0; i < writeRate.Burst+1; i++ {
+		req := httptest.NewRequest("DELETE", "/employees/Nobody", nil)
+		req.Header.Set(auth.APIKeyHeader, adminKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes[w.Code]++
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Error("expected Retry-After")
+		}
+	}
+	if codes[http.StatusNotFound] != writeRate.Burst || codes[http.StatusTooManyRequests] != 1 {
+		t.Errorf("expected the burst to pass and one 429, got %v", codes)
+	}
+
+	// random keys are limited by IP before authentication
+	router = NewRouter(testStore, testAuthn)
+	codes = map[int]int{}
+	for i := 0; i < ipRate.Burst+1; i++ {
+		req := httptest.NewRequest("GET", "/employees", nil)
+		req.Header.Set(auth.APIKeyHeader, fmt.Sprintf("guess-%d", i))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes[w.Code]++
+	}
+	if codes[http.StatusUnauthorized] != ipRate.Burst || codes[http.StatusTooManyRequests] != 1 {
+		t.Errorf("expected the IP burst to pass and one 429, got %v", codes)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	resetEmployees()
+	w := doAs(t, "", "GET", "/openapi.json", "")
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Summary string `json:"summary"`
+		} `json:"paths"`
+	}
+	decode(t, w, &doc)
+	for path, methods := range map[string][]string{
+		"/employees":        {"get", "post"},
+		"/employees/{name}": {"get", "put", "patch", "delete"},
+	} {
+		for _, method := range methods {
+			if doc.Paths[path][method].Summary == "" {
+				t.Errorf("expected %s %s in %+v", method, path, doc.Paths)
+			}
+		}
+	}
+
+	var body errorEnvelope
+	w = do(t, "GET", "/employees?limit=-1", "")
+	decode(t, w, &body)
+	if w.Code != http.StatusBadRequest || body.Error.Details["limit"] == "" {
+		t.Errorf("expected 400 of limit, got %d %+v", w.Code, body)
+	}
+	if w := do(t, "GET", "/employees/1Mike", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 of the name, got %d", w.Code)
+	}
+	if w := do(t, "PATCH", "/employees/Mike", `{"age":"36"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 of the type of age, got %d", w.Code)
+	}
+}
+
+func TestCaching(t *testing.T) {
+	resetEmployees()
+	router := NewRouter(testStore, testAuthn)
+	send := func(method, url, body, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(auth.APIKeyHeader, adminKey)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "/employees/Mike", "", "")
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Cache-Control") != "private, no-cache" || w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	if w := send("GET", "/employees/Mike", "", etag); w.Code != http.StatusNotModified || w.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected 304 from the cache, got %d %v", w.Code, w.Header())
+	}
+	list := send("GET", "/employees", "", "").Header().Get("ETag")
+
+	// a write drops the employee and the lists
+	if w := send("PATCH", "/employees/Mike", `{"age":36}`, ""); w.Code != http.StatusOK {
+		t.Fatalf("patch: %d", w.Code)
+	}
+	w = send("GET", "/employees/Mike", "", etag)
+	var e Employee
+	decode(t, w, &e)
+	if w.Code != http.StatusOK || e.Age != 36 || w.Header().Get("ETag") == etag {
+		t.Errorf("expected the new employee, got %d %+v %v", w.Code, e, w.Header())
+	}
+	if w := send("GET", "/employees", "", list); w.Code != http.StatusOK {
+		t.Errorf("expected the new list, got %d", w.Code)
+	}
+	if w := send("GET", "/employees/Rose", "", ""); w.Code != http.StatusOK {
+		t.Errorf("unexpected %d", w.Code)
+	}
+	if w := send("DELETE", "/employees/Rose", "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("unexpected %d", w.Code)
+	}
+	if w := send("GET", "/employees/Rose", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("a deleted employee should not be served from the cache, got %d", w.Code)
+	}
+}
@@ -1,57 +1,98 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/julienschmidt/httprouter"
+	"context"
+	"errors"
+	"flag"
+	"go_learning/src/25_http/auth"
+	"go_learning/src/25_http/roa/handler"
+	"go_learning/src/25_http/roa/store"
+	"go_learning/src/25_http/server"
 	"log"
-	"net/http"
+	"os"
+	"time"
 )
 
-type Employee struct {
-	ID string `json:"id"`
-	Name string `json:"name"`
-	Age int `json:"age"`
-}
-
-var employeeDB map[string]*Employee
-
-func init() {
-	employeeDB = map[string]*Employee{}
-	employeeDB["Mike"] = &Employee{"e-1", "Mike", 35}
-	employeeDB["Rose"] = &Employee{"e-2", "Rose", 45}
+// newAuthenticator accepts the API keys and Basic credentials of the files,
+// and JWTs signed with $EMPLOYEE_JWT_SECRET when it is set.
+func newAuthenticator(apiKeysFile, basicFile string, insecure bool) (auth.Authenticator, error) {
+	if insecure {
+		log.Println("WARNING: authentication is disabled, every request may read and write employees")
+		return auth.Static(auth.Principal{Subject: "anonymous", Roles: []string{handler.RoleRead, handler.RoleWrite}}), nil
+	}
+	var authns []auth.Authenticator
+	if apiKeysFile != "" {
+		keys, err := auth.LoadAPIKeysFile(apiKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		authns = append(authns, keys)
+	}
+	if basicFile != "" {
+		basic, err := auth.LoadBasicFile(basicFile, "employees")
+		if err != nil {
+			return nil, err
+		}
+		authns = append(authns, basic)
+	}
+	if secret := os.Getenv("EMPLOYEE_JWT_SECRET"); secret != "" {
+		jwt, err := auth.NewJWT(auth.JWTConfig{Secret: []byte(secret), Audience: "employees", Leeway: time.Minute, Realm: "employees"})
+		if err != nil {
+			return nil, err
+		}
+		authns = append(authns, jwt)
+	}
+	if len(authns) == 0 {
+		return nil, errors.New("no authentication configured, use -api-keys, -basic, $EMPLOYEE_JWT_SECRET or -insecure")
+	}
+	return auth.Chain(authns...), nil
 }
 
-// http://localhost:8080/
-func Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	fmt.Fprint(w, "Welcome!\n")
-}
+// $ go run . -data employees.log -basic users.txt
+// the API is documented at http://localhost:8080/openapi.json
+// keeps the employees in an append-only log file, without -data they are kept in memory,
+// the lines of users.txt are made by auth/cmd/authhash
+func main() {
+	dataFile := flag.String("data", "", "path of the employee log file, empty for an in-memory store")
+	apiKeysFile := flag.String("api-keys", "", "file of the API keys, subject:sha256-of-key:roles per line")
+	basicFile := flag.String("basic", "", "file of the HTTP Basic users, user:password-hash:roles per line")
+	insecure := flag.Bool("insecure", false, "disable authentication")
+	cfg := server.DefaultConfig(":8080")
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-// http://localhost:8080/employees/Mike
-// http://localhost:8080/employees/Rose
-func GetEmployeeByName(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	qName := ps.ByName("name")
-	var (
-		ok			bool
-		info		*Employee
-		infoJson	[]byte
-		err			error
-	)
-	if info, ok = employeeDB[qName]; !ok {
-		w.Write([]byte("{\"error\":\"Not Found\"}"))
-		return
-	}
-	if infoJson, err = json.Marshal(info); err != nil {
-		w.Write([]byte(fmt.Sprintf("{\"error\":,\"%s\"}", err)))
-		return
-	}
-	w.Write(infoJson)
-}
+	authn, err := newAuthenticator(*apiKeysFile, *basicFile, *insecure)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func main() {
-	router := httprouter.New()
-	router.GET("/", Index)
-	router.GET("/employees/:name", GetEmployeeByName)
+	var s store.EmployeeStore = store.NewMemoryStore()
+	seed := true
+	if *dataFile != "" {
+		fs, err := store.OpenFileStore(*dataFile, true)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// only a new log is seeded, deleted employees don't come back after a restart
+		s, seed = fs, fs.Created()
+	}
+	if seed {
+		if err := handler.Seed(s); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	log.Fatal(http.ListenAndServe(":8080", router))
+	health := server.NewHealth()
+	health.AddCheck("store", func(ctx context.Context) error {
+		_, err := s.List()
+		return err
+	})
+	err = server.Run(context.Background(), cfg, handler.NewServerHandler(handler.NewRouter(s, authn), os.Stdout, os.Stderr), health)
+	// the requests have been drained, nothing writes to the store any more
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the openapi tags document the rules of Validate, TestOpenAPITags keeps them the same
+type Employee struct {
+	ID   string `json:"id" openapi:"pattern=^(e-[0-9]+)?$"`
+	Name string `json:"name" openapi:"pattern=^[A-Za-z][A-Za-z0-9_-]{0,63}$"`
+	Age  int    `json:"age" openapi:"minimum=16;maximum=100"`
+}
+
+// NamePattern is the pattern of the names, the name is used as the resource id in /employees/:name, so keep it URL safe
+const NamePattern = `^[A-Za-z][A-Za-z0-9_-]{0,63}$`
+
+var namePattern = regexp.MustCompile(NamePattern)
+var idPattern = regexp.MustCompile(`^e-[0-9]+$`)
+
+const (
+	minAge = 16
+	maxAge = 100
+)
+
+// ValidationError maps each invalid field to the reason.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	var strs []string
+	for field, reason := range e.Fields {
+		strs = append(strs, field+": "+reason)
+	}
+	sort.Strings(strs)
+	return "invalid employee: " + strings.Join(strs, "; ")
+}
+
+// Validate checks every field of e, an empty ID is allowed and assigned on creation.
+func (e *Employee) Validate() error {
+	fields := map[string]string{}
+	if e.ID != "" && !idPattern.MatchString(e.ID) {
+		fields["id"] = "should look like e-<number>"
+	}
+	if !namePattern.MatchString(e.Name) {
+		fields["name"] = "should start with a letter and contain at most 64 letters, digits, '_' or '-'"
+	}
+	if e.Age < minAge || e.Age > maxAge {
+		fields["age"] = fmt.Sprintf("should be between %d and %d", minAge, maxAge)
+	}
+	if len(fields) > 0 {
+		return &ValidationError{fields}
+	}
+	return nil
+}
+
+// EmployeePatch holds the fields of a PATCH request, nil fields are left unchanged.
+type EmployeePatch struct {
+	ID   *string `json:"id" openapi:"pattern=^(e-[0-9]+)?$"`
+	Name *string `json:"name" openapi:"pattern=^[A-Za-z][A-Za-z0-9_-]{0,63}$"`
+	Age  *int    `json:"age" openapi:"minimum=16;maximum=100"`
+}
+
+func (p *EmployeePatch) Apply(e Employee) Employee {
+	if p.ID != nil {
+		e.ID = *p.ID
+	}
+	if p.Name != nil {
+		e.Name = *p.Name
+	}
+	if p.Age != nil {
+		e.Age = *p.Age
+	}
+	return e
+}
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPITags checks that the openapi tags, validated before the handlers run, follow Validate.
+func TestOpenAPITags(t *testing.T) {
+	want := map[string]string{
+		"ID":   "pattern=^(" + strings.TrimSuffix(strings.TrimPrefix(idPattern.String(), "^"), "$") + ")?$",
+		"Name": "pattern=" + namePattern.String(),
+		"Age":  fmt.Sprintf("minimum=%d;maximum=%d", minAge, maxAge),
+	}
+	for _, typ := range []reflect.Type{reflect.TypeOf(Employee{}), reflect.TypeOf(EmployeePatch{})} {
+		for field, tag := range want {
+			f, ok := typ.FieldByName(field)
+			if !ok {
+				t.Fatalf("%v has no field %s", typ, field)
+			}
+			if got := f.Tag.Get("openapi"); got != tag {
+				t.Errorf("%v.%s: expected openapi tag %q, got %q", typ, field, tag, got)
+			}
+		}
+	}
+}
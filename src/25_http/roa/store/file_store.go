@@ -0,0 +1,290 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+/*
+	FileStore 把每一次修改當成一行 JSON append 到 log 檔（append-only log）：
+		{"op":"put","name":"Mike","employee":{"id":"e-1","name":"Mike","age":35}}
+		{"op":"delete","name":"Mike"}
+	重新啟動時依序 replay 所有紀錄就能還原資料。
+	寫到一半就 crash 的最後一行（沒有換行）會被忽略並截掉；中間的紀錄壞掉則視為檔案損毀。
+	log 會越來越長，Compact 會把目前的資料重寫成新的 log 再 rename 蓋掉舊檔。
+	寫入或 fsync 失敗時（磁碟滿了）把檔案 truncate 回寫入前的長度，不留下半行，之後的寫入還能接在後面；
+	連 truncate 都失敗的話不再接受寫入（BrokenError），重新開啟時 replay 會截掉最後的半行。
+*/
+
+var (
+	ClosedError = errors.New("store is closed")
+	BrokenError = errors.New("store log has a partial record, reopen the store")
+)
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+	opSeq    = "seq" // written by Compact, so IDs of deleted employees are not reused
+)
+
+type record struct {
+	Op       string    `json:"op"`
+	Name     string    `json:"name"`
+	Employee *Employee `json:"employee,omitempty"`
+	Seq      int       `json:"seq,omitempty"`
+}
+
+// CorruptedError is returned by OpenFileStore when a record in the middle of the log is unreadable.
+type CorruptedError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *CorruptedError) Error() string {
+	return fmt.Sprintf("corrupted store %s at line %d: %v", e.Path, e.Line, e.Err)
+}
+
+func (e *CorruptedError) Unwrap() error {
+	return e.Err
+}
+
+type FileStore struct {
+	path string
+	sync bool // fsync after every write
+
+	mu    sync.RWMutex
+	state *state
+	f     logFile
+	// created is true when the log had no records at open
+	created bool
+	// broken is the error of a failed write that couldn't be truncated away
+	broken error
+}
+
+// logFile is the *os.File of the log, tests make its writes fail
+type logFile interface {
+	io.ReadWriteCloser
+	io.Seeker
+	Sync() error
+	Truncate(size int64) error
+}
+
+// OpenFileStore replays the log at path, creating it if needed.
+// With syncWrites every change is fsynced before it is visible.
+func OpenFileStore(path string, syncWrites bool) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{path: path, sync: syncWrites, state: newState(), f: f}
+	if err := s.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) replay() error {
+	r := bufio.NewReader(s.f)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			s.created = line == 1
+			// a partial last record means a crash during the write, drop it
+			if len(data) > 0 {
+				if err := s.f.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.apply(data); err != nil {
+			return &CorruptedError{s.path, line, err}
+		}
+		offset += int64(len(data))
+	}
+	return nil
+}
+
+func (s *FileStore) apply(data []byte) error {
+	var rec record
+	if err := json.Unmarshal(bytes.TrimSpace(data), &rec); err != nil {
+		return err
+	}
+	switch rec.Op {
+	case opPut:
+		if rec.Employee == nil {
+			return errors.New("put without employee")
+		}
+		s.state.put(rec.Name, *rec.Employee)
+	case opDelete:
+		delete(s.state.employees, rec.Name)
+	case opSeq:
+		if rec.Seq > s.state.seq {
+			s.state.seq = rec.Seq
+		}
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// Created reports whether the log had no records when it was opened,
+// unlike an empty List it is false for a log whose employees were all deleted.
+func (s *FileStore) Created() bool {
+	return s.created
+}
+
+// append must be called with s.mu held.
+func (s *FileStore) append(rec record) error {
+	if s.f == nil {
+		return ClosedError
+	}
+	if s.broken != nil {
+		return s.broken
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	offset, err := s.f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	_, err = s.f.Write(append(data, '\n'))
+	if err == nil && s.sync {
+		err = s.f.Sync()
+	}
+	if err != nil {
+		// the record is not applied, so it must not be in the log either
+		if truncErr := s.f.Truncate(offset); truncErr != nil {
+			s.broken = fmt.Errorf("%w: %v", BrokenError, truncErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Get(name string) (Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.get(name)
+}
+
+func (s *FileStore) List() ([]Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.list(), nil
+}
+
+func (s *FileStore) Create(e Employee) (Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, err := s.state.prepareCreate(e)
+	if err != nil {
+		return Employee{}, err
+	}
+	if err := s.append(record{Op: opPut, Name: e.Name, Employee: &e}); err != nil {
+		return Employee{}, err
+	}
+	s.state.put(e.Name, e)
+	return e, nil
+}
+
+func (s *FileStore) Update(name string, fn func(old Employee) (Employee, error)) (Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, err := s.state.prepareUpdate(name, fn)
+	if err != nil {
+		return Employee{}, err
+	}
+	if err := s.append(record{Op: opPut, Name: name, Employee: &e}); err != nil {
+		return Employee{}, err
+	}
+	s.state.put(name, e)
+	return e, nil
+}
+
+func (s *FileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.state.get(name); err != nil {
+		return err
+	}
+	if err := s.append(record{Op: opDelete, Name: name}); err != nil {
+		return err
+	}
+	return s.state.delete(name)
+}
+
+// Compact rewrites the log with one record per employee.
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return ClosedError
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(record{Op: opSeq, Seq: s.state.seq})
+	for _, e := range s.state.list() {
+		if err != nil {
+			break
+		}
+		e := e
+		err = encoder.Encode(record{Op: opPut, Name: e.Name, Employee: &e})
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	s.f.Close()
+	s.f = nil
+	f, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	// the new log has no partial record
+	s.f, s.broken = f, nil
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var diskFullError = errors.New("no space left on device")
+
+// failingFile writes half of the data of a failing write, like a full disk
+type failingFile struct {
+	*os.File
+	failWrites    bool
+	failTruncates bool
+}
+
+func (f *failingFile) Write(data []byte) (int, error) {
+	if !f.failWrites {
+		return f.File.Write(data)
+	}
+	n, _ := f.File.Write(data[:len(data)/2])
+	return n, diskFullError
+}
+
+func (f *failingFile) Truncate(size int64) error {
+	if f.failTruncates {
+		return diskFullError
+	}
+	return f.File.Truncate(size)
+}
+
+func openFailing(t *testing.T, path string) (*FileStore, *failingFile) {
+	t.Helper()
+	s, err := OpenFileStore(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &failingFile{File: s.f.(*os.File)}
+	s.f = f
+	return s, f
+}
+
+func TestFileStoreFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.log")
+	s, f := openFailing(t, path)
+	s.Create(Employee{Name: "Mike", Age: 35})
+	f.failWrites = true
+	if _, err := s.Create(Employee{Name: "Rose", Age: 45}); !errors.Is(err, diskFullError) {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+	// the partial record is truncated away, later writes go on
+	f.failWrites = false
+	if _, err := s.Create(Employee{Name: "Jack", Age: 28}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err := OpenFileStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	list, _ := s.List()
+	if len(list) != 2 || list[0].Name != "Jack" || list[1].Name != "Mike" {
+		t.Errorf("unexpected %+v", list)
+	}
+}
+
+func TestFileStoreBroken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.log")
+	s, f := openFailing(t, path)
+	s.Create(Employee{Name: "Mike", Age: 35})
+	f.failWrites, f.failTruncates = true, true
+	if _, err := s.Create(Employee{Name: "Rose", Age: 45}); !errors.Is(err, diskFullError) {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+	// a record after the partial one would make the log corrupted, so the store refuses it
+	f.failWrites, f.failTruncates = false, false
+	if _, err := s.Create(Employee{Name: "Jack", Age: 28}); !errors.Is(err, BrokenError) {
+		t.Fatalf("expected BrokenError, got %v", err)
+	}
+	if err := s.Delete("Mike"); !errors.Is(err, BrokenError) {
+		t.Fatalf("expected BrokenError, got %v", err)
+	}
+	s.Close()
+
+	// reopening drops the partial last record
+	s, err := OpenFileStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	list, _ := s.List()
+	if len(list) != 1 || list[0].Name != "Mike" {
+		t.Errorf("unexpected %+v", list)
+	}
+}
@@ -0,0 +1,127 @@
+package store_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go_learning/src/25_http/roa/store"
+	"go_learning/src/25_http/roa/store/storetest"
+)
+
+func openFileStore(t *testing.T, path string) *store.FileStore {
+	t.Helper()
+	s, err := store.OpenFileStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFileStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.EmployeeStore {
+		return openFileStore(t, filepath.Join(t.TempDir(), "employees.log"))
+	})
+}
+
+func TestFileStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.log")
+	s := openFileStore(t, path)
+	s.Create(store.Employee{Name: "Mike", Age: 35})
+	s.Create(store.Employee{Name: "Rose", Age: 45})
+	s.Update("Mike", func(old store.Employee) (store.Employee, error) {
+		old.Age = 36
+		return old, nil
+	})
+	s.Delete("Rose")
+	if !s.Created() {
+		t.Error("a new log should be created")
+	}
+	s.Close()
+
+	s = openFileStore(t, path)
+	defer s.Close()
+	if s.Created() {
+		t.Error("a log with records should not be created")
+	}
+	list, _ := s.List()
+	if len(list) != 1 || list[0] != (store.Employee{ID: "e-1", Name: "Mike", Age: 36}) {
+		t.Errorf("unexpected %+v", list)
+	}
+	if jack, _ := s.Create(store.Employee{Name: "Jack", Age: 28}); jack.ID != "e-3" {
+		t.Errorf("expected e-3, got %q", jack.ID)
+	}
+}
+
+func TestFileStoreCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.log")
+	s := openFileStore(t, path)
+	for i := 0; i < 10; i++ {
+		s.Create(store.Employee{Name: "Mike", Age: 35})
+		s.Delete("Mike")
+	}
+	s.Create(store.Employee{Name: "Rose", Age: 45})
+	before, _ := os.Stat(path)
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("expected a smaller log, %d >= %d", after.Size(), before.Size())
+	}
+	// still writable after compaction
+	s.Create(store.Employee{Name: "Jack", Age: 28})
+	s.Close()
+
+	s = openFileStore(t, path)
+	defer s.Close()
+	list, _ := s.List()
+	if len(list) != 2 || list[0].Name != "Jack" || list[0].ID != "e-12" || list[1].Name != "Rose" {
+		t.Errorf("unexpected %+v", list)
+	}
+}
+
+func TestFileStoreTruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.log")
+	s := openFileStore(t, path)
+	s.Create(store.Employee{Name: "Mike", Age: 35})
+	s.Close()
+
+	// a crash in the middle of a write
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"op":"put","name":"Rose","employee":{"id":"e-2","na`)
+	f.Close()
+
+	s = openFileStore(t, path)
+	list, _ := s.List()
+	if len(list) != 1 || list[0].Name != "Mike" {
+		t.Errorf("unexpected %+v", list)
+	}
+	s.Create(store.Employee{Name: "Rose", Age: 45})
+	s.Close()
+
+	s = openFileStore(t, path)
+	defer s.Close()
+	if list, _ := s.List(); len(list) != 2 {
+		t.Errorf("unexpected %+v", list)
+	}
+}
+
+func TestFileStoreCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.log")
+	os.WriteFile(path, []byte("{\"op\":\"put\",\"name\":\"Mike\",\"employee\":{\"id\":\"e-1\",\"name\":\"Mike\",\"age\":35}}\nnot json\n{\"op\":\"delete\",\"name\":\"Mike\"}\n"), 0644)
+	_, err := store.OpenFileStore(path, false)
+	var ce *store.CorruptedError
+	if !errors.As(err, &ce) || ce.Line != 2 {
+		t.Errorf("expected CorruptedError at line 2, got %v", err)
+	}
+}
+
+func TestFileStoreClosed(t *testing.T) {
+	s := openFileStore(t, filepath.Join(t.TempDir(), "employees.log"))
+	s.Close()
+	if _, err := s.Create(store.Employee{Name: "Mike", Age: 35}); err != store.ClosedError {
+		t.Errorf("expected ClosedError, got %v", err)
+	}
+}
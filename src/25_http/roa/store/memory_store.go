@@ -0,0 +1,61 @@
+package store
+
+import "sync"
+
+// MemoryStore keeps employees in a map guarded by a RWMutex, data is lost on restart.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	state *state
+}
+
+func NewMemoryStore(employees ...Employee) *MemoryStore {
+	s := &MemoryStore{state: newState()}
+	for _, e := range employees {
+		s.Create(e)
+	}
+	return s
+}
+
+func (s *MemoryStore) Get(name string) (Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.get(name)
+}
+
+func (s *MemoryStore) List() ([]Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.list(), nil
+}
+
+func (s *MemoryStore) Create(e Employee) (Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, err := s.state.prepareCreate(e)
+	if err != nil {
+		return Employee{}, err
+	}
+	s.state.put(e.Name, e)
+	return e, nil
+}
+
+func (s *MemoryStore) Update(name string, fn func(old Employee) (Employee, error)) (Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, err := s.state.prepareUpdate(name, fn)
+	if err != nil {
+		return Employee{}, err
+	}
+	s.state.put(name, e)
+	return e, nil
+}
+
+func (s *MemoryStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.delete(name)
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
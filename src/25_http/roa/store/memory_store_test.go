@@ -0,0 +1,14 @@
+package store_test
+
+import (
+	"testing"
+
+	"go_learning/src/25_http/roa/store"
+	"go_learning/src/25_http/roa/store/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.EmployeeStore {
+		return store.NewMemoryStore()
+	})
+}
@@ -0,0 +1,41 @@
+package store
+
+// NotifyingStore calls onChange with the names of the employees changed by each successful write,
+// so caches of the employees can drop what is stale.
+type NotifyingStore struct {
+	EmployeeStore
+	onChange func(names ...string)
+}
+
+// Notify wraps s, onChange is called after the write and must not call the store.
+func Notify(s EmployeeStore, onChange func(names ...string)) *NotifyingStore {
+	return &NotifyingStore{EmployeeStore: s, onChange: onChange}
+}
+
+func (s *NotifyingStore) Create(e Employee) (Employee, error) {
+	e, err := s.EmployeeStore.Create(e)
+	if err == nil {
+		s.onChange(e.Name)
+	}
+	return e, err
+}
+
+func (s *NotifyingStore) Update(name string, fn func(old Employee) (Employee, error)) (Employee, error) {
+	e, err := s.EmployeeStore.Update(name, fn)
+	if err == nil {
+		if e.Name != name {
+			s.onChange(name, e.Name)
+		} else {
+			s.onChange(name)
+		}
+	}
+	return e, err
+}
+
+func (s *NotifyingStore) Delete(name string) error {
+	err := s.EmployeeStore.Delete(name)
+	if err == nil {
+		s.onChange(name)
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+package store_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"go_learning/src/25_http/roa/store"
+	"go_learning/src/25_http/roa/store/storetest"
+)
+
+func TestNotifyingStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.EmployeeStore {
+		return store.Notify(store.NewMemoryStore(), func(names ...string) {})
+	})
+}
+
+func TestNotify(t *testing.T) {
+	var mu sync.Mutex
+	var changed [][]string
+	s := store.Notify(store.NewMemoryStore(), func(names ...string) {
+		mu.Lock()
+		defer mu.Unlock()
+		changed = append(changed, names)
+	})
+	s.Create(store.Employee{Name: "Mike", Age: 35})
+	s.Create(store.Employee{Name: "Mike", Age: 35})
+	s.Update("Mike", func(old store.Employee) (store.Employee, error) {
+		old.Name = "Michael"
+		return old, nil
+	})
+	s.Delete("Michael")
+	s.Delete("Nobody")
+	want := [][]string{{"Mike"}, {"Mike", "Michael"}, {"Michael"}}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("expected %v, got %v", want, changed)
+	}
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var NotFoundError = errors.New("employee not found")
+var AlreadyExistsError = errors.New("employee already exists")
+
+// EmployeeStore keeps employees by name. Implementations must be safe for concurrent use.
+type EmployeeStore interface {
+	// Get returns NotFoundError if there is no employee called name.
+	Get(name string) (Employee, error)
+	// List returns all employees sorted by name.
+	List() ([]Employee, error)
+	// Create adds e, assigning an ID if e.ID is empty. It returns AlreadyExistsError if the name is taken.
+	Create(e Employee) (Employee, error)
+	// Update replaces the employee called name with the result of fn, atomically.
+	// An error of fn is returned as is and nothing is changed.
+	Update(name string, fn func(old Employee) (Employee, error)) (Employee, error)
+	// Delete returns NotFoundError if there is no employee called name.
+	Delete(name string) error
+	Close() error
+}
+
+// state is the data shared by the stores, callers take care of the locking.
+type state struct {
+	employees map[string]Employee
+	seq       int // the last assigned ID number
+}
+
+func newState() *state {
+	return &state{employees: map[string]Employee{}}
+}
+
+func (s *state) get(name string) (Employee, error) {
+	e, ok := s.employees[name]
+	if !ok {
+		return Employee{}, NotFoundError
+	}
+	return e, nil
+}
+
+func (s *state) list() []Employee {
+	ret := make([]Employee, 0, len(s.employees))
+	for _, e := range s.employees {
+		ret = append(ret, e)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// prepareCreate checks e can be created and assigns its ID, without changing s.
+func (s *state) prepareCreate(e Employee) (Employee, error) {
+	if _, ok := s.employees[e.Name]; ok {
+		return Employee{}, fmt.Errorf("%w: %s", AlreadyExistsError, e.Name)
+	}
+	if e.ID == "" {
+		e.ID = fmt.Sprintf("e-%d", s.seq+1)
+	}
+	return e, nil
+}
+
+// prepareUpdate returns the new value of name, without changing s.
+func (s *state) prepareUpdate(name string, fn func(old Employee) (Employee, error)) (Employee, error) {
+	old, err := s.get(name)
+	if err != nil {
+		return Employee{}, err
+	}
+	e, err := fn(old)
+	if err != nil {
+		return Employee{}, err
+	}
+	if e.Name != name {
+		if _, ok := s.employees[e.Name]; ok {
+			return Employee{}, fmt.Errorf("%w: %s", AlreadyExistsError, e.Name)
+		}
+	}
+	return e, nil
+}
+
+// put stores e under name, name may differ from e.Name on rename.
+func (s *state) put(name string, e Employee) {
+	if name != e.Name {
+		delete(s.employees, name)
+	}
+	s.employees[e.Name] = e
+	if n, err := strconv.Atoi(strings.TrimPrefix(e.ID, "e-")); err == nil && n > s.seq {
+		s.seq = n
+	}
+}
+
+func (s *state) delete(name string) error {
+	if _, ok := s.employees[name]; !ok {
+		return NotFoundError
+	}
+	delete(s.employees, name)
+	return nil
+}
@@ -0,0 +1,221 @@
+// Package storetest is the conformance suite every store.EmployeeStore implementation must pass.
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go_learning/src/25_http/roa/store"
+)
+
+// NewStore returns an empty store, it is closed by the suite.
+type NewStore func(t *testing.T) store.EmployeeStore
+
+// Run runs every conformance test against the stores created by newStore.
+func Run(t *testing.T, newStore NewStore) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.EmployeeStore)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"CreateExisting", testCreateExisting},
+		{"GetMissing", testGetMissing},
+		{"List", testList},
+		{"Update", testUpdate},
+		{"UpdateError", testUpdateError},
+		{"Rename", testRename},
+		{"Delete", testDelete},
+		{"IDsAreNotReused", testIDsAreNotReused},
+		{"ConcurrentWrites", testConcurrentWrites},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			s := newStore(t)
+			defer s.Close()
+			test.fn(t, s)
+		})
+	}
+}
+
+func mustCreate(t *testing.T, s store.EmployeeStore, e store.Employee) store.Employee {
+	t.Helper()
+	created, err := s.Create(e)
+	if err != nil {
+		t.Fatalf("create %+v: %v", e, err)
+	}
+	return created
+}
+
+func testCreateAndGet(t *testing.T, s store.EmployeeStore) {
+	mike := mustCreate(t, s, store.Employee{Name: "Mike", Age: 35})
+	if mike.ID == "" {
+		t.Error("an ID should be assigned")
+	}
+	rose := mustCreate(t, s, store.Employee{ID: "e-42", Name: "Rose", Age: 45})
+	if rose.ID != "e-42" {
+		t.Errorf("the given ID should be kept, got %q", rose.ID)
+	}
+	if got, err := s.Get("Mike"); err != nil || got != mike {
+		t.Errorf("expected %+v, got %+v (%v)", mike, got, err)
+	}
+	// new IDs don't collide with given ones
+	jack := mustCreate(t, s, store.Employee{Name: "Jack", Age: 28})
+	if jack.ID == mike.ID || jack.ID == rose.ID {
+		t.Errorf("duplicated ID %q", jack.ID)
+	}
+}
+
+func testCreateExisting(t *testing.T, s store.EmployeeStore) {
+	mustCreate(t, s, store.Employee{Name: "Mike", Age: 35})
+	if _, err := s.Create(store.Employee{Name: "Mike", Age: 36}); !errors.Is(err, store.AlreadyExistsError) {
+		t.Errorf("expected AlreadyExistsError, got %v", err)
+	}
+	if got, _ := s.Get("Mike"); got.Age != 35 {
+		t.Errorf("the existing employee should be kept, got %+v", got)
+	}
+}
+
+func testGetMissing(t *testing.T, s store.EmployeeStore) {
+	if _, err := s.Get("Nobody"); !errors.Is(err, store.NotFoundError) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+}
+
+func testList(t *testing.T, s store.EmployeeStore) {
+	if list, err := s.List(); err != nil || len(list) != 0 {
+		t.Errorf("expected an empty list, got %v (%v)", list, err)
+	}
+	for _, name := range []string{"Rose", "Amy", "Mike"} {
+		mustCreate(t, s, store.Employee{Name: name, Age: 30})
+	}
+	list, err := s.List()
+	if err != nil || len(list) != 3 || list[0].Name != "Amy" || list[1].Name != "Mike" || list[2].Name != "Rose" {
+		t.Errorf("expected the employees sorted by name, got %v (%v)", list, err)
+	}
+}
+
+func testUpdate(t *testing.T, s store.EmployeeStore) {
+	mike := mustCreate(t, s, store.Employee{Name: "Mike", Age: 35})
+	updated, err := s.Update("Mike", func(old store.Employee) (store.Employee, error) {
+		if old != mike {
+			t.Errorf("expected %+v, got %+v", mike, old)
+		}
+		old.Age++
+		return old, nil
+	})
+	if err != nil || updated.Age != 36 {
+		t.Errorf("unexpected %+v (%v)", updated, err)
+	}
+	if got, _ := s.Get("Mike"); got != updated {
+		t.Errorf("expected %+v, got %+v", updated, got)
+	}
+	if _, err := s.Update("Nobody", func(old store.Employee) (store.Employee, error) {
+		t.Error("fn should not be called")
+		return old, nil
+	}); !errors.Is(err, store.NotFoundError) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+}
+
+func testUpdateError(t *testing.T, s store.EmployeeStore) {
+	mustCreate(t, s, store.Employee{Name: "Mike", Age: 35})
+	failed := errors.New("failed")
+	if _, err := s.Update("Mike", func(old store.Employee) (store.Employee, error) {
+		old.Age = 99
+		return old, failed
+	}); err != failed {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+	if got, _ := s.Get("Mike"); got.Age != 35 {
+		t.Errorf("nothing should change, got %+v", got)
+	}
+}
+
+func testRename(t *testing.T, s store.EmployeeStore) {
+	mustCreate(t, s, store.Employee{Name: "Mike", Age: 35})
+	mustCreate(t, s, store.Employee{Name: "Rose", Age: 45})
+	rename := func(name string) func(store.Employee) (store.Employee, error) {
+		return func(old store.Employee) (store.Employee, error) {
+			old.Name = name
+			return old, nil
+		}
+	}
+	if _, err := s.Update("Mike", rename("Rose")); !errors.Is(err, store.AlreadyExistsError) {
+		t.Errorf("expected AlreadyExistsError, got %v", err)
+	}
+	if _, err := s.Update("Mike", rename("Michael")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("Mike"); !errors.Is(err, store.NotFoundError) {
+		t.Errorf("the old name should be gone, got %v", err)
+	}
+	if got, err := s.Get("Michael"); err != nil || got.Age != 35 {
+		t.Errorf("unexpected %+v (%v)", got, err)
+	}
+}
+
+func testDelete(t *testing.T, s store.EmployeeStore) {
+	mustCreate(t, s, store.Employee{Name: "Mike", Age: 35})
+	if err := s.Delete("Mike"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("Mike"); !errors.Is(err, store.NotFoundError) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+	if err := s.Delete("Mike"); !errors.Is(err, store.NotFoundError) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+	// the name can be used again
+	mustCreate(t, s, store.Employee{Name: "Mike", Age: 40})
+}
+
+func testIDsAreNotReused(t *testing.T, s store.EmployeeStore) {
+	first := mustCreate(t, s, store.Employee{Name: "Mike", Age: 35})
+	if err := s.Delete("Mike"); err != nil {
+		t.Fatal(err)
+	}
+	second := mustCreate(t, s, store.Employee{Name: "Rose", Age: 45})
+	if first.ID == second.ID {
+		t.Errorf("ID %q is reused", first.ID)
+	}
+}
+
+func testConcurrentWrites(t *testing.T, s store.EmployeeStore) {
+	mustCreate(t, s, store.Employee{Name: "Counter", Age: 0})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := s.Create(store.Employee{Name: fmt.Sprintf("Worker%d", i), Age: 20}); err != nil {
+				t.Error(err)
+			}
+			if _, err := s.Update("Counter", func(old store.Employee) (store.Employee, error) {
+				old.Age++
+				return old, nil
+			}); err != nil {
+				t.Error(err)
+			}
+			s.Get("Counter")
+			s.List()
+		}(i)
+	}
+	wg.Wait()
+	if got, _ := s.Get("Counter"); got.Age != 20 {
+		t.Errorf("expected 20 atomic updates, got %d", got.Age)
+	}
+	list, _ := s.List()
+	ids := map[string]bool{}
+	for _, e := range list {
+		if ids[e.ID] {
+			t.Errorf("duplicated ID %q", e.ID)
+		}
+		ids[e.ID] = true
+	}
+	if len(list) != 21 {
+		t.Errorf("expected 21 employees, got %d", len(list))
+	}
+}
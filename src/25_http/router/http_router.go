@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
+	"go_learning/src/25_http/middleware"
+	"go_learning/src/25_http/server"
 	"log"
 	"net/http"
+	"os"
 )
 
 func Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -17,9 +22,21 @@ func Hello(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 }
 
 func main() {
+	cfg := server.DefaultConfig(":8080")
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
 	router := httprouter.New()
 	router.GET("/", Index)
 	router.GET("/hello/:name", Hello)
 
-	log.Fatal(http.ListenAndServe(":8080", router))
+	handler := middleware.Chain(
+		middleware.RequestID(),
+		middleware.AccessLog(os.Stdout),
+		middleware.Recover(os.Stderr),
+		middleware.Gzip(),
+	)(router)
+	if err := server.Run(context.Background(), cfg, handler, server.NewHealth()); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_learning/src/25_http/response"
+)
+
+// Check reports whether a dependency can serve requests.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health serves liveness and readiness.
+//
+//	liveness:  the process is alive, failing it gets the process restarted
+//	readiness: the process can serve now, failing it only takes it out of the load balancer
+type Health struct {
+	ready        int32
+	mu           sync.RWMutex
+	checks       []namedCheck
+	checkTimeout time.Duration
+}
+
+func NewHealth() *Health {
+	return &Health{checkTimeout: 2 * time.Second}
+}
+
+// AddCheck adds a dependency checked by readiness.
+func (h *Health) AddCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedCheck{name, check})
+}
+
+// SetReady is called by Serve, true once serving and false when shutting down.
+func (h *Health) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&h.ready, v)
+}
+
+func (h *Health) Ready() bool {
+	return atomic.LoadInt32(&h.ready) == 1
+}
+
+type healthBody struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func noStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noStore(w)
+		response.JSON(w, http.StatusOK, healthBody{Status: "ok"})
+	})
+}
+
+// ReadinessHandler answers 503 while not ready or when a check fails.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noStore(w)
+		if !h.Ready() {
+			response.JSON(w, http.StatusServiceUnavailable, healthBody{Status: "shutting down"})
+			return
+		}
+		h.mu.RLock()
+		checks := h.checks
+		h.mu.RUnlock()
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.checkTimeout)
+		defer cancel()
+		body := healthBody{Status: "ok", Checks: map[string]string{}}
+		status := http.StatusOK
+		for _, c := range checks {
+			if err := c.check(ctx); err != nil {
+				body.Checks[c.name] = err.Error()
+				body.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+			} else {
+				body.Checks[c.name] = "ok"
+			}
+		}
+		response.JSON(w, status, body)
+	})
+}
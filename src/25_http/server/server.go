@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+/*
+	http.ListenAndServe(addr, handler) 用的是沒有任何 timeout 的 http.Server：
+	* 慢慢送 header 的 client（slowloris）可以一直佔住連線
+	* 收到 SIGTERM 時直接結束，處理到一半的 request 全部中斷
+
+	Run 建立設定好 timeout 的 http.Server，並在收到 SIGINT/SIGTERM 時：
+	1. readiness 改回 503，load balancer 不再送新的 request 過來
+	2. 等 ShutdownDelay，讓 load balancer 有時間發現
+	3. http.Server.Shutdown：不再接受新連線，等進行中的 request 完成，最多等 ShutdownTimeout
+	4. 時間到還沒完成的連線直接 Close
+
+	cfg := server.DefaultConfig(":8080")
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	log.Fatal(server.Run(context.Background(), cfg, handler, server.NewHealth()))
+*/
+
+// Config of the http.Server, the durations are described in net/http.
+type Config struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// ShutdownDelay is the wait between failing readiness and closing the listener
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is the deadline to drain the requests in flight
+	ShutdownTimeout time.Duration
+	LivenessPath    string
+	ReadinessPath   string
+}
+
+// DefaultConfig listens on $HTTP_ADDR, or :$PORT, or addr.
+func DefaultConfig(addr string) Config {
+	if env := os.Getenv("HTTP_ADDR"); env != "" {
+		addr = env
+	} else if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	return Config{
+		Addr:              addr,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxHeaderBytes:    64 << 10,
+		ShutdownTimeout:   15 * time.Second,
+		LivenessPath:      "/healthz",
+		ReadinessPath:     "/readyz",
+	}
+}
+
+// RegisterFlags lets the flags of fs override c, the current values are the defaults.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "listen address, also $HTTP_ADDR or $PORT")
+	fs.DurationVar(&c.ReadHeaderTimeout, "read-header-timeout", c.ReadHeaderTimeout, "max time to read the request header")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "max time to read the whole request")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "max time from the end of the request header to the end of the response")
+	fs.DurationVar(&c.IdleTimeout, "idle-timeout", c.IdleTimeout, "max time a keep-alive connection waits for the next request")
+	fs.IntVar(&c.MaxHeaderBytes, "max-header-bytes", c.MaxHeaderBytes, "max size of the request header")
+	fs.DurationVar(&c.ShutdownDelay, "shutdown-delay", c.ShutdownDelay, "wait after failing readiness before shutting down")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "deadline to drain the requests in flight")
+}
+
+// NewServer returns the http.Server of c, the health endpoints are served before handler.
+func NewServer(c Config, handler http.Handler, health *Health) *http.Server {
+	if health != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/", handler)
+		if c.LivenessPath != "" {
+			mux.Handle(c.LivenessPath, health.LivenessHandler())
+		}
+		if c.ReadinessPath != "" {
+			mux.Handle(c.ReadinessPath, health.ReadinessHandler())
+		}
+		handler = mux
+	}
+	return &http.Server{
+		Addr:              c.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+}
+
+// Run listens on c.Addr and serves until ctx is done or SIGINT/SIGTERM arrives,
+// it returns nil after a graceful shutdown.
+//
+// Once the shutdown starts the signals aren't caught anymore, a second SIGINT/SIGTERM
+// during ShutdownDelay or the draining kills the process.
+func Run(ctx context.Context, c Config, handler http.Handler, health *Health) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	l, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("listening on %s", l.Addr())
+	return Serve(ctx, l, c, handler, health)
+}
+
+// Serve serves on l until ctx is done, then shuts down gracefully.
+func Serve(ctx context.Context, l net.Listener, c Config, handler http.Handler, health *Health) error {
+	if health == nil {
+		health = NewHealth()
+	}
+	srv := NewServer(c, handler, health)
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(l)
+	}()
+	health.SetReady(true)
+
+	select {
+	case err := <-served:
+		// the listener failed before any shutdown
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, draining for at most %v", c.ShutdownTimeout)
+	health.SetReady(false)
+	if c.ShutdownDelay > 0 {
+		time.Sleep(c.ShutdownDelay)
+	}
+	drainCtx := context.Background()
+	if c.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithTimeout(drainCtx, c.ShutdownTimeout)
+		defer cancel()
+	}
+	err := srv.Shutdown(drainCtx)
+	if err != nil {
+		srv.Close()
+		err = fmt.Errorf("shutdown: requests were still in flight after %v: %w", c.ShutdownTimeout, err)
+	}
+	if serveErr := <-served; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	return err
+}
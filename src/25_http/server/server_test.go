@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testConfig() Config {
+	c := DefaultConfig("127.0.0.1:0")
+	c.Addr = "127.0.0.1:0"
+	return c
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestDefaultConfigEnv(t *testing.T) {
+	t.Setenv("HTTP_ADDR", "")
+	t.Setenv("PORT", "9000")
+	if c := DefaultConfig(":8080"); c.Addr != ":9000" {
+		t.Errorf("expected $PORT, got %q", c.Addr)
+	}
+	t.Setenv("HTTP_ADDR", "127.0.0.1:7000")
+	c := DefaultConfig(":8080")
+	if c.Addr != "127.0.0.1:7000" {
+		t.Errorf("expected $HTTP_ADDR, got %q", c.Addr)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-addr", ":6000", "-write-timeout", "1m", "-max-header-bytes", "1024"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != ":6000" || c.WriteTimeout != time.Minute || c.MaxHeaderBytes != 1024 || c.ReadTimeout != 10*time.Second {
+		t.Errorf("unexpected %+v", c)
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	c := testConfig()
+	srv := NewServer(c, http.NotFoundHandler(), nil)
+	if srv.ReadHeaderTimeout == 0 || srv.ReadTimeout == 0 || srv.WriteTimeout == 0 || srv.IdleTimeout == 0 || srv.MaxHeaderBytes == 0 {
+		t.Errorf("every limit should be set, got %+v", srv)
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(entered)
+			<-release
+		}
+		io.WriteString(w, "done")
+	})
+	health := NewHealth()
+	l := listen(t)
+	base := "http://" + l.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, l, testConfig(), handler, health)
+	}()
+
+	if code, _ := get(t, base+"/healthz"); code != http.StatusOK {
+		t.Errorf("liveness: expected 200, got %d", code)
+	}
+	if code, _ := get(t, base+"/readyz"); code != http.StatusOK {
+		t.Errorf("readiness: expected 200, got %d", code)
+	}
+	if code, body := get(t, base+"/anything"); code != http.StatusOK || body != "done" {
+		t.Errorf("unexpected %d %q", code, body)
+	}
+
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		slow <- string(b)
+	}()
+	<-entered
+	cancel()
+
+	// readiness fails as soon as the shutdown starts
+	deadline := time.Now().Add(time.Second)
+	for health.Ready() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if health.Ready() {
+		t.Error("readiness should fail while shutting down")
+	}
+
+	close(release)
+	if body := <-slow; body != "done" {
+		t.Errorf("the request in flight should complete, got %q", body)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("expected a graceful shutdown, got %v", err)
+	}
+	if _, err := http.Get(base + "/anything"); err == nil {
+		t.Error("the listener should be closed")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+	l := listen(t)
+	c := testConfig()
+	c.ShutdownTimeout = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, l, c, handler, nil)
+	}()
+	go http.Get("http://" + l.Addr().String() + "/")
+	<-entered
+	cancel()
+
+	select {
+	case err := <-served:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the drain deadline was not respected")
+	}
+}
+
+func TestReadinessChecks(t *testing.T) {
+	health := NewHealth()
+	health.SetReady(true)
+	var failing int32
+	health.AddCheck("store", func(ctx context.Context) error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+	l := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Serve(ctx, l, testConfig(), http.NotFoundHandler(), health)
+	url := "http://" + l.Addr().String() + "/readyz"
+
+	if code, body := get(t, url); code != http.StatusOK || !strings.Contains(body, `"store":"ok"`) {
+		t.Errorf("unexpected %d %s", code, body)
+	}
+	atomic.StoreInt32(&failing, 1)
+	if code, body := get(t, url); code != http.StatusServiceUnavailable || !strings.Contains(body, "disk full") {
+		t.Errorf("unexpected %d %s", code, body)
+	}
+}
+
+func TestMaxHeaderBytes(t *testing.T) {
+	l := listen(t)
+	c := testConfig()
+	c.MaxHeaderBytes = 1024
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Serve(ctx, l, c, http.NotFoundHandler(), nil)
+
+	req, _ := http.NewRequest("GET", "http://"+l.Addr().String()+"/", nil)
+	// net/http allows 4KB more than MaxHeaderBytes
+	req.Header.Set("X-Big", strings.Repeat("x", 8<<10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("expected 431, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,197 @@
+package bench
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleOutput = `goos: linux
+goarch: amd64
+pkg: go_learning/src/05_string/transfer
+cpu: Intel(R) Core(TM) i7-8700 CPU @ 3.20GHz
+BenchmarkStringBuilder10000-8   	   19872	     60335 ns/op	  128552 B/op	      26 allocs/op
+BenchmarkStringBuilder10000-8   	   20001	     61002 ns/op	  128552 B/op	      26 allocs/op
+PASS
+ok  	go_learning/src/05_string/transfer	3.512s
+pkg: go_learning/src/19_unit_test/benchmark
+BenchmarkConcatStringByAdd  	 4385326	       260.4 ns/op
+--- BENCH: BenchmarkConcatStringByAdd
+    benchmark_test.go:20: a log line
+BenchmarkSort/size=10-12	 1000000	      1052 ns/op	   12.50 MB/s
+BenchmarkBroken-8	abc	 1 ns/op
+PASS
+`
+
+func TestParse(t *testing.T) {
+	out, err := Parse(strings.NewReader(sampleOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantConfig := map[string]string{"goos": "linux", "goarch": "amd64", "cpu": "Intel(R) Core(TM) i7-8700 CPU @ 3.20GHz"}
+	if !reflect.DeepEqual(out.Config, wantConfig) {
+		t.Errorf("Config = %v, want %v", out.Config, wantConfig)
+	}
+	if len(out.Results) != 4 {
+		t.Fatalf("got %d results, want 4: %+v", len(out.Results), out.Results)
+	}
+	want := Result{
+		Package: "go_learning/src/05_string/transfer", Name: "StringBuilder10000", Procs: 8, Iterations: 19872,
+		Metrics: []Metric{{60335, "ns/op"}, {128552, "B/op"}, {26, "allocs/op"}},
+	}
+	if !reflect.DeepEqual(out.Results[0], want) {
+		t.Errorf("Results[0] = %+v, want %+v", out.Results[0], want)
+	}
+	concat := out.Results[2]
+	if concat.Package != "go_learning/src/19_unit_test/benchmark" || concat.Name != "ConcatStringByAdd" || concat.Procs != 1 {
+		t.Errorf("Results[2] = %+v", concat)
+	}
+	sub := out.Results[3]
+	if sub.Name != "Sort/size=10" || sub.Procs != 12 || sub.Metrics[1] != (Metric{12.5, "MB/s"}) {
+		t.Errorf("Results[3] = %+v", sub)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{10, 12, 11, 13, 9, 100})
+	if s.N != 5 || s.Outliers != 1 {
+		t.Fatalf("N = %d, Outliers = %d, want 5, 1", s.N, s.Outliers)
+	}
+	if s.Mean != 11 || s.Min != 9 || s.Max != 13 {
+		t.Errorf("Mean = %v, Min = %v, Max = %v", s.Mean, s.Min, s.Max)
+	}
+	if math.Abs(s.StdDev-math.Sqrt(2.5)) > 1e-9 {
+		t.Errorf("StdDev = %v, want %v", s.StdDev, math.Sqrt(2.5))
+	}
+	if cv := s.CV(); math.Abs(cv-math.Sqrt(2.5)*100/11) > 1e-9 {
+		t.Errorf("CV = %v", cv)
+	}
+	// too few values to find the outliers
+	if s := Summarize([]float64{1, 2, 100}); s.N != 3 || s.Outliers != 0 {
+		t.Errorf("N = %d, Outliers = %d, want 3, 0", s.N, s.Outliers)
+	}
+	if s := Summarize(nil); s.N != 0 || s.Mean != 0 {
+		t.Errorf("empty Summary = %+v", s)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		// only 2 of the C(10,5) = 252 orders are as separated
+		{"separated", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{"reversed", []float64{6, 7, 8, 9, 10}, []float64{1, 2, 3, 4, 5}, 2.0 / 252},
+		{"interleaved", []float64{1, 3, 5, 7, 9}, []float64{2, 4, 6, 8, 10}, 0.6905},
+		{"same", []float64{5, 5, 5}, []float64{5, 5, 5}, 1},
+		{"empty", nil, []float64{1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MannWhitneyU(tt.x, tt.y); math.Abs(got-tt.want) > 1e-4 {
+				t.Errorf("MannWhitneyU = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// the normal approximation with ties
+	x := []float64{1, 2, 2, 3, 3, 3, 4, 4, 5, 5}
+	y := []float64{6, 6, 7, 7, 7, 8, 8, 9, 9, 10}
+	if p := MannWhitneyU(x, y); p > 0.001 {
+		t.Errorf("p = %v of separated samples with ties, want < 0.001", p)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	k := func(name string) Key { return Key{Name: name, Unit: "ns/op"} }
+	before, after := NewSamples(), NewSamples()
+	before.Add(k("Fast"), 100, 101, 102, 99, 98)
+	after.Add(k("Fast"), 50, 51, 49, 52, 48)
+	before.Add(k("Noisy"), 100, 110, 90, 105, 95)
+	after.Add(k("Noisy"), 102, 108, 93, 99, 97)
+	before.Add(k("Removed"), 1)
+	after.Add(k("Added"), 1)
+
+	comps := Compare(before, after, 0)
+	var names []string
+	for _, c := range comps {
+		names = append(names, c.Name)
+	}
+	if want := []string{"Fast", "Noisy", "Removed", "Added"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+	if c := comps[0]; !c.Significant || math.Abs(c.Delta+50) > 1e-9 {
+		t.Errorf("Fast = %+v, want a significant -50%%", c)
+	}
+	if c := comps[1]; c.Significant {
+		t.Errorf("Noisy is significant, p = %v", c.P)
+	}
+	if c := comps[3]; c.Old.N != 0 || c.New.N != 1 || c.Significant {
+		t.Errorf("Added = %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteComparisons(&buf, comps); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	for _, want := range []string{"old ns/op", "100ns ± 2%", "50.0ns ± 3%", "-50.00%", "(p=0.008 n=5+5)", "~"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("comparison doesn't contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		unit string
+		want string
+	}{
+		{60335, "ns/op", "60.3µs"},
+		{260.4, "ns/op", "260ns"},
+		{2.5e9, "ns/op", "2.50s"},
+		{3.2e12, "ns/op", "3200s"},
+		{128552, "B/op", "126kB"},
+		{512, "B/op", "512B"},
+		{26, "allocs/op", "26.0"},
+		{0, "allocs/op", "0"},
+	}
+	for _, tt := range tests {
+		if got := FormatValue(tt.v, tt.unit); got != tt.want {
+			t.Errorf("FormatValue(%v, %q) = %q, want %q", tt.v, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSummaries(t *testing.T) {
+	out, err := Parse(strings.NewReader(sampleOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSummaries(&buf, SamplesOf(out.Results)); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	for _, want := range []string{"name", "StringBuilder10000", "60.7µs ± 1%", "allocs/op", "MB/s"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("summaries don't contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestConfigArgs(t *testing.T) {
+	c := Config{Packages: []string{"./src/05_string/transfer"}, Bench: "StringBuilder10000", Benchtime: "1000x", Benchmem: true}
+	want := []string{"test", "-run=^$", "-bench=StringBuilder10000", "-count=10", "-benchtime=1000x", "-benchmem", "./src/05_string/transfer"}
+	if got := c.Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Args = %q, want %q", got, want)
+	}
+	want = []string{"test", "-run=^$", "-bench=.", "-count=3"}
+	if got := (Config{Count: 3}).Args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Args = %q, want %q", got, want)
+	}
+}
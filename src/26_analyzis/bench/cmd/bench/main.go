@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"go_learning/src/26_analyzis/bench"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const usage = `usage:
+  bench run [flags] [packages]       run the benchmarks N times, summarize them and append them to the history
+  bench compare [flags] old new      compare two runs, a file of go test -bench output or @commit / @label of the history
+  bench history [flags]              show how the benchmarks changed over the commits
+
+$ go run ./src/26_analyzis/bench/cmd/bench run -bench 'StringBuilder10000|StringPlus10000' -count 10 -label before ./src/05_string/transfer
+$ go run ./src/26_analyzis/bench/cmd/bench run -bench ConcatStringByAdd -compare before ./src/19_unit_test/benchmark
+$ go run ./src/26_analyzis/bench/cmd/bench compare old.txt new.txt
+$ go run ./src/26_analyzis/bench/cmd/bench compare @before @a1b2c3d
+$ go run ./src/26_analyzis/bench/cmd/bench history -bench StringBuilder10000 -unit ns/op
+$ go run ./src/26_analyzis/bench/cmd/bench history -format csv > trend.csv
+`
+
+const defaultHistory = "bench_history.jsonl"
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var err error
+	switch os.Args[1] {
+	case "run":
+		err = run(ctx, os.Args[2:])
+	case "compare":
+		err = compare(os.Args[2:])
+	case "history":
+		err = history(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	var c bench.Config
+	fs.StringVar(&c.Bench, "bench", ".", "regular expression of the benchmarks")
+	fs.IntVar(&c.Count, "count", 10, "how many times each benchmark runs")
+	fs.StringVar(&c.Benchtime, "benchtime", "", "go test -benchtime, like 1s or 1000x")
+	fs.BoolVar(&c.Benchmem, "benchmem", true, "report B/op and allocs/op")
+	label := fs.String("label", "", "label of the run in the history, like before or after")
+	historyPath := fs.String("history", defaultHistory, "history file, empty doesn't record the run")
+	output := fs.String("o", "", "also write the raw go test output to this file")
+	compareRef := fs.String("compare", "", "compare with the latest run of this commit or label of the history")
+	alpha := fs.Float64("alpha", bench.DefaultAlpha, "significance level of -compare")
+	verbose := fs.Bool("v", false, "show the go test output as it runs")
+	fs.Parse(args)
+	c.Packages = fs.Args()
+	if *verbose {
+		c.Output = os.Stderr
+	}
+
+	start := time.Now()
+	out, raw, err := bench.Run(ctx, c)
+	if *output != "" {
+		if werr := ioutil.WriteFile(*output, raw, 0o644); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	if err != nil {
+		if !*verbose {
+			os.Stderr.Write(raw)
+		}
+		return err
+	}
+	if len(out.Results) == 0 {
+		return fmt.Errorf("no benchmark matches %q", c.Bench)
+	}
+	samples := bench.SamplesOf(out.Results)
+	if err := bench.WriteSummaries(os.Stdout, samples); err != nil {
+		return err
+	}
+
+	var records []bench.Record
+	if *historyPath != "" || *compareRef != "" {
+		records, err = readHistory(*historyPath)
+		if err != nil {
+			return err
+		}
+	}
+	if *compareRef != "" {
+		before, err := bench.HistorySamples(records, *compareRef)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\ncompared with %s:\n", *compareRef)
+		if err := bench.WriteComparisons(os.Stdout, bench.Compare(before, samples, *alpha)); err != nil {
+			return err
+		}
+	}
+	if *historyPath != "" {
+		run := bench.Record{Time: start.UTC().Truncate(time.Second), Commit: gitCommit(), Label: *label, GoVersion: runtime.Version()}
+		added := bench.NewRecords(out, run)
+		if err := bench.AppendHistory(*historyPath, added); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "\n%d records of commit %q appended to %s\n", len(added), run.Commit, *historyPath)
+	}
+	return nil
+}
+
+func compare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	historyPath := fs.String("history", defaultHistory, "history file of the @refs")
+	alpha := fs.Float64("alpha", bench.DefaultAlpha, "significance level, a p-value below it is a real difference")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("compare needs two runs, got %d\n%s", fs.NArg(), usage)
+	}
+	var runs [2]*bench.Samples
+	var records []bench.Record
+	for i, arg := range fs.Args() {
+		var err error
+		if strings.HasPrefix(arg, "@") {
+			if records == nil {
+				if records, err = readHistory(*historyPath); err != nil {
+					return err
+				}
+			}
+			runs[i], err = bench.HistorySamples(records, arg[1:])
+		} else {
+			runs[i], err = readOutput(arg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bench.WriteComparisons(os.Stdout, bench.Compare(runs[0], runs[1], *alpha))
+}
+
+func history(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	historyPath := fs.String("history", defaultHistory, "history file")
+	pattern := fs.String("bench", ".", "regular expression of the benchmark names")
+	unit := fs.String("unit", "", "only this unit, like ns/op, B/op or allocs/op")
+	format := fs.String("format", "text", "text or csv")
+	fs.Parse(args)
+	re, err := regexp.Compile(*pattern)
+	if err != nil {
+		return err
+	}
+	records, err := readHistory(*historyPath)
+	if err != nil {
+		return err
+	}
+	var selected []bench.Record
+	for _, r := range records {
+		if re.MatchString(r.Name) && (*unit == "" || r.Unit == *unit) {
+			selected = append(selected, r)
+		}
+	}
+	switch *format {
+	case "text":
+		return bench.WriteTrend(os.Stdout, selected)
+	case "csv":
+		return bench.WriteCSV(os.Stdout, selected)
+	}
+	return fmt.Errorf("unknown -format %q, should be text or csv", *format)
+}
+
+// readHistory reads the history file, a missing file is an empty history.
+func readHistory(path string) ([]bench.Record, error) {
+	records, err := bench.ReadHistory(path)
+	if os.IsNotExist(err) {
+		return []bench.Record{}, nil
+	}
+	return records, err
+}
+
+func readOutput(path string) (*bench.Samples, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out, err := bench.Parse(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Results) == 0 {
+		return nil, fmt.Errorf("%s has no benchmark result", path)
+	}
+	return bench.SamplesOf(out.Results), nil
+}
+
+// gitCommit is the short hash of HEAD, -dirty when there are uncommitted changes, empty out of a git repository.
+func gitCommit() string {
+	commit, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	hash := string(bytes.TrimSpace(commit))
+	status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+	if err == nil && len(bytes.TrimSpace(status)) > 0 {
+		hash += "-dirty"
+	}
+	return hash
+}
@@ -0,0 +1,205 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"text/tabwriter"
+)
+
+// DefaultAlpha is the significance level, a p-value below it is a real difference.
+const DefaultAlpha = 0.05
+
+// Key is a metric of a benchmark.
+type Key struct {
+	Package string
+	Name    string
+	Unit    string
+}
+
+// Samples are the values of each key, e.g. the ns/op of the -count runs of a benchmark.
+type Samples struct {
+	// Keys in the order they were first added
+	Keys   []Key
+	Values map[Key][]float64
+}
+
+func NewSamples() *Samples {
+	return &Samples{Values: map[Key][]float64{}}
+}
+
+func (s *Samples) Add(k Key, values ...float64) {
+	if _, ok := s.Values[k]; !ok {
+		s.Keys = append(s.Keys, k)
+	}
+	s.Values[k] = append(s.Values[k], values...)
+}
+
+// SamplesOf groups the metrics of the results.
+func SamplesOf(results []Result) *Samples {
+	s := NewSamples()
+	for _, r := range results {
+		for _, m := range r.Metrics {
+			s.Add(Key{Package: r.Package, Name: r.Name, Unit: m.Unit}, m.Value)
+		}
+	}
+	return s
+}
+
+// Comparison of a key between an old and a new run, a key missing in a run has a zero Summary.
+type Comparison struct {
+	Key
+	Old Summary
+	New Summary
+	// Delta of the means in percent of the old mean
+	Delta float64
+	// P is the p-value of the Mann-Whitney U test
+	P           float64
+	Significant bool
+}
+
+// Compare compares the keys of before and after, in the order of before then the keys only in after.
+func Compare(before, after *Samples, alpha float64) []Comparison {
+	if alpha <= 0 {
+		alpha = DefaultAlpha
+	}
+	keys := append([]Key(nil), before.Keys...)
+	for _, k := range after.Keys {
+		if _, ok := before.Values[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	var comparisons []Comparison
+	for _, k := range keys {
+		c := Comparison{Key: k, Old: Summarize(before.Values[k]), New: Summarize(after.Values[k]), P: 1}
+		if c.Old.N > 0 && c.New.N > 0 {
+			if c.Old.Mean != 0 {
+				c.Delta = (c.New.Mean - c.Old.Mean) * 100 / math.Abs(c.Old.Mean)
+			}
+			c.P = MannWhitneyU(c.Old.Values, c.New.Values)
+			c.Significant = c.P < alpha && c.Old.Mean != c.New.Mean
+		}
+		comparisons = append(comparisons, c)
+	}
+	return comparisons
+}
+
+// FormatValue formats v of the unit with 3 significant digits, the time and the bytes scaled like 52.3µs or 1.50MB.
+func FormatValue(v float64, unit string) string {
+	scaled, suffix := v, ""
+	switch unit {
+	case "ns/op":
+		for _, s := range []string{"ns", "µs", "ms", "s"} {
+			suffix = s
+			if math.Abs(scaled) < 1000 || s == "s" {
+				break
+			}
+			scaled /= 1000
+		}
+	case "B/op":
+		for _, s := range []string{"B", "kB", "MB", "GB"} {
+			suffix = s
+			if math.Abs(scaled) < 1024 || s == "GB" {
+				break
+			}
+			scaled /= 1024
+		}
+	}
+	return significant(scaled, 3) + suffix
+}
+
+func significant(v float64, digits int) string {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	decimals := digits - 1 - int(math.Floor(math.Log10(math.Abs(v))))
+	if decimals < 0 {
+		decimals = 0
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+func formatSummary(s Summary, unit string) string {
+	if s.N == 0 {
+		return "-"
+	}
+	if s.N == 1 {
+		return FormatValue(s.Mean, unit)
+	}
+	return fmt.Sprintf("%s ± %.0f%%", FormatValue(s.Mean, unit), s.CV())
+}
+
+// groupByUnit returns the distinct units in the order they first appear, and the indexes of each unit
+func groupByUnit(units []string) ([]string, map[string][]int) {
+	var order []string
+	indexes := map[string][]int{}
+	for i, unit := range units {
+		if _, ok := indexes[unit]; !ok {
+			order = append(order, unit)
+		}
+		indexes[unit] = append(indexes[unit], i)
+	}
+	return order, indexes
+}
+
+// WriteComparisons writes a table per unit like benchstat:
+//
+//	name                old ns/op      new ns/op      delta
+//	StringBuilder10000  60.3µs ± 2%    21.4µs ± 1%    -64.51%  (p=0.000 n=10+10)
+//	ConcatStringByAdd   260ns ± 12%    255ns ± 9%     ~        (p=0.684 n=10+9)
+//
+// ± is the standard deviation in percent of the mean, ~ is no significant difference.
+func WriteComparisons(w io.Writer, comparisons []Comparison) error {
+	all := make([]string, len(comparisons))
+	for i, c := range comparisons {
+		all[i] = c.Unit
+	}
+	units, keysOf := groupByUnit(all)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, unit := range units {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "name\told %s\tnew %s\tdelta\t\n", unit, unit)
+		for _, j := range keysOf[unit] {
+			c := comparisons[j]
+			delta := "~"
+			if c.Significant {
+				delta = fmt.Sprintf("%+.2f%%", c.Delta)
+			}
+			stats := fmt.Sprintf("(p=%.3f n=%d+%d)", c.P, c.Old.N, c.New.N)
+			if c.Old.N == 0 || c.New.N == 0 {
+				delta, stats = "", ""
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Name, formatSummary(c.Old, unit), formatSummary(c.New, unit), delta, stats)
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteSummaries writes the mean ± standard deviation of each key, a table per unit.
+func WriteSummaries(w io.Writer, s *Samples) error {
+	all := make([]string, len(s.Keys))
+	for i, k := range s.Keys {
+		all[i] = k.Unit
+	}
+	units, keysOf := groupByUnit(all)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, unit := range units {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "name\t%s\tstddev\tn\t\n", unit)
+		for _, j := range keysOf[unit] {
+			k := s.Keys[j]
+			sum := Summarize(s.Values[k])
+			n := strconv.Itoa(sum.N)
+			if sum.Outliers > 0 {
+				n += fmt.Sprintf(" (%d outliers)", sum.Outliers)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", k.Name, formatSummary(sum, unit), FormatValue(sum.StdDev, unit), n)
+		}
+	}
+	return tw.Flush()
+}
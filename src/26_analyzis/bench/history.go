@@ -0,0 +1,185 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+/*
+	每次 run 的結果附加到 history 檔案（JSON Lines，一行一個 benchmark 的一個單位），
+	記下 commit 與 label，之後可以看某個 benchmark 隨著 commit 的變化，或匯出 CSV 畫圖：
+		{"time":"2022-06-25T10:00:00Z","commit":"a1b2c3d","label":"before","name":"StringBuilder10000","unit":"ns/op","values":[60335,...],"n":10,"mean":60335,"stddev":1204}
+	原始的每次的值也存下來，兩個 commit 之間一樣可以做顯著性檢定
+*/
+
+var RefNotFoundError = errors.New("no benchmark run of the ref in the history")
+
+// Record is the values of a metric of a benchmark in a run.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Commit    string    `json:"commit,omitempty"`
+	Label     string    `json:"label,omitempty"`
+	GoVersion string    `json:"go_version,omitempty"`
+	GOOS      string    `json:"goos,omitempty"`
+	GOARCH    string    `json:"goarch,omitempty"`
+	CPU       string    `json:"cpu,omitempty"`
+	Package   string    `json:"package,omitempty"`
+	Name      string    `json:"name"`
+	Unit      string    `json:"unit"`
+	Values    []float64 `json:"values"`
+	// N, Mean and StdDev are after removing the outliers
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+}
+
+func (r Record) key() Key {
+	return Key{Package: r.Package, Name: r.Name, Unit: r.Unit}
+}
+
+// NewRecords are the records of the results of out, with the time, commit and label of run.
+// The goos, goarch and cpu are taken from out when run doesn't have them.
+func NewRecords(out *Output, run Record) []Record {
+	if run.GOOS == "" {
+		run.GOOS = out.Config["goos"]
+	}
+	if run.GOARCH == "" {
+		run.GOARCH = out.Config["goarch"]
+	}
+	if run.CPU == "" {
+		run.CPU = out.Config["cpu"]
+	}
+	samples := SamplesOf(out.Results)
+	records := make([]Record, 0, len(samples.Keys))
+	for _, k := range samples.Keys {
+		r := run
+		r.Package, r.Name, r.Unit = k.Package, k.Name, k.Unit
+		r.Values = samples.Values[k]
+		s := Summarize(r.Values)
+		r.N, r.Mean, r.StdDev = s.N, s.Mean, s.StdDev
+		records = append(records, r)
+	}
+	return records
+}
+
+// AppendHistory appends the records to the history file at path, it's created when missing.
+func AppendHistory(path string, records []Record) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadHistory reads the records of the history file at path, oldest first.
+func ReadHistory(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+// HistorySamples are the values of the latest run of ref, a commit or a label, to Compare with another run.
+func HistorySamples(records []Record, ref string) (*Samples, error) {
+	var latest time.Time
+	for _, r := range records {
+		if (r.Commit == ref || r.Label == ref) && r.Time.After(latest) {
+			latest = r.Time
+		}
+	}
+	if latest.IsZero() {
+		return nil, fmt.Errorf("%w: %q", RefNotFoundError, ref)
+	}
+	s := NewSamples()
+	for _, r := range records {
+		if (r.Commit == ref || r.Label == ref) && r.Time.Equal(latest) {
+			s.Add(r.key(), r.Values...)
+		}
+	}
+	return s, nil
+}
+
+// WriteTrend writes the records a table per benchmark and unit, the delta is from the previous run.
+func WriteTrend(w io.Writer, records []Record) error {
+	var keys []Key
+	byKey := map[Key][]Record{}
+	for _, r := range records {
+		k := r.key()
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], r)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, k := range keys {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s %s\n", k.Name, k.Unit)
+		fmt.Fprintln(tw, "time\tcommit\tlabel\tmean\tstddev\tn\tdelta\t")
+		runs := byKey[k]
+		for j, r := range runs {
+			delta := ""
+			if j > 0 && runs[j-1].Mean != 0 {
+				delta = fmt.Sprintf("%+.2f%%", (r.Mean-runs[j-1].Mean)*100/runs[j-1].Mean)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t\n", r.Time.Format("2006-01-02 15:04"), r.Commit, r.Label,
+				FormatValue(r.Mean, r.Unit), FormatValue(r.StdDev, r.Unit), r.N, delta)
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the records as CSV with a header, to chart them in a spreadsheet.
+func WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "commit", "label", "package", "name", "unit", "n", "mean", "stddev"})
+	for _, r := range records {
+		cw.Write([]string{
+			r.Time.UTC().Format(time.RFC3339), r.Commit, r.Label, r.Package, r.Name, r.Unit, strconv.Itoa(r.N),
+			strconv.FormatFloat(r.Mean, 'g', -1, 64), strconv.FormatFloat(r.StdDev, 'g', -1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,111 @@
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	day := time.Date(2022, 6, 25, 10, 0, 0, 0, time.UTC)
+	runs := []struct {
+		run    Record
+		output string
+	}{
+		{Record{Time: day, Commit: "a1b2c3d", Label: "before"}, `goos: linux
+pkg: go_learning/src/05_string/transfer
+BenchmarkStringBuilder10000-8   	19872	60000 ns/op	128552 B/op
+BenchmarkStringBuilder10000-8   	19872	61000 ns/op	128552 B/op
+BenchmarkStringBuilder10000-8   	19872	59000 ns/op	128552 B/op
+`},
+		{Record{Time: day.Add(time.Hour), Commit: "e4f5a6b", Label: "after"}, `goos: linux
+pkg: go_learning/src/05_string/transfer
+BenchmarkStringBuilder10000-8   	39872	30000 ns/op	64000 B/op
+BenchmarkStringBuilder10000-8   	39872	31000 ns/op	64000 B/op
+BenchmarkStringBuilder10000-8   	39872	29000 ns/op	64000 B/op
+`},
+	}
+	// appended in the reverse order, ReadHistory sorts them by time
+	for i := len(runs) - 1; i >= 0; i-- {
+		out, err := Parse(strings.NewReader(runs[i].output))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records := NewRecords(out, runs[i].run)
+		if len(records) != 2 || records[0].GOOS != "linux" || records[0].N != 3 {
+			t.Fatalf("records = %+v", records)
+		}
+		if err := AppendHistory(path, records); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := ReadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[0].Commit != "a1b2c3d" || records[3].Commit != "e4f5a6b" {
+		t.Fatalf("records = %+v", records)
+	}
+	if r := records[0]; r.Unit != "ns/op" || r.Mean != 60000 || r.Package != "go_learning/src/05_string/transfer" {
+		t.Errorf("records[0] = %+v", r)
+	}
+
+	before, err := HistorySamples(records, "before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := HistorySamples(records, "e4f5a6b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comps := Compare(before, after, DefaultAlpha)
+	if len(comps) != 2 || comps[0].Delta != -50 || comps[1].Unit != "B/op" {
+		t.Errorf("comparisons = %+v", comps)
+	}
+	if _, err := HistorySamples(records, "missing"); !errors.Is(err, RefNotFoundError) {
+		t.Errorf("err = %v, want RefNotFoundError", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTrend(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	for _, want := range []string{"StringBuilder10000 ns/op", "2022-06-25 11:00", "e4f5a6b", "30.0µs", "-50.00%"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("trend doesn't contain %q:\n%s", want, text)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteCSV(&buf, records[:1]); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,commit,label,package,name,unit,n,mean,stddev\n" +
+		"2022-06-25T10:00:00Z,a1b2c3d,before,go_learning/src/05_string/transfer,StringBuilder10000,ns/op,3,60000,1000\n"
+	if buf.String() != want {
+		t.Errorf("csv = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestReadHistoryError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := AppendHistory(path, []Record{{Name: "A", Unit: "ns/op"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{\"name\":\"A\"}\n\n{broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadHistory(path); err == nil || !strings.Contains(err.Error(), ":3:") {
+		t.Errorf("err = %v, want the line 3", err)
+	}
+}
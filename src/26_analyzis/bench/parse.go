@@ -0,0 +1,107 @@
+package bench
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+	go test -bench 的輸出一行一個結果：
+		BenchmarkStringBuilder10000-8   	   19872	     60335 ns/op	  128552 B/op	      26 allocs/op
+	* 名字後面的 -8 是 GOMAXPROCS，GOMAXPROCS 是 1 時沒有
+	* 接著是跑了幾次（b.N），後面是一組一組的 值 單位：ns/op、B/op（-benchmem）、MB/s（b.SetBytes）、
+	  b.ReportMetric 自訂的單位
+	* 前面的 goos:、goarch:、pkg:、cpu: 是設定，pkg: 之後的結果屬於那個 package
+	其他的行（PASS、ok、t.Log 的輸出）都略過，-count 跑幾次就有幾行同名的結果。
+*/
+
+// Metric is a value of a result, like 60335 ns/op.
+type Metric struct {
+	Value float64
+	Unit  string
+}
+
+type Result struct {
+	Package string
+	// Name without the Benchmark prefix and the -procs suffix, like StringBuilder10000 or Sort/size=10
+	Name       string
+	Procs      int
+	Iterations int
+	Metrics    []Metric
+}
+
+// Output is the results of a go test -bench run.
+type Output struct {
+	// Config is the goos, goarch, cpu lines
+	Config  map[string]string
+	Results []Result
+}
+
+// Parse reads the output of go test -bench, the lines which aren't results are skipped.
+func Parse(r io.Reader) (*Output, error) {
+	out := &Output{Config: map[string]string{}}
+	pkg := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if key, value, ok := configLine(line); ok {
+			if key == "pkg" {
+				pkg = value
+			} else if _, seen := out.Config[key]; !seen {
+				out.Config[key] = value
+			}
+			continue
+		}
+		if res, ok := parseResult(line); ok {
+			res.Package = pkg
+			out.Results = append(out.Results, res)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// configLine is like goos: linux, the key is lower case without spaces
+func configLine(line string) (string, string, bool) {
+	i := strings.Index(line, ": ")
+	if i <= 0 {
+		return "", "", false
+	}
+	key := line[:i]
+	for _, c := range key {
+		if (c < 'a' || c > 'z') && c != '_' {
+			return "", "", false
+		}
+	}
+	return key, strings.TrimSpace(line[i+2:]), true
+}
+
+func parseResult(line string) (Result, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return Result{}, false
+	}
+	res := Result{Name: strings.TrimPrefix(fields[0], "Benchmark"), Procs: 1}
+	if i := strings.LastIndexByte(res.Name, '-'); i > 0 {
+		if procs, err := strconv.Atoi(res.Name[i+1:]); err == nil {
+			res.Name, res.Procs = res.Name[:i], procs
+		}
+	}
+	if res.Name == "" {
+		return Result{}, false
+	}
+	var err error
+	if res.Iterations, err = strconv.Atoi(fields[1]); err != nil {
+		return Result{}, false
+	}
+	for i := 2; i < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return Result{}, false
+		}
+		res.Metrics = append(res.Metrics, Metric{Value: v, Unit: fields[i+1]})
+	}
+	return res, true
+}
@@ -0,0 +1,66 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+)
+
+// Config of a go test -bench run.
+type Config struct {
+	// Packages like ./src/05_string/transfer, the current package when empty
+	Packages []string
+	// Bench is the -bench regular expression, . by default
+	Bench string
+	// Count is how many times each benchmark runs, 10 by default
+	Count int
+	// Benchtime like 1s or 1000x, the default of go test when empty
+	Benchtime string
+	// Benchmem reports B/op and allocs/op
+	Benchmem bool
+	// Dir the go command runs in, the working directory when empty
+	Dir string
+	// Output receives the output of go test as it runs, may be nil
+	Output io.Writer
+}
+
+// Args are the arguments of the go command, the tests are skipped by -run=^$.
+func (c Config) Args() []string {
+	bench, count := c.Bench, c.Count
+	if bench == "" {
+		bench = "."
+	}
+	if count <= 0 {
+		count = 10
+	}
+	args := []string{"test", "-run=^$", "-bench=" + bench, "-count=" + strconv.Itoa(count)}
+	if c.Benchtime != "" {
+		args = append(args, "-benchtime="+c.Benchtime)
+	}
+	if c.Benchmem {
+		args = append(args, "-benchmem")
+	}
+	return append(args, c.Packages...)
+}
+
+// Run runs the benchmarks and parses their output, the raw output is returned to be saved too.
+// A failed go test returns the results parsed so far with the error.
+func Run(ctx context.Context, c Config) (*Output, []byte, error) {
+	var raw bytes.Buffer
+	w := io.Writer(&raw)
+	if c.Output != nil {
+		w = io.MultiWriter(&raw, c.Output)
+	}
+	cmd := exec.CommandContext(ctx, "go", c.Args()...)
+	cmd.Dir = c.Dir
+	cmd.Stdout, cmd.Stderr = w, w
+	runErr := cmd.Run()
+	out, err := Parse(bytes.NewReader(raw.Bytes()))
+	if runErr != nil {
+		return out, raw.Bytes(), fmt.Errorf("go test: %w", runErr)
+	}
+	return out, raw.Bytes(), err
+}
@@ -0,0 +1,182 @@
+package bench
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+	同一個 benchmark 每次跑的結果都不一樣（CPU 頻率、GC、其他程式），只看一次的數字沒有意義：
+	* 跑 N 次（go test -count N），用平均與標準差描述，先用 Tukey fences 去掉離群值：
+	  小於 Q1 - 1.5×IQR 或大於 Q3 + 1.5×IQR 的值，IQR = Q3 - Q1
+	* 兩組結果是不是真的不同用 Mann-Whitney U test（benchstat 也是用這個），不假設常態分佈，
+	  p 值小於 alpha（預設 0.05）才算顯著，否則兩組的差距可能只是雜訊，報告顯示 ~
+	  樣本少（沒有相同的值、兩組都不超過 50 個）時算精確的分佈，否則用常態近似
+*/
+
+// Summary of the values of a benchmark, after removing the outliers.
+type Summary struct {
+	// N is the number of values kept, Outliers the number removed
+	N        int
+	Outliers int
+	Mean     float64
+	StdDev   float64
+	Min      float64
+	Max      float64
+	// Values kept, sorted
+	Values []float64
+}
+
+// CV is the coefficient of variation, the standard deviation in percent of the mean.
+func (s Summary) CV() float64 {
+	if s.Mean == 0 {
+		return 0
+	}
+	return s.StdDev * 100 / math.Abs(s.Mean)
+}
+
+// Summarize removes the outliers of values and summarizes the others.
+func Summarize(values []float64) Summary {
+	kept := removeOutliers(values)
+	s := Summary{N: len(kept), Outliers: len(values) - len(kept), Values: kept}
+	if len(kept) == 0 {
+		return s
+	}
+	s.Min, s.Max = kept[0], kept[len(kept)-1]
+	for _, v := range kept {
+		s.Mean += v
+	}
+	s.Mean /= float64(len(kept))
+	if len(kept) > 1 {
+		var sum float64
+		for _, v := range kept {
+			sum += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(sum / float64(len(kept)-1))
+	}
+	return s
+}
+
+// quantile of sorted values, linearly interpolated
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// removeOutliers returns the sorted values within the Tukey fences.
+func removeOutliers(values []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if len(sorted) < 4 {
+		return sorted
+	}
+	q1, q3 := quantile(sorted, 0.25), quantile(sorted, 0.75)
+	low, high := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+	kept := sorted[:0:0]
+	for _, v := range sorted {
+		if v >= low && v <= high {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// MannWhitneyU is the two-sided p-value of the Mann-Whitney U test of x and y,
+// the probability to see a difference as large if both were from the same distribution.
+func MannWhitneyU(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	// rank all the values together, the ties get the average of their ranks
+	type value struct {
+		v     float64
+		fromX bool
+	}
+	all := make([]value, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, value{v, true})
+	}
+	for _, v := range y {
+		all = append(all, value{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+	var rankX, tieCorrection float64
+	hasTies := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromX {
+				rankX += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			hasTies = true
+			tieCorrection += t*t*t - t
+		}
+		i = j
+	}
+	u := rankX - float64(n1*(n1+1))/2
+	// the smaller of U1 and U2, the test is symmetric
+	u = math.Min(u, float64(n1*n2)-u)
+
+	if !hasTies && n1 <= 50 && n2 <= 50 {
+		return math.Min(1, 2*exactUCDF(n1, n2, int(u)))
+	}
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieCorrection/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+	// continuity correction
+	z := (u - mean + 0.5) / math.Sqrt(variance)
+	return math.Min(1, 2*normalCDF(z))
+}
+
+// exactUCDF is P(U <= u) when there are no ties: the number of the orders of n1 x and n2 y
+// with u pairs where an x is after a y, over all the orders.
+func exactUCDF(n1, n2, u int) float64 {
+	// f(m, n, k) is the number of the orders of m x and n y with k pairs, the last one is
+	// a y: f(m, n-1, k), or an x after the n y's: f(m-1, n, k-n); prev is f(., n-1, .)
+	maxU := n1 * n2
+	prev, cur := make([][]float64, n1+1), make([][]float64, n1+1)
+	for m := range prev {
+		prev[m], cur[m] = make([]float64, maxU+1), make([]float64, maxU+1)
+		prev[m][0] = 1 // no y: a single order, no pair
+	}
+	for n := 1; n <= n2; n++ {
+		for m := range cur {
+			for k := range cur[m] {
+				cur[m][k] = 0
+				if k <= m*n {
+					cur[m][k] = prev[m][k]
+					if m > 0 && k >= n {
+						cur[m][k] += cur[m-1][k-n]
+					}
+				}
+			}
+		}
+		prev, cur = cur, prev
+	}
+	var below, total float64
+	for k, c := range prev[n1] {
+		total += c
+		if k <= u {
+			below += c
+		}
+	}
+	return below / total
+}
+
+func normalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
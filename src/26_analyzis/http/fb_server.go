@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"go_learning/src/25_http/ratelimit"
+	"go_learning/src/25_http/server"
+	"go_learning/src/26_analyzis/profiling"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
+	"runtime"
+	"time"
 )
 
 func GetFibonacciSeries(n int) []int {
@@ -29,12 +36,59 @@ func CreateFBS(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(fmt.Sprintf("%v", fbs)))
 }
 
+// /fb burns CPU for seconds, so each IP may start one every 5s (2 at once),
+// and at most one per CPU runs at the same time, the others get 429/503 with Retry-After
+var fbPolicy = ratelimit.Policy{
+	Rate:        ratelimit.Rate{Requests: 1, Per: 5 * time.Second, Burst: 2},
+	Key:         ratelimit.ByIP,
+	MaxInFlight: runtime.NumCPU(),
+	RetryAfter:  5 * time.Second,
+}
+
+// startSnapshots takes CPU and heap snapshots in the background until ctx is done,
+// they are listed at /debug/snapshots/ and downloaded at /debug/snapshots/<id>/cpu.prof
+func startSnapshots(ctx context.Context, c profiling.ContinuousConfig) error {
+	p, err := profiling.NewContinuous(c)
+	if err != nil {
+		return err
+	}
+	http.Handle("/debug/snapshots/", http.StripPrefix("/debug/snapshots", p.Handler()))
+	go p.Run(ctx, log.Printf)
+	return nil
+}
+
 func main() {
+	cfg := server.DefaultConfig(":8081")
+	// /debug/pprof/profile records 30s by default and /fb runs for seconds
+	cfg.WriteTimeout = 2 * time.Minute
+	cfg.RegisterFlags(flag.CommandLine)
+	var snapshots profiling.ContinuousConfig
+	flag.StringVar(&snapshots.Dir, "snapshot-dir", "", "directory of the continuous profiling snapshots, empty disables them")
+	flag.DurationVar(&snapshots.Interval, "snapshot-interval", 5*time.Minute, "time between two scheduled snapshots")
+	flag.DurationVar(&snapshots.CPUDuration, "snapshot-cpu", 10*time.Second, "how long the CPU profile of a snapshot records")
+	flag.IntVar(&snapshots.MaxSnapshots, "snapshot-keep", 24, "number of snapshots kept")
+	flag.DurationVar(&snapshots.MaxAge, "snapshot-max-age", 0, "remove the snapshots older than this, 0 keeps them")
+	flag.Uint64Var(&snapshots.HeapThreshold, "heap-threshold", 0, "take a snapshot when the heap grows over these bytes, 0 disables it")
+	flag.IntVar(&snapshots.GoroutineThreshold, "goroutine-threshold", 0, "take a snapshot when there are more goroutines, 0 disables it")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if snapshots.Dir != "" {
+		if err := startSnapshots(ctx, snapshots); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	http.HandleFunc("/", Index)
-	http.HandleFunc("/fb", CreateFBS)
-	log.Fatal(http.ListenAndServe(":8081", nil))
+	http.Handle("/fb", fbPolicy.Middleware()(http.HandlerFunc(CreateFBS)))
+	if err := server.Run(ctx, cfg, http.DefaultServeMux, server.NewHealth()); err != nil {
+		log.Fatal(err)
+	}
 }
 // http://localhost:8081/debug/pprof/
+// $ go run . -snapshot-dir snapshots -snapshot-interval 1m -heap-threshold 268435456 -goroutine-threshold 10000
+// http://localhost:8081/debug/snapshots/
+// $ go tool pprof http://localhost:8081/debug/snapshots/<id>/cpu.prof
 // $ go tool pprof http://localhost:8081/debug/pprof/profile
-// $ go-torch http://localhost:8081/debug/pprof/profile
-// Writing svg to torch.svg
\ No newline at end of file
+// $ curl -s 'http://localhost:8081/debug/pprof/profile?seconds=10' | go run ../profiling/cmd/flamegraph -o torch.svg
\ No newline at end of file
@@ -3,7 +3,6 @@ package optimize
 import (
 	"encoding/json"
 	"strconv"
-	"strings"
 )
 
 func createRequest() string {
@@ -38,21 +37,20 @@ func processRequestOld(reqs []string) []string {
 	return reps
 }
 
-// with easyjson
-func processRequest(reqs []string) []string {
+// decoded like the records of the Processor (validate.go), encoded with the codec generated by jsoncodec (structs_jsoncodec.go)
+func processRequest(reqs []string, naming Naming) ([]string, error) {
 	reps := []string{}
-	for _, req := range reqs {
-		reqObj := &Request{}
-		reqObj.UnmarshalJSON([]byte(req))
+	s := &requestScratch{}
+	for i, req := range reqs {
 		// json.Unmarshal([]byte(req), reqObj)
 		// ret := ""
-		var buf strings.Builder
-		for _, e := range reqObj.PayLoad {
-			// ret += strconv.Itoa(e) + ","
-			buf.WriteString(strconv.Itoa(e))
-			buf.WriteString(",")
+		// for _, e := range reqObj.PayLoad {
+		// 	ret += strconv.Itoa(e) + ","
+		// }
+		if err := s.decode([]byte(req), naming); err != nil {
+			return nil, &RecordError{Line: i + 1, Err: err}
 		}
-		repObj := &Response{reqObj.TransactionID, buf.String()}
+		repObj := &Response{string(s.id), string(s.exp)}
 		repJson, err := repObj.MarshalJSON()
 		// repJson, err := json.Marshal(&repObj)
 		if err != nil {
@@ -60,5 +58,5 @@ func processRequest(reqs []string) []string {
 		}
 		reps = append(reps, string(repJson))
 	}
-	return reps
+	return reps, nil
 }
\ No newline at end of file
@@ -0,0 +1,327 @@
+package optimize
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+/*
+	processRequest 一次拿到全部的 request，每個 request 都配置新的 Response、回傳的 string，
+	再 append 到越來越大的 []string，request 越多配置越多。Processor 改成串流：
+	* 從 io.Reader 一行讀一個 record（NDJSON），回應一行一個寫到 io.Writer，記憶體不隨資料量增加
+	* Handler 把回應 append 到傳入的 []byte，record 與回應的 buffer 都用 sync.Pool 重複使用，
+	  穩定之後每個 record 幾乎沒有配置（AppendResponse 用 jlexer 直接解析，不經過 Request 的 string）
+	* 多個 worker 平行處理，record 分成 batch 依序放進 ordered channel，writer 照順序等每個 batch 完成再寫出，
+	  所以輸出的順序與輸入相同
+		reader ──batch──▶ work ──▶ worker × N ──done──┐
+		   └─────batch──▶ ordered ──▶ writer ◀────────┘
+*/
+
+var RecordTooLongError = errors.New("record longer than MaxRecordSize")
+
+// Handler appends the response of a record to dst, a record is a line without the newline.
+type Handler func(dst, record []byte) ([]byte, error)
+
+// RecordError is the error of the record at Line, the first line is 1.
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+type Config struct {
+	// Workers process the batches in parallel, GOMAXPROCS by default, 1 processes them in the calling goroutine
+	Workers int
+	// BatchSize is the most records a worker processes at a time, 128 by default
+	BatchSize int
+	// MaxRecordSize is the longest line, 1MB by default
+	MaxRecordSize int
+	// ContinueOnError writes an error line like {"line":3,"error":"payload: missing field"} in place of
+	// the response of a record the Handler fails, and goes on with the next records
+	ContinueOnError bool
+}
+
+// batchBytes ends a batch before BatchSize records when the records are long
+const batchBytes = 64 * 1024
+
+type Processor struct {
+	handler Handler
+	c       Config
+	batches sync.Pool
+	buffers sync.Pool
+}
+
+func NewProcessor(handler Handler, c Config) *Processor {
+	if c.Workers <= 0 {
+		c.Workers = runtime.GOMAXPROCS(0)
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 128
+	}
+	if c.MaxRecordSize <= 0 {
+		c.MaxRecordSize = 1024 * 1024
+	}
+	p := &Processor{handler: handler, c: c}
+	p.batches.New = func() interface{} {
+		return &batch{done: make(chan struct{}, 1)}
+	}
+	p.buffers.New = func() interface{} {
+		buf := make([]byte, 64*1024)
+		return &buf
+	}
+	return p
+}
+
+// batch is the records a worker processes at a time, and their responses
+type batch struct {
+	// records concatenated, ends[i] is the end of the record i in in and lines[i] its line
+	in    []byte
+	ends  []int
+	lines []int
+	out   []byte
+	err   error
+	// done receives when the batch is processed
+	done chan struct{}
+}
+
+func (b *batch) add(record []byte, line int) {
+	b.in = append(b.in, record...)
+	b.ends = append(b.ends, len(b.in))
+	b.lines = append(b.lines, line)
+}
+
+func (p *Processor) getBatch() *batch {
+	b := p.batches.Get().(*batch)
+	b.in, b.ends, b.lines, b.out, b.err = b.in[:0], b.ends[:0], b.lines[:0], b.out[:0], nil
+	return b
+}
+
+// run processes the records of b, it stops at the first error and keeps the responses before it.
+func (p *Processor) run(b *batch) {
+	start := 0
+	for i, end := range b.ends {
+		n := len(b.out)
+		out, err := p.handler(b.out, b.in[start:end])
+		if err != nil {
+			if !p.c.ContinueOnError {
+				b.out, b.err = b.out[:n], &RecordError{Line: b.lines[i], Err: err}
+				return
+			}
+			out = appendRecordError(b.out[:n], b.lines[i], err)
+		}
+		b.out = append(out, '\n')
+		start = end
+	}
+}
+
+// Process handles the records of r and writes the responses to w in the same order,
+// it stops at the first error, the responses of the records before it are written.
+// With ContinueOnError only the errors of reading r and writing w stop it.
+func (p *Processor) Process(r io.Reader, w io.Writer) error {
+	if p.c.Workers == 1 {
+		var err error
+		readErr := p.read(r, func(b *batch) bool {
+			p.run(b)
+			if _, err = w.Write(b.out); err == nil {
+				err = b.err
+			}
+			p.batches.Put(b)
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+		return readErr
+	}
+
+	work := make(chan *batch, p.c.Workers)
+	ordered := make(chan *batch, 2*p.c.Workers)
+	for i := 0; i < p.c.Workers; i++ {
+		go func() {
+			for b := range work {
+				p.run(b)
+				b.done <- struct{}{}
+			}
+		}()
+	}
+	var failed int32
+	writeErr := make(chan error, 1)
+	go func() {
+		var err error
+		for b := range ordered {
+			<-b.done
+			// after an error the batches are only waited, a worker may still use them
+			if err == nil {
+				if _, err = w.Write(b.out); err == nil {
+					err = b.err
+				}
+				if err != nil {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+			p.batches.Put(b)
+		}
+		writeErr <- err
+	}()
+	readErr := p.read(r, func(b *batch) bool {
+		ordered <- b
+		work <- b
+		return atomic.LoadInt32(&failed) == 0
+	})
+	close(work)
+	close(ordered)
+	// the error of an earlier record comes first
+	if err := <-writeErr; err != nil {
+		return err
+	}
+	return readErr
+}
+
+// read splits r into batches of records, the empty lines are skipped. emit returns false to stop reading.
+func (p *Processor) read(r io.Reader, emit func(*batch) bool) error {
+	buf := p.buffers.Get().(*[]byte)
+	defer p.buffers.Put(buf)
+	scanner := bufio.NewScanner(r)
+	// the longest token is the larger of the capacity of the buffer and the max
+	if p.c.MaxRecordSize < len(*buf) {
+		scanner.Buffer((*buf)[:0:p.c.MaxRecordSize], p.c.MaxRecordSize)
+	} else {
+		scanner.Buffer(*buf, p.c.MaxRecordSize)
+	}
+	b := p.getBatch()
+	line := 0
+	for scanner.Scan() {
+		line++
+		record := bytes.TrimSpace(scanner.Bytes())
+		if len(record) == 0 {
+			continue
+		}
+		b.add(record, line)
+		if len(b.ends) >= p.c.BatchSize || len(b.in) >= batchBytes {
+			if !emit(b) {
+				return nil
+			}
+			b = p.getBatch()
+		}
+	}
+	if len(b.ends) > 0 {
+		if !emit(b) {
+			return nil
+		}
+	} else {
+		p.batches.Put(b)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = RecordTooLongError
+		}
+		return &RecordError{Line: line + 1, Err: err}
+	}
+	return nil
+}
+
+// requestScratch is the fields of a Request decoded by AppendResponse, reused through scratches
+type requestScratch struct {
+	id  []byte
+	exp []byte
+}
+
+var scratches = sync.Pool{New: func() interface{} { return &requestScratch{} }}
+
+// AppendResponse is the Handler of a Request record with Strict naming, it appends the same Response as processRequest.
+func AppendResponse(dst, record []byte) ([]byte, error) {
+	return appendResponse(dst, record, Strict)
+}
+
+// ResponseHandler is the Handler of a Request record with the naming.
+func ResponseHandler(naming Naming) Handler {
+	return func(dst, record []byte) ([]byte, error) {
+		return appendResponse(dst, record, naming)
+	}
+}
+
+func appendResponse(dst, record []byte, naming Naming) ([]byte, error) {
+	s := scratches.Get().(*requestScratch)
+	defer scratches.Put(s)
+	if err := s.decode(record, naming); err != nil {
+		return dst, err
+	}
+	dst = append(dst, `{"transaction_id":`...)
+	dst = appendString(dst, s.id)
+	dst = append(dst, `,"exp":"`...)
+	dst = append(dst, s.exp...)
+	return append(dst, `"}`...), nil
+}
+
+func appendRecordError(dst []byte, line int, err error) []byte {
+	dst = append(dst, `{"line":`...)
+	dst = strconv.AppendInt(dst, int64(line), 10)
+	dst = append(dst, `,"error":`...)
+	dst = appendString(dst, []byte(err.Error()))
+	return append(dst, '}')
+}
+
+const hex = "0123456789abcdef"
+
+// appendString appends s as a JSON string, escaped like the easyjson writer
+func appendString(dst, s []byte) []byte {
+	dst = append(dst, '"')
+	p := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[p:i]...)
+			switch c {
+			case '\t':
+				dst = append(dst, `\t`...)
+			case '\r':
+				dst = append(dst, `\r`...)
+			case '\n':
+				dst = append(dst, `\n`...)
+			case '\\':
+				dst = append(dst, `\\`...)
+			case '"':
+				dst = append(dst, `\"`...)
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			p = i
+			continue
+		}
+		r, size := utf8.DecodeRune(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[p:i]...)
+			dst = append(dst, `\ufffd`...)
+		} else if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[p:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xf])
+		} else {
+			i += size
+			continue
+		}
+		i += size
+		p = i
+	}
+	dst = append(dst, s[p:]...)
+	return append(dst, '"')
+}
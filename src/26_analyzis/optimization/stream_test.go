@@ -0,0 +1,209 @@
+package optimize
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"go_learning/src/24_json/codegen"
+)
+
+// requests are n Request records with different ids and payloads, and their requests as strings
+func requests(n int) ([]byte, []string) {
+	var buf bytes.Buffer
+	reqs := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		payload := make([]int, 100+i%50)
+		for j := range payload {
+			payload[j] = i*j - 50
+		}
+		v, err := json.Marshal(&Request{fmt.Sprintf("tx-%d", i), payload})
+		if err != nil {
+			panic(err)
+		}
+		reqs = append(reqs, string(v))
+		buf.Write(v)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), reqs
+}
+
+func TestProcessor(t *testing.T) {
+	input, reqs := requests(1000)
+	reps, err := processRequest(reqs, Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join(reps, "\n") + "\n"
+	for _, c := range []Config{{Workers: 1}, {Workers: 4, BatchSize: 7}, {}} {
+		var out bytes.Buffer
+		if err := NewProcessor(AppendResponse, c).Process(bytes.NewReader(input), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("%+v: the responses differ from processRequest", c)
+		}
+	}
+}
+
+func TestAppendResponse(t *testing.T) {
+	tests := []struct {
+		record string
+		want   string
+	}{
+		{`{"transaction_id":"a","payload":[1,-2,3]}`, `{"transaction_id":"a","exp":"1,-2,3,"}`},
+		{` {"payload":[],"transaction_id":"b"} `, `{"transaction_id":"b","exp":""}`},
+		{`{"transaction_id":"<\"é\u2028\n\\>","payload":[]}`, `{"transaction_id":"\u003c\"é\u2028\n\\\u003e","exp":""}`},
+	}
+	for _, tt := range tests {
+		got, err := AppendResponse([]byte("prefix "), []byte(tt.record))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.record, err)
+		}
+		if string(got) != "prefix "+tt.want {
+			t.Errorf("AppendResponse(%s) = %s, want %s", tt.record, got, tt.want)
+		}
+		// the same as the generated Response codec
+		var req Request
+		if err := req.UnmarshalJSON([]byte(tt.record)); err != nil {
+			t.Fatal(err)
+		}
+		rep := Response{TransactionID: req.TransactionID}
+		for _, e := range req.PayLoad {
+			rep.Expression += fmt.Sprintf("%d,", e)
+		}
+		if generated, _ := rep.MarshalJSON(); string(generated) != tt.want {
+			t.Errorf("generated = %s, want %s", generated, tt.want)
+		}
+	}
+}
+
+// the records of the legacy Request are answered in Lenient naming only
+func TestAppendResponseLegacy(t *testing.T) {
+	tests := []struct {
+		record string
+		want   string
+		// the field Strict naming fails
+		strict string
+		err    error
+	}{
+		{`{"translation_id":"a","payload":[1,-2,3]}`, `{"transaction_id":"a","exp":"1,-2,3,"}`, "translation_id", UnknownFieldError},
+		{` {"payload":[],"other":{"x":[1]},"translation_id":"b"} `, `{"transaction_id":"b","exp":""}`, "other", UnknownFieldError},
+		{`{"translation_id":"<\"é\u2028\n\\>","payload":null}`, `{"transaction_id":"\u003c\"é\u2028\n\\\u003e","exp":""}`, "translation_id", UnknownFieldError},
+		{`{"transaction_id":"c","payload":null}`, `{"transaction_id":"c","exp":""}`, "payload", MissingFieldError},
+	}
+	for _, tt := range tests {
+		got, err := ResponseHandler(Lenient)([]byte("prefix "), []byte(tt.record))
+		if err != nil || string(got) != "prefix "+tt.want {
+			t.Errorf("lenient %s = %s, %v, want %s", tt.record, got, err, tt.want)
+		}
+		_, err = AppendResponse(nil, []byte(tt.record))
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != tt.strict || !errors.Is(err, tt.err) {
+			t.Errorf("strict %s: err = %v, want %s: %v", tt.record, err, tt.strict, tt.err)
+		}
+	}
+}
+
+func TestGeneratedCodecIsUpToDate(t *testing.T) {
+	issues, err := codegen.CheckDirectives(".")
+	for _, issue := range issues {
+		t.Log(issue)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessorErrors(t *testing.T) {
+	input := `{"transaction_id":"a","payload":[1]}
+
+{"transaction_id":"b","payload":[1,}
+{"transaction_id":"c","payload":[1]}
+`
+	for _, workers := range []int{1, 3} {
+		var out bytes.Buffer
+		err := NewProcessor(AppendResponse, Config{Workers: workers, BatchSize: 1}).Process(strings.NewReader(input), &out)
+		var recordErr *RecordError
+		if !errors.As(err, &recordErr) || recordErr.Line != 3 {
+			t.Fatalf("workers %d: err = %v, want an error at line 3", workers, err)
+		}
+		if want := `{"transaction_id":"a","exp":"1,"}` + "\n"; out.String() != want {
+			t.Errorf("workers %d: out = %q, want %q", workers, out.String(), want)
+		}
+	}
+
+	long := `{"transaction_id":"a","payload":[1]}` + "\n" + strings.Repeat(" ", 100) + "\n"
+	err := NewProcessor(AppendResponse, Config{Workers: 1, MaxRecordSize: 64}).Process(strings.NewReader(long), io.Discard)
+	var recordErr *RecordError
+	if !errors.Is(err, RecordTooLongError) || !errors.As(err, &recordErr) || recordErr.Line != 2 {
+		t.Errorf("err = %v, want RecordTooLongError at line 2", err)
+	}
+}
+
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, io.ErrClosedPipe
+}
+
+func TestProcessorWriteError(t *testing.T) {
+	input, _ := requests(500)
+	w := &failingWriter{}
+	err := NewProcessor(AppendResponse, Config{Workers: 4, BatchSize: 10}).Process(bytes.NewReader(input), w)
+	if !errors.Is(err, io.ErrClosedPipe) || w.writes != 1 {
+		t.Errorf("err = %v after %d writes, want ErrClosedPipe after 1", err, w.writes)
+	}
+}
+
+func TestProcessorAllocs(t *testing.T) {
+	input, reqs := requests(100)
+	p := NewProcessor(AppendResponse, Config{Workers: 1})
+	allocs := testing.AllocsPerRun(10, func() {
+		if err := p.Process(bytes.NewReader(input), io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	})
+	old := testing.AllocsPerRun(2, func() { processRequestOld(reqs) })
+	t.Logf("allocs of 100 requests: Processor %.0f, processRequestOld %.0f", allocs, old)
+	// a few per run and none per record, more with the race detector which drops pooled items at random
+	if allocs*10 > old {
+		t.Errorf("Processor allocs %.0f, want below 10%% of processRequestOld %.0f", allocs, old)
+	}
+}
+
+// go test -bench=Requests -benchmem
+// or run them 10 times and compare with the bench tool:
+// go run ../bench/cmd/bench run -bench Requests .
+func benchmarkRequests(b *testing.B, process func(input []byte, reqs []string)) {
+	input, reqs := requests(100)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		process(input, reqs)
+	}
+}
+
+func BenchmarkRequestsOld(b *testing.B) {
+	benchmarkRequests(b, func(_ []byte, reqs []string) { processRequestOld(reqs) })
+}
+
+func BenchmarkRequestsCodec(b *testing.B) {
+	benchmarkRequests(b, func(_ []byte, reqs []string) { processRequest(reqs, Strict) })
+}
+
+func BenchmarkRequestsProcessor(b *testing.B) {
+	p := NewProcessor(AppendResponse, Config{Workers: 1})
+	benchmarkRequests(b, func(input []byte, _ []string) { p.Process(bytes.NewReader(input), io.Discard) })
+}
+
+func BenchmarkRequestsProcessorParallel(b *testing.B) {
+	p := NewProcessor(AppendResponse, Config{BatchSize: 16})
+	benchmarkRequests(b, func(input []byte, _ []string) { p.Process(bytes.NewReader(input), io.Discard) })
+}
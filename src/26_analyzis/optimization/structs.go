@@ -1,7 +1,9 @@
 package optimize
 
+//go:generate go run go_learning/src/24_json/codegen/cmd/jsoncodec structs.go
+
 type Request struct {
-	TransactionID string `json:"translation_id"`
+	TransactionID string `json:"transaction_id"`
 	PayLoad []int `json:"payload"`
 }
 
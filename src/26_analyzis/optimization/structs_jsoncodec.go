@@ -0,0 +1,186 @@
+// Code generated by jsoncodec structs.go; DO NOT EDIT.
+
+package optimize
+
+import (
+	"strings"
+
+	"go_learning/src/24_json/codegen/codec"
+
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// MarshalJSON supports json.Marshaler interface
+func (v Request) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	jsoncodecEncodeRequest(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Request) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	jsoncodecDecodeRequest(&r, v)
+	r.Consumed()
+	return codec.Error(&r)
+}
+
+func jsoncodecEncodeRequest(out *jwriter.Writer, in Request) {
+	out.RawByte('{')
+	out.RawString("\"transaction_id\":")
+	out.String(in.TransactionID)
+	out.RawString(",\"payload\":")
+	if in.PayLoad == nil {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for i1, v1 := range in.PayLoad {
+			if i1 > 0 {
+				out.RawByte(',')
+			}
+			out.Int(v1)
+		}
+		out.RawByte(']')
+	}
+	out.RawByte('}')
+}
+
+func jsoncodecDecodeRequest(in *jlexer.Lexer, out *Request) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch jsoncodecFieldRequest(key) {
+		case 0:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.TransactionID = in.String()
+			}
+		case 1:
+			if in.IsNull() {
+				in.Skip()
+				out.PayLoad = nil
+			} else {
+				in.Delim('[')
+				if out.PayLoad == nil {
+					if !in.IsDelim(']') {
+						out.PayLoad = make([]int, 0, 4)
+					} else {
+						out.PayLoad = []int{}
+					}
+				} else {
+					out.PayLoad = out.PayLoad[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 int
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v1 = in.Int()
+					}
+					out.PayLoad = append(out.PayLoad, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+// jsoncodecFieldRequest is the index of the field of the key, -1 for an unknown key
+func jsoncodecFieldRequest(key string) int {
+	switch key {
+	case "transaction_id":
+		return 0
+	case "payload":
+		return 1
+	}
+	switch {
+	case strings.EqualFold(key, "transaction_id"):
+		return 0
+	case strings.EqualFold(key, "payload"):
+		return 1
+	}
+	return -1
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Response) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	jsoncodecEncodeResponse(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Response) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	jsoncodecDecodeResponse(&r, v)
+	r.Consumed()
+	return codec.Error(&r)
+}
+
+func jsoncodecEncodeResponse(out *jwriter.Writer, in Response) {
+	out.RawByte('{')
+	out.RawString("\"transaction_id\":")
+	out.String(in.TransactionID)
+	out.RawString(",\"exp\":")
+	out.String(in.Expression)
+	out.RawByte('}')
+}
+
+func jsoncodecDecodeResponse(in *jlexer.Lexer, out *Response) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch jsoncodecFieldResponse(key) {
+		case 0:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.TransactionID = in.String()
+			}
+		case 1:
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Expression = in.String()
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+// jsoncodecFieldResponse is the index of the field of the key, -1 for an unknown key
+func jsoncodecFieldResponse(key string) int {
+	switch key {
+	case "transaction_id":
+		return 0
+	case "exp":
+		return 1
+	}
+	switch {
+	case strings.EqualFold(key, "transaction_id"):
+		return 0
+	case strings.EqualFold(key, "exp"):
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,185 @@
+package optimize
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mailru/easyjson/jlexer"
+)
+
+/*
+	Request 與 Response 的 id 欄位都是 transaction_id，舊版的 Request 誤寫成 translation_id。
+	解析 record 時照 schema 檢查，每個 record 的錯誤是 RecordError{Line, FieldError{Field, Err}}：
+		line 3: payload: parse error: expected number near offset 33 of 'x'
+		line 4: transaction_id: missing field
+		line 5: unexpected EOF（被截斷的 record）
+	欄位名稱的比對有兩種模式：
+	* Strict：只接受 json tag 的名稱，不認得的欄位是錯誤
+	* Lenient：不分大小寫、忽略 _ 與 -（TransactionID、transactionId 都可以），
+	  也接受舊的 translation_id，不認得的欄位略過
+	兩種模式都檢查型別、重複的欄位、必要的欄位（null 當作沒有這個欄位）；
+	只有 Lenient 的 "payload":null 是空的 payload，和舊版 Request 的解碼一樣。
+*/
+
+var (
+	MissingFieldError   = errors.New("missing field")
+	EmptyFieldError     = errors.New("empty field")
+	UnknownFieldError   = errors.New("unknown field")
+	DuplicateFieldError = errors.New("duplicate field")
+)
+
+// FieldError is the error of a field of a record.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Naming is how the field names of a record are matched.
+type Naming int
+
+const (
+	// Strict accepts only the names of the json tags, an unknown field is an error
+	Strict Naming = iota
+	// Lenient ignores the case, _ and -, accepts the legacy translation_id and skips the unknown fields
+	Lenient
+)
+
+func (n Naming) String() string {
+	if n == Lenient {
+		return "lenient"
+	}
+	return "strict"
+}
+
+const (
+	fieldUnknown = iota
+	fieldTransactionID
+	fieldPayload
+)
+
+// requestField is the field of the name in a Request record
+func requestField(name string, naming Naming) int {
+	switch name {
+	case "transaction_id":
+		return fieldTransactionID
+	case "payload":
+		return fieldPayload
+	}
+	if naming == Lenient {
+		switch {
+		case foldedEqual(name, "transactionid"), foldedEqual(name, "translationid"):
+			return fieldTransactionID
+		case foldedEqual(name, "payload"):
+			return fieldPayload
+		}
+	}
+	return fieldUnknown
+}
+
+// foldedEqual compares name to the lower case folded without the _ and -
+func foldedEqual(name, folded string) bool {
+	j := 0
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' || c == '-' {
+			continue
+		}
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if j >= len(folded) || folded[j] != c {
+			return false
+		}
+		j++
+	}
+	return j == len(folded)
+}
+
+// fieldError wraps the error of the lexer in the field, the end of the data is an unexpected EOF
+func fieldError(field string, in *jlexer.Lexer) error {
+	err := in.Error()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return &FieldError{Field: field, Err: err}
+}
+
+// decode reads and validates a Request record, the payload is formatted to the Expression of the Response at once.
+func (s *requestScratch) decode(record []byte, naming Naming) error {
+	s.id, s.exp = s.id[:0], s.exp[:0]
+	var seen [3]bool
+	in := jlexer.Lexer{Data: record}
+	in.Delim('{')
+	for in.Ok() && !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		field := requestField(key, naming)
+		if !in.Ok() {
+			break
+		}
+		if field == fieldUnknown && naming == Strict {
+			// key may point into the record, which is reused
+			return &FieldError{Field: string([]byte(key)), Err: UnknownFieldError}
+		}
+		if seen[field] && field != fieldUnknown {
+			return &FieldError{Field: string([]byte(key)), Err: DuplicateFieldError}
+		}
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			if field == fieldPayload && naming == Lenient {
+				seen[field] = true
+			}
+			continue
+		}
+		seen[field] = true
+		switch field {
+		case fieldTransactionID:
+			s.id = append(s.id, in.UnsafeBytes()...)
+			if !in.Ok() {
+				return fieldError("transaction_id", &in)
+			}
+		case fieldPayload:
+			in.Delim('[')
+			for in.Ok() && !in.IsDelim(']') {
+				s.exp = strconv.AppendInt(s.exp, in.Int64(), 10)
+				s.exp = append(s.exp, ',')
+				in.WantComma()
+			}
+			in.Delim(']')
+			if !in.Ok() {
+				return fieldError("payload", &in)
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	in.Consumed()
+	if err := in.Error(); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	switch {
+	case !seen[fieldTransactionID]:
+		return &FieldError{Field: "transaction_id", Err: MissingFieldError}
+	case len(s.id) == 0:
+		return &FieldError{Field: "transaction_id", Err: EmptyFieldError}
+	case !seen[fieldPayload]:
+		return &FieldError{Field: "payload", Err: MissingFieldError}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package optimize
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValidateRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		naming Naming
+		want   string
+		err    error
+		field  string
+	}{
+		{"strict", `{"transaction_id":"a","payload":[1,2]}`, Strict, `{"transaction_id":"a","exp":"1,2,"}`, nil, ""},
+		{"legacy name strict", `{"translation_id":"a","payload":[1]}`, Strict, "", UnknownFieldError, "translation_id"},
+		{"legacy name lenient", `{"translation_id":"a","payload":[1]}`, Lenient, `{"transaction_id":"a","exp":"1,"}`, nil, ""},
+		{"folded names", `{"TransactionID":"a","Pay-Load":[1]}`, Lenient, `{"transaction_id":"a","exp":"1,"}`, nil, ""},
+		{"camel case", `{"transactionId":"a","payload":[]}`, Lenient, `{"transaction_id":"a","exp":""}`, nil, ""},
+		{"unknown strict", `{"transaction_id":"a","payload":[1],"extra":{"x":[1]}}`, Strict, "", UnknownFieldError, "extra"},
+		{"unknown lenient", `{"transaction_id":"a","extra":{"x":[1]},"payload":[1]}`, Lenient, `{"transaction_id":"a","exp":"1,"}`, nil, ""},
+		{"duplicate", `{"transaction_id":"a","payload":[1],"transaction_id":"b"}`, Strict, "", DuplicateFieldError, "transaction_id"},
+		{"duplicate folded", `{"transaction_id":"a","TransactionID":"b","payload":[1]}`, Lenient, "", DuplicateFieldError, "TransactionID"},
+		{"missing id", `{"payload":[1]}`, Strict, "", MissingFieldError, "transaction_id"},
+		{"null id", `{"transaction_id":null,"payload":[1]}`, Strict, "", MissingFieldError, "transaction_id"},
+		{"empty id", `{"transaction_id":"","payload":[1]}`, Strict, "", EmptyFieldError, "transaction_id"},
+		{"missing payload", `{"transaction_id":"a"}`, Lenient, "", MissingFieldError, "payload"},
+		{"id type", `{"transaction_id":1,"payload":[1]}`, Strict, "", nil, "transaction_id"},
+		{"payload type", `{"transaction_id":"a","payload":"1,2"}`, Strict, "", nil, "payload"},
+		{"payload element", `{"transaction_id":"a","payload":[1,"x"]}`, Strict, "", nil, "payload"},
+		{"payload float", `{"transaction_id":"a","payload":[1.5]}`, Strict, "", nil, "payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResponseHandler(tt.naming)(nil, []byte(tt.record))
+			if tt.field == "" {
+				if err != nil || string(got) != tt.want {
+					t.Fatalf("got %s, %v, want %s", got, err, tt.want)
+				}
+				return
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || fieldErr.Field != tt.field {
+				t.Fatalf("err = %v, want an error of the field %s", err, tt.field)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if len(got) != 0 {
+				t.Errorf("got %s with an error", got)
+			}
+		})
+	}
+}
+
+func TestMalformedRecords(t *testing.T) {
+	valid := `{"transaction_id":"a","payload":[1,2,3]}`
+	// every truncation of a valid record is an error, never an empty transaction
+	for i := 0; i < len(valid); i++ {
+		for _, naming := range []Naming{Strict, Lenient} {
+			got, err := ResponseHandler(naming)(nil, []byte(valid[:i]))
+			if err == nil {
+				t.Errorf("%s %q: got %s, want an error", naming, valid[:i], got)
+			}
+		}
+	}
+	for _, record := range []string{`{"transaction_id":"a","payload":[1,2`, `{"transaction_id":"a",`, `{`} {
+		if _, err := AppendResponse(nil, []byte(record)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%q: err = %v, want unexpected EOF", record, err)
+		}
+	}
+	for _, record := range []string{`[]`, `null`, `"a"`, `{"transaction_id":"a","payload":[1]}}`, `{"transaction_id":"a" "payload":[1]}`, `{transaction_id:"a"}`} {
+		if got, err := AppendResponse(nil, []byte(record)); err == nil {
+			t.Errorf("%q: got %s, want an error", record, got)
+		}
+	}
+}
+
+func TestProcessorContinueOnError(t *testing.T) {
+	input := `{"transaction_id":"a","payload":[1]}
+{"translation_id":"b","payload":[2]}
+{"transaction_id":"c","payload":[3
+{"transaction_id":"d","payload":[4]}
+`
+	want := `{"transaction_id":"a","exp":"1,"}
+{"line":2,"error":"translation_id: unknown field"}
+{"line":3,"error":"unexpected EOF"}
+{"transaction_id":"d","exp":"4,"}
+`
+	for _, workers := range []int{1, 2} {
+		var out bytes.Buffer
+		p := NewProcessor(AppendResponse, Config{Workers: workers, BatchSize: 1, ContinueOnError: true})
+		if err := p.Process(strings.NewReader(input), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("workers %d: out =\n%s\nwant\n%s", workers, out.String(), want)
+		}
+	}
+
+	// the legacy name is accepted in lenient mode
+	var out bytes.Buffer
+	p := NewProcessor(ResponseHandler(Lenient), Config{Workers: 1, ContinueOnError: true})
+	if err := p.Process(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `{"transaction_id":"b","exp":"2,"}`) {
+		t.Errorf("lenient out =\n%s", out.String())
+	}
+}
+
+func TestProcessRequestErrors(t *testing.T) {
+	valid := createRequest()
+	tests := []struct {
+		name   string
+		req    string
+		naming Naming
+		err    error
+	}{
+		{"truncated", valid[:len(valid)/2], Strict, io.ErrUnexpectedEOF},
+		{"legacy name", `{"translation_id":"a","payload":[1]}`, Strict, UnknownFieldError},
+		{"missing payload", `{"transaction_id":"a"}`, Lenient, MissingFieldError},
+		{"malformed", `{"transaction_id":"a","payload":[1,x]}`, Lenient, nil},
+	}
+	for _, tt := range tests {
+		reps, err := processRequest([]string{valid, tt.req}, tt.naming)
+		// the same error as the Processor
+		_, want := ResponseHandler(tt.naming)(nil, []byte(tt.req))
+		var recordErr *RecordError
+		if !errors.As(err, &recordErr) || recordErr.Line != 2 || err.Error() != "line 2: "+want.Error() {
+			t.Errorf("%s: reps = %v, err = %v, want the error of the line 2", tt.name, reps, err)
+			continue
+		}
+		if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	reps, err := processRequest([]string{`{"translation_id":"a","payload":[1]}`}, Lenient)
+	if err != nil || len(reps) != 1 || reps[0] != `{"transaction_id":"a","exp":"1,"}` {
+		t.Errorf("lenient naming should accept the legacy name, got %v, %v", reps, err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go_learning/src/26_analyzis/profiling/flamegraph"
+	"go_learning/src/26_analyzis/profiling/profile"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// renders a pprof file, or folded stacks, as an interactive SVG flame graph without go-torch and flamegraph.pl
+// $ go run ./src/26_analyzis/profiling/cmd/flamegraph -o cpu.svg src/26_analyzis/tools/cpu.prof
+// $ go run ./src/26_analyzis/profiling/cmd/flamegraph -sample alloc_space -o mem.svg src/26_analyzis/tools/mem.prof
+// $ go run ./src/26_analyzis/profiling/cmd/flamegraph -format folded src/26_analyzis/tools/cpu.prof > cpu.folded
+// $ go run ./src/26_analyzis/profiling/cmd/flamegraph -o cpu.svg cpu.folded
+// $ curl -s http://localhost:8081/debug/pprof/profile?seconds=10 | go run ./src/26_analyzis/profiling/cmd/flamegraph -o cpu.svg
+func main() {
+	sample := flag.String("sample", "", "sample type like cpu, alloc_space or inuse_space, the default of the profile when empty")
+	format := flag.String("format", "svg", "svg or folded")
+	output := flag.String("o", "", "output file, stdout when empty")
+	title := flag.String("title", "", "title of the graph, Flame Graph by default")
+	width := flag.Int("width", flamegraph.DefaultWidth, "width of the graph in pixels")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [profile or folded stacks, stdin when missing]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 || (*format != "svg" && *format != "folded") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	name, data, err := readInput(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var folded map[string]int64
+	o := flamegraph.Options{Title: *title, Width: *width, Subtitle: name}
+	p, err := profile.Parse(bytes.NewReader(data))
+	switch {
+	case err == nil:
+		i, err := p.SampleIndex(*sample)
+		if err != nil {
+			log.Fatal(err)
+		}
+		folded = p.Folded(i)
+		o.Unit = p.SampleType[i].Unit
+		o.Subtitle = fmt.Sprintf("%s, %s, total %s", name, p.SampleType[i], profile.FormatValue(p.Total(i), o.Unit))
+	case errors.Is(err, profile.MalformedError):
+		// not a profile, folded stacks like flamegraph.pl reads
+		if folded, err = flamegraph.ReadFolded(bytes.NewReader(data)); err != nil {
+			log.Fatalf("%s is neither a profile nor folded stacks: %v", name, err)
+		}
+	default:
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "folded" {
+		err = flamegraph.WriteFolded(w, folded)
+	} else {
+		err = flamegraph.Render(w, flamegraph.Build(folded), o)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readInput(path string) (string, []byte, error) {
+	if path == "" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		return "stdin", data, err
+	}
+	data, err := ioutil.ReadFile(path)
+	return filepath.Base(path), data, err
+}
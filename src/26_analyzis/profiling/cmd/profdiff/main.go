@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go_learning/src/26_analyzis/profiling/profile"
+	"log"
+	"os"
+)
+
+// regressionExitCode is the exit status when a function regressed, errors exit with 1
+const regressionExitCode = 3
+
+// compares two pprof files and ranks the functions by how much they changed
+// $ go test -bench=. -cpuprofile=old.prof   # before the optimization
+// $ go test -bench=. -cpuprofile=new.prof   # after
+// $ go run ./src/26_analyzis/profiling/cmd/profdiff old.prof new.prof
+// $ go run ./src/26_analyzis/profiling/cmd/profdiff -by cum -format markdown -threshold 5 old.prof new.prof
+// $ go run ./src/26_analyzis/profiling/cmd/profdiff -sample alloc_space old_mem.prof new_mem.prof
+// exits with 3 when a function grew by more than -threshold percent of the before total
+func main() {
+	sample := flag.String("sample", "", "sample type like cpu, alloc_space or inuse_space, the default of the profile when empty")
+	by := flag.String("by", string(profile.Flat), "rank and check the regressions by flat or cum")
+	top := flag.Int("top", 20, "number of functions reported, 0 reports all of them")
+	format := flag.String("format", "text", "text or markdown")
+	threshold := flag.Float64("threshold", 0, "fail when a function grew by more than this percent of the before total, 0 disables it")
+	normalize := flag.Bool("normalize", false, "scale the before profile to the total of the after profile")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] before.prof after.prof\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	write := (*profile.Report).WriteText
+	switch *format {
+	case "text":
+	case "markdown":
+		write = (*profile.Report).WriteMarkdown
+	default:
+		log.Fatalf("unknown -format %q, should be text or markdown", *format)
+	}
+
+	before, err := profile.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	after, err := profile.Open(flag.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	report, err := profile.Diff(before, after, profile.Options{SampleType: *sample, Normalize: *normalize, By: profile.Metric(*by)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := write(report, os.Stdout, *top, *threshold); err != nil {
+		log.Fatal(err)
+	}
+	if len(report.Regressions(*threshold)) > 0 {
+		os.Exit(regressionExitCode)
+	}
+}
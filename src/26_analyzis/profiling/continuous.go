@@ -0,0 +1,349 @@
+package profiling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Continuous 在背景定時拍下 CPU 與 heap profile，問題發生之後還找得到當時的資料，
+	不用等有人剛好去打 /debug/pprof：
+
+	* 每 Interval 拍一次 snapshot：CPU 記錄 CPUDuration，然後寫 heap 與 goroutine
+	* 每 CheckInterval 檢查 heap（runtime.MemStats.HeapAlloc）與 goroutine 數量，
+	  超過門檻就提早拍一次，同一個原因至少相隔 Cooldown，避免一直拍
+	* snapshot 放在 Dir/<時間>-<原因>/ 底下，像 ring buffer 一樣只保留最新的 MaxSnapshots 個，
+	  以及 MaxAge 以內的
+	* Handler 列出 snapshot（JSON）並提供下載：
+		GET /                          → [{"id":"20220531T214600.000Z-scheduled", ...}]
+		GET /<id>/cpu.prof             → go tool pprof 可以直接讀的檔案
+
+	p, err := profiling.NewContinuous(profiling.ContinuousConfig{Dir: "snapshots", HeapThreshold: 512 << 20})
+	go p.Run(ctx, log.Printf)
+	http.Handle("/debug/snapshots/", http.StripPrefix("/debug/snapshots", p.Handler()))
+	$ go tool pprof http://localhost:8081/debug/snapshots/<id>/cpu.prof
+*/
+
+const (
+	ReasonScheduled = "scheduled"
+	ReasonHeap      = "heap"
+	ReasonGoroutine = "goroutine"
+	ReasonManual    = "manual"
+)
+
+var (
+	SnapshotNotFoundError = errors.New("snapshot not found")
+	InvalidReasonError    = errors.New("invalid snapshot reason")
+)
+
+// ContinuousConfig of a Continuous, only Dir is required.
+type ContinuousConfig struct {
+	Dir string
+	// Interval between the scheduled snapshots, 5 minutes by default
+	Interval time.Duration
+	// CPUDuration is how long the CPU profile of a snapshot records, 10s by default, negative skips it
+	CPUDuration time.Duration
+	// MaxSnapshots are kept, the oldest are removed first, 24 by default
+	MaxSnapshots int
+	// MaxAge removes the older snapshots, 0 keeps them until MaxSnapshots
+	MaxAge time.Duration
+	// HeapThreshold in bytes and GoroutineThreshold trigger a snapshot, 0 disables them
+	HeapThreshold      uint64
+	GoroutineThreshold int
+	// CheckInterval between the threshold checks, 10s by default
+	CheckInterval time.Duration
+	// Cooldown is the least time between two triggered snapshots of the same reason, 1 minute by default
+	Cooldown time.Duration
+	// Now is time.Now by default
+	Now func() time.Time
+}
+
+// SnapshotFile is a profile of a snapshot.
+type SnapshotFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Snapshot is a set of profiles taken at the same time.
+type Snapshot struct {
+	ID     string         `json:"id"`
+	Time   time.Time      `json:"time"`
+	Reason string         `json:"reason"`
+	Files  []SnapshotFile `json:"files"`
+}
+
+// Continuous takes snapshots in the background, see ContinuousConfig.
+type Continuous struct {
+	config ContinuousConfig
+
+	// mu serializes the captures and the pruning
+	mu          sync.Mutex
+	lastTrigger map[string]time.Time
+}
+
+func NewContinuous(c ContinuousConfig) (*Continuous, error) {
+	if c.Dir == "" {
+		return nil, errors.New("profiling: the snapshot directory is required")
+	}
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Minute
+	}
+	if c.CPUDuration == 0 {
+		c.CPUDuration = 10 * time.Second
+	}
+	if c.MaxSnapshots <= 0 {
+		c.MaxSnapshots = 24
+	}
+	if c.CheckInterval <= 0 {
+		c.CheckInterval = 10 * time.Second
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = time.Minute
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create snapshot directory: %w", err)
+	}
+	return &Continuous{config: c, lastTrigger: map[string]time.Time{}}, nil
+}
+
+// Run takes the scheduled and the triggered snapshots until ctx is done.
+// A failed snapshot is reported to logf, which may be nil, and doesn't stop Run.
+func (p *Continuous) Run(ctx context.Context, logf func(format string, args ...interface{})) error {
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+	schedule := time.NewTicker(p.config.Interval)
+	defer schedule.Stop()
+	check := time.NewTicker(p.config.CheckInterval)
+	defer check.Stop()
+	for {
+		reason := ""
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-schedule.C:
+			reason = ReasonScheduled
+		case <-check.C:
+			reason = p.exceeded()
+		}
+		if reason == "" {
+			continue
+		}
+		if s, err := p.Capture(ctx, reason); err != nil {
+			logf("profiling: %s snapshot %s: %v", reason, s.ID, err)
+		}
+	}
+}
+
+// exceeded returns the reason of a crossed threshold out of its cooldown, "" for none.
+func (p *Continuous) exceeded() string {
+	var reasons []string
+	if p.config.HeapThreshold > 0 {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		if ms.HeapAlloc > p.config.HeapThreshold {
+			reasons = append(reasons, ReasonHeap)
+		}
+	}
+	if p.config.GoroutineThreshold > 0 && runtime.NumGoroutine() > p.config.GoroutineThreshold {
+		reasons = append(reasons, ReasonGoroutine)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.config.Now()
+	for _, reason := range reasons {
+		if last, ok := p.lastTrigger[reason]; !ok || now.Sub(last) >= p.config.Cooldown {
+			p.lastTrigger[reason] = now
+			return reason
+		}
+	}
+	return ""
+}
+
+var (
+	snapshotIDPattern = regexp.MustCompile(`^\d{8}T\d{6}\.\d{3}Z(-\d+)?-[a-z]+$`)
+	// the reason is a part of the id, a path or a dash in it would make an id the pattern rejects
+	reasonPattern = regexp.MustCompile(`^[a-z]+$`)
+)
+
+const snapshotTimeLayout = "20060102T150405.000Z"
+
+// Capture takes a snapshot now, then removes the snapshots out of the retention.
+// The snapshot is kept with the profiles written even when one of them fails,
+// e.g. the CPU profile while someone is reading /debug/pprof/profile.
+// The reason is lower case letters like ReasonManual, InvalidReasonError otherwise.
+func (p *Continuous) Capture(ctx context.Context, reason string) (Snapshot, error) {
+	if !reasonPattern.MatchString(reason) {
+		return Snapshot{}, fmt.Errorf("%w %q, want lower case letters", InvalidReasonError, reason)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.config.Now().UTC()
+	id := now.Format(snapshotTimeLayout) + "-" + reason
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(p.config.Dir, id)); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d-%s", now.Format(snapshotTimeLayout), i, reason)
+	}
+	// written under a temporary name, the list never shows a snapshot half written
+	tmp := filepath.Join(p.config.Dir, "."+id+".tmp")
+
+	var firstErr error
+	if p.config.CPUDuration > 0 {
+		if err := p.profileCPU(ctx, tmp); err != nil {
+			firstErr = err
+		}
+	}
+	if _, err := Run(Config{Kinds: []Kind{Heap, Goroutine}, Dir: tmp}, func(context.Context) error { return nil }); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	if err := os.Rename(tmp, filepath.Join(p.config.Dir, id)); err != nil {
+		os.RemoveAll(tmp)
+		return Snapshot{ID: id}, fmt.Errorf("save snapshot: %w", err)
+	}
+	if err := p.prune(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	s, err := p.snapshot(id)
+	if err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return s, firstErr
+}
+
+func (p *Continuous) profileCPU(ctx context.Context, dir string) error {
+	s, err := Start(Config{Kinds: []Kind{CPU}, Dir: dir})
+	if err != nil {
+		os.Remove(filepath.Join(dir, CPU.File()))
+		return err
+	}
+	timer := time.NewTimer(p.config.CPUDuration)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+	case <-timer.C:
+	}
+	_, err = s.Stop()
+	return err
+}
+
+// Snapshots lists the snapshots, the newest first.
+func (p *Continuous) Snapshots() ([]Snapshot, error) {
+	entries, err := ioutil.ReadDir(p.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !snapshotIDPattern.MatchString(entry.Name()) {
+			continue
+		}
+		s, err := p.snapshot(entry.Name())
+		if err != nil {
+			// removed by a concurrent prune
+			continue
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].ID > snapshots[j].ID })
+	return snapshots, nil
+}
+
+func (p *Continuous) snapshot(id string) (Snapshot, error) {
+	if !snapshotIDPattern.MatchString(id) {
+		return Snapshot{}, SnapshotNotFoundError
+	}
+	files, err := ioutil.ReadDir(filepath.Join(p.config.Dir, id))
+	if os.IsNotExist(err) {
+		return Snapshot{}, SnapshotNotFoundError
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	t, _ := time.Parse(snapshotTimeLayout, id[:len(snapshotTimeLayout)])
+	s := Snapshot{ID: id, Time: t, Reason: id[strings.LastIndexByte(id, '-')+1:], Files: []SnapshotFile{}}
+	for _, f := range files {
+		if f.Mode().IsRegular() {
+			s.Files = append(s.Files, SnapshotFile{Name: f.Name(), Size: f.Size()})
+		}
+	}
+	return s, nil
+}
+
+// prune removes the snapshots beyond MaxSnapshots and older than MaxAge.
+func (p *Continuous) prune() error {
+	snapshots, err := p.Snapshots()
+	if err != nil {
+		return err
+	}
+	now := p.config.Now()
+	for i, s := range snapshots {
+		tooOld := p.config.MaxAge > 0 && now.Sub(s.Time) > p.config.MaxAge
+		if i >= p.config.MaxSnapshots || tooOld {
+			if err := os.RemoveAll(filepath.Join(p.config.Dir, s.ID)); err != nil {
+				return fmt.Errorf("remove snapshot: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Handler lists the snapshots at / and serves their files at /<id>/<file>.
+func (p *Continuous) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		path := strings.Trim(r.URL.Path, "/")
+		if path == "" {
+			snapshots, err := p.Snapshots()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(snapshots)
+			return
+		}
+		parts := strings.Split(path, "/")
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		s, err := p.snapshot(parts[0])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		for _, f := range s.Files {
+			// only the listed names, so the path can't leave the snapshot directory
+			if f.Name == parts[1] {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.ID+"-"+f.Name))
+				http.ServeFile(w, r, filepath.Join(p.config.Dir, s.ID, f.Name))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+}
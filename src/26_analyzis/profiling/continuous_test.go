@@ -0,0 +1,179 @@
+package profiling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newContinuous(t *testing.T, c ContinuousConfig) (*Continuous, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2022, 5, 31, 21, 46, 0, 0, time.UTC)}
+	c.Dir = t.TempDir()
+	if c.CPUDuration == 0 {
+		c.CPUDuration = 10 * time.Millisecond
+	}
+	c.Now = clock.Now
+	p, err := NewContinuous(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, clock
+}
+
+func TestCapture(t *testing.T) {
+	p, _ := newContinuous(t, ContinuousConfig{})
+	s, err := p.Capture(context.Background(), ReasonManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != "20220531T214600.000Z-manual" || s.Reason != ReasonManual || !s.Time.Equal(time.Date(2022, 5, 31, 21, 46, 0, 0, time.UTC)) {
+		t.Errorf("unexpected %+v", s)
+	}
+	names := map[string]bool{}
+	for _, f := range s.Files {
+		names[f.Name] = f.Size > 0
+	}
+	if !names["cpu.prof"] || !names["heap.prof"] || !names["goroutine.prof"] {
+		t.Errorf("expected non-empty profiles, got %+v", s.Files)
+	}
+
+	// a second snapshot of the same millisecond gets another id
+	s2, err := p.Capture(context.Background(), ReasonManual)
+	if err != nil || s2.ID == s.ID {
+		t.Errorf("unexpected %+v %v", s2, err)
+	}
+	for _, reason := range []string{"", "../../etc", "on-demand", "Manual", "heap2"} {
+		if _, err := p.Capture(context.Background(), reason); !errors.Is(err, InvalidReasonError) {
+			t.Errorf("reason %q: expected InvalidReasonError, got %v", reason, err)
+		}
+	}
+}
+
+func TestCaptureWhileCPUBusy(t *testing.T) {
+	p, _ := newContinuous(t, ContinuousConfig{})
+	f, err := os.Create(filepath.Join(t.TempDir(), "busy.prof"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := pprof.StartCPUProfile(f); err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.Capture(context.Background(), ReasonManual)
+	pprof.StopCPUProfile()
+	if err == nil || len(s.Files) != 2 {
+		t.Errorf("expected the heap and goroutine profiles and an error, got %+v %v", s, err)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	p, clock := newContinuous(t, ContinuousConfig{CPUDuration: -1, MaxSnapshots: 3, MaxAge: time.Hour})
+	for i := 0; i < 5; i++ {
+		if _, err := p.Capture(context.Background(), ReasonScheduled); err != nil {
+			t.Fatal(err)
+		}
+		clock.now = clock.now.Add(time.Minute)
+	}
+	snapshots, err := p.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 3 || snapshots[0].ID != "20220531T215000.000Z-scheduled" || snapshots[2].ID != "20220531T214800.000Z-scheduled" {
+		t.Fatalf("expected the newest 3, got %+v", snapshots)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := p.Capture(context.Background(), ReasonScheduled); err != nil {
+		t.Fatal(err)
+	}
+	if snapshots, _ := p.Snapshots(); len(snapshots) != 1 {
+		t.Errorf("expected the old snapshots to be removed, got %+v", snapshots)
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	p, clock := newContinuous(t, ContinuousConfig{GoroutineThreshold: 1, HeapThreshold: 1, Cooldown: time.Minute})
+	first, second := p.exceeded(), p.exceeded()
+	if first != ReasonHeap || second != ReasonGoroutine {
+		t.Errorf("expected heap then goroutine, got %q %q", first, second)
+	}
+	if reason := p.exceeded(); reason != "" {
+		t.Errorf("expected the cooldown, got %q", reason)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if reason := p.exceeded(); reason != ReasonHeap {
+		t.Errorf("expected heap after the cooldown, got %q", reason)
+	}
+
+	quiet, _ := newContinuous(t, ContinuousConfig{GoroutineThreshold: 1 << 20})
+	if reason := quiet.exceeded(); reason != "" {
+		t.Errorf("unexpected %q", reason)
+	}
+}
+
+func TestContinuousRun(t *testing.T) {
+	p, _ := newContinuous(t, ContinuousConfig{CPUDuration: -1, Interval: time.Hour, CheckInterval: 5 * time.Millisecond, GoroutineThreshold: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := p.Run(ctx, t.Logf); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected %v", err)
+	}
+	// the fake clock doesn't move, so the cooldown allows one snapshot
+	snapshots, _ := p.Snapshots()
+	if len(snapshots) != 1 || snapshots[0].Reason != ReasonGoroutine {
+		t.Errorf("expected a goroutine snapshot, got %+v", snapshots)
+	}
+}
+
+func TestContinuousHandler(t *testing.T) {
+	p, _ := newContinuous(t, ContinuousConfig{CPUDuration: -1})
+	s, err := p.Capture(context.Background(), ReasonManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := http.StripPrefix("/debug/snapshots", p.Handler())
+	serve := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w
+	}
+
+	w := serve("GET", "/debug/snapshots/")
+	var list []Snapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0].ID != s.ID {
+		t.Fatalf("unexpected %q %v", w.Body, err)
+	}
+	w = serve("GET", "/debug/snapshots/"+s.ID+"/heap.prof")
+	if w.Code != http.StatusOK || w.Body.Len() == 0 || w.Header().Get("Content-Disposition") == "" {
+		t.Errorf("unexpected %d %v", w.Code, w.Header())
+	}
+	for _, url := range []string{
+		"/debug/snapshots/" + s.ID + "/cpu.prof",
+		"/debug/snapshots/" + s.ID + "/../../etc/passwd",
+		"/debug/snapshots/nothing/heap.prof",
+		"/debug/snapshots/" + s.ID,
+	} {
+		if w := serve("GET", url); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", url, w.Code)
+		}
+	}
+	if w := serve("DELETE", "/debug/snapshots/"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
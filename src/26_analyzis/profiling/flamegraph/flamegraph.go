@@ -0,0 +1,132 @@
+package flamegraph
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	不用 go-torch 與 flamegraph.pl 畫火焰圖（flame graph）：
+
+	* 把每個 call stack 從 root 開始疊起來，x 軸是值（CPU 時間、記憶體）的比例，不是時間順序，
+	  同一層的 frame 依名字排序，y 軸是 stack 的深度，最下面是 root
+	* 越寬的 frame 佔越多，最上面（沒有東西疊在上面）的部分是 function 自己花的（flat）
+	* 產生的 SVG 用瀏覽器打開：滑鼠移到 frame 上看名字與值，點一下放大這個 frame，
+	  Search 用 regular expression 標出符合的 frame 並算出佔了多少
+
+	root := flamegraph.Build(p.Folded(index))    // 或 flamegraph.ReadFolded 讀 folded stacks 檔案
+	err := flamegraph.Render(w, root, flamegraph.Options{Title: "CPU", Unit: "nanoseconds"})
+*/
+
+var InvalidFoldedError = errors.New("invalid folded stacks")
+
+// Node is a frame of the graph, Value includes the children.
+type Node struct {
+	Name     string
+	Value    int64
+	Children []*Node
+}
+
+// Self is the value of the node itself, the flat of the frame.
+func (n *Node) Self() int64 {
+	self := n.Value
+	for _, c := range n.Children {
+		self -= c.Value
+	}
+	return self
+}
+
+// Depth is the number of levels under and including n.
+func (n *Node) Depth() int {
+	depth := 0
+	for _, c := range n.Children {
+		if d := c.Depth(); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+// Build merges the folded stacks, root first and separated by ;, into a tree under the root "all".
+// The children are sorted by name, negative and zero values are ignored.
+func Build(folded map[string]int64) *Node {
+	root := &Node{Name: "all"}
+	index := map[*Node]map[string]*Node{}
+	for stack, v := range folded {
+		if v <= 0 || stack == "" {
+			continue
+		}
+		n := root
+		n.Value += v
+		for _, name := range strings.Split(stack, ";") {
+			children := index[n]
+			if children == nil {
+				children = map[string]*Node{}
+				index[n] = children
+			}
+			child := children[name]
+			if child == nil {
+				child = &Node{Name: name}
+				children[name] = child
+				n.Children = append(n.Children, child)
+			}
+			child.Value += v
+			n = child
+		}
+	}
+	sortChildren(root)
+	return root
+}
+
+func sortChildren(n *Node) {
+	sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+	for _, c := range n.Children {
+		sortChildren(c)
+	}
+}
+
+// WriteFolded writes the folded stacks a line each, sorted like flamegraph.pl expects.
+func WriteFolded(w io.Writer, folded map[string]int64) error {
+	stacks := make([]string, 0, len(folded))
+	for stack := range folded {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	bw := bufio.NewWriter(w)
+	for _, stack := range stacks {
+		fmt.Fprintf(bw, "%s %d\n", stack, folded[stack])
+	}
+	return bw.Flush()
+}
+
+// ReadFolded reads folded stacks written by WriteFolded or stackcollapse-go.pl, a stack per line
+// followed by a space and its value. The values of the same stack are added.
+func ReadFolded(r io.Reader) (map[string]int64, error) {
+	folded := map[string]int64{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		i := strings.LastIndexByte(text, ' ')
+		if i <= 0 {
+			return nil, fmt.Errorf("%w: line %d has no value", InvalidFoldedError, line)
+		}
+		v, err := strconv.ParseInt(text[i+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", InvalidFoldedError, line, err)
+		}
+		folded[strings.TrimSpace(text[:i])] += v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return folded, nil
+}
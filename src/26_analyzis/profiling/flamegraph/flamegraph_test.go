@@ -0,0 +1,123 @@
+package flamegraph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var folded = map[string]int64{
+	"runtime.main;main.main;main.fillMatrix":                    10,
+	"runtime.main;main.main;main.caculate":                      30,
+	"runtime.main;main.main;runtime.makeslice;runtime.mallocgc": 20,
+	"runtime.main;main.main":                                    5,
+	"runtime.gcBgMarkWorker;runtime.scanobject":                 60,
+	"runtime.mcall;main.(*T[...]).Less<int>":                    1,
+	"ignored":                                                   0,
+}
+
+func TestBuild(t *testing.T) {
+	root := Build(folded)
+	if root.Name != "all" || root.Value != 126 || root.Depth() != 5 || len(root.Children) != 3 {
+		t.Fatalf("unexpected %+v depth %d", root, root.Depth())
+	}
+	if root.Children[0].Name != "runtime.gcBgMarkWorker" || root.Children[1].Name != "runtime.main" || root.Children[2].Name != "runtime.mcall" {
+		t.Errorf("expected the children sorted by name, got %+v", root.Children)
+	}
+	main := root.Children[1].Children[0]
+	if main.Name != "main.main" || main.Value != 65 || main.Self() != 5 || len(main.Children) != 3 {
+		t.Errorf("unexpected %+v", main)
+	}
+	if main.Children[0].Name != "main.caculate" || main.Children[0].Self() != 30 {
+		t.Errorf("unexpected %+v", main.Children[0])
+	}
+}
+
+func TestFolded(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFolded(&buf, map[string]int64{"b;c": 2, "a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "a 1\nb;c 2\n" {
+		t.Errorf("unexpected %q", buf.String())
+	}
+
+	read, err := ReadFolded(strings.NewReader("a;b 1\n\n  a;b 2\nmain.(*T).String;fmt.Sprintf 7\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 2 || read["a;b"] != 3 || read["main.(*T).String;fmt.Sprintf"] != 7 {
+		t.Errorf("unexpected %v", read)
+	}
+	for _, s := range []string{"a;b", "a;b x", "a;b 1\nc 99999999999999999999"} {
+		if _, err := ReadFolded(strings.NewReader(s)); !errors.Is(err, InvalidFoldedError) {
+			t.Errorf("%q: expected InvalidFoldedError, got %v", s, err)
+		}
+	}
+}
+
+func render(t *testing.T, root *Node, o Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Render(&buf, root, o); err != nil {
+		t.Fatal(err)
+	}
+	// a browser refuses to show an SVG that isn't well-formed
+	d := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid XML: %v", err)
+		}
+	}
+	return buf.String()
+}
+
+func TestRender(t *testing.T) {
+	svg := render(t, Build(folded), Options{Title: "CPU <main>", Subtitle: "cpu.prof", Unit: "nanoseconds"})
+	if !strings.Contains(svg, `width="1200" height="162"`) || !strings.Contains(svg, ">CPU &lt;main&gt;</text>") {
+		t.Errorf("unexpected header\n%s", svg[:600])
+	}
+	if n := strings.Count(svg, `<g class="f"`); n != 11 {
+		t.Errorf("expected a frame per node, got %d", n)
+	}
+	if !strings.Contains(svg, `<title>main.caculate (30ns, 23.81%)</title>`) {
+		t.Error("expected the value and the percent in the title")
+	}
+	if !strings.Contains(svg, `<title>main.(*T[...]).Less&lt;int&gt; (1ns, 0.79%)</title>`) {
+		t.Error("expected the name escaped")
+	}
+	// the root spans the width, at the bottom
+	if !strings.Contains(svg, `data-x="0.00000000" data-w="1.00000000" data-d="0"><title>all (126ns, 100.00%)</title><rect x="10.0" y="112" width="1180.0"`) {
+		t.Error("unexpected root frame")
+	}
+	if !strings.Contains(svg, `fill="rgb(2`) || strings.Contains(svg, `fill="rgb(0,`) {
+		t.Error("expected the hot palette")
+	}
+
+	narrow := render(t, Build(folded), Options{Width: 200, MinWidth: 2, Unit: "bytes"})
+	if strings.Contains(narrow, "Less") || !strings.Contains(narrow, ">Flame Graph</text>") || !strings.Contains(narrow, `fill="rgb(0,`) {
+		t.Error("expected the narrow frame skipped, the default title and the mem palette")
+	}
+
+	empty := render(t, Build(nil), Options{})
+	if strings.Contains(empty, `<g class="f"`) {
+		t.Error("expected no frame of an empty graph")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if s := truncate("runtime.gcBgMarkWorker", 1000); s != "runtime.gcBgMarkWorker" {
+		t.Errorf("unexpected %q", s)
+	}
+	if s := truncate("runtime.gcBgMarkWorker", 100); s != "runtime.gcB.." {
+		t.Errorf("unexpected %q", s)
+	}
+	if s := truncate("runtime.gcBgMarkWorker", 20); s != "" {
+		t.Errorf("unexpected %q", s)
+	}
+}
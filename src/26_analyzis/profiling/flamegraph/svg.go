@@ -0,0 +1,254 @@
+package flamegraph
+
+import (
+	"bufio"
+	"fmt"
+	"go_learning/src/26_analyzis/profiling/profile"
+	"hash/fnv"
+	"io"
+	"strings"
+)
+
+const (
+	DefaultWidth = 1200
+	frameHeight  = 16
+	fontSize     = 12
+	// average width of a character in font size units, to truncate the names
+	fontWidth = 0.59
+	xPad      = 10
+	topPad    = fontSize * 4
+	bottomPad = fontSize*2 + 10
+)
+
+// Options of Render, the zero value renders a 1200px wide graph of counts.
+type Options struct {
+	// Title is Flame Graph by default
+	Title string
+	// Subtitle is shown under the title, e.g. the profile and the sample type
+	Subtitle string
+	// Width of the SVG in pixels
+	Width int
+	// Unit of the values, like nanoseconds or bytes, formats the values in the details
+	Unit string
+	// Palette is hot or mem, mem for bytes and hot for the others by default
+	Palette string
+	// MinWidth skips the frames narrower than these pixels, 0.1 by default
+	MinWidth float64
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#39;")
+
+// color is picked by the hash of the name, so a function has the same color in every graph.
+func color(palette, name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := h.Sum32()
+	v1, v2, v3 := float64(sum&0xff)/255, float64(sum>>8&0xff)/255, float64(sum>>16&0xff)/255
+	if palette == "mem" {
+		return fmt.Sprintf("rgb(%d,%d,%d)", 0, 190+int(50*v2), int(210*v1))
+	}
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+int(50*v3), int(230*v1), int(55*v2))
+}
+
+// truncate fits name in width pixels like the script does after a zoom.
+func truncate(name string, width float64) string {
+	n := int((width - 6) / (fontSize * fontWidth))
+	if n < 3 {
+		return ""
+	}
+	if len(name) <= n {
+		return name
+	}
+	return name[:n-2] + ".."
+}
+
+type renderer struct {
+	w     *bufio.Writer
+	o     Options
+	total int64
+	depth int
+	scale float64
+}
+
+// Render writes root as an interactive SVG flame graph.
+func Render(w io.Writer, root *Node, o Options) error {
+	if o.Title == "" {
+		o.Title = "Flame Graph"
+	}
+	if o.Width <= 0 {
+		o.Width = DefaultWidth
+	}
+	if o.Palette == "" {
+		o.Palette = "hot"
+		if o.Unit == "bytes" {
+			o.Palette = "mem"
+		}
+	}
+	if o.MinWidth <= 0 {
+		o.MinWidth = 0.1
+	}
+	r := &renderer{w: bufio.NewWriter(w), o: o, total: root.Value, depth: root.Depth()}
+	if r.total > 0 {
+		r.scale = float64(o.Width-2*xPad) / float64(r.total)
+	}
+	height := topPad + r.depth*frameHeight + bottomPad
+
+	fmt.Fprintf(r.w, `<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg" data-width="%d">
+<defs><linearGradient id="background" y1="0" y2="1" x1="0" x2="0"><stop stop-color="#eeeeee" offset="5%%"/><stop stop-color="#eeeeb0" offset="95%%"/></linearGradient></defs>
+<style>
+text { font-family: Verdana, sans-serif; font-size: %dpx; fill: rgb(0,0,0); }
+#title { font-size: %dpx; }
+g.f:hover rect { stroke: black; stroke-width: 0.5; cursor: pointer; }
+g.f text { pointer-events: none; }
+.parent { opacity: 0.5; }
+.hidden { display: none; }
+#reset, #search { cursor: pointer; }
+</style>
+<rect x="0" y="0" width="%d" height="%d" fill="url(#background)"/>
+<text id="title" x="%d" y="%d" text-anchor="middle">%s</text>
+<text x="%d" y="%d" text-anchor="middle">%s</text>
+<text id="reset" class="hidden" x="%d" y="%d">Reset Zoom</text>
+<text id="search" x="%d" y="%d" text-anchor="end">Search</text>
+<text id="matched" x="%d" y="%d" text-anchor="end"></text>
+<text id="details" x="%d" y="%d"> </text>
+`,
+		o.Width, height, o.Width, height, o.Width-2*xPad,
+		fontSize, fontSize+5,
+		o.Width, height,
+		o.Width/2, fontSize*2, escaper.Replace(o.Title),
+		o.Width/2, fontSize*3+4, escaper.Replace(o.Subtitle),
+		xPad, fontSize*2,
+		o.Width-xPad, fontSize*2,
+		o.Width-xPad, height-fontSize/2,
+		xPad, height-fontSize/2,
+	)
+	if r.total > 0 {
+		r.frame(root, 0, 0)
+	}
+	fmt.Fprintf(r.w, "<script><![CDATA[\nvar fontSize = %d, fontWidth = %g, xPad = %d;\n%s]]></script>\n</svg>\n", fontSize, fontWidth, xPad, script)
+	return r.w.Flush()
+}
+
+// frame writes n at depth starting at start, then its children on top of it.
+func (r *renderer) frame(n *Node, depth int, start int64) {
+	width := float64(n.Value) * r.scale
+	if width < r.o.MinWidth || n.Value <= 0 {
+		return
+	}
+	x := xPad + float64(start)*r.scale
+	y := topPad + (r.depth-1-depth)*frameHeight
+	percent := float64(n.Value) * 100 / float64(r.total)
+	fmt.Fprintf(r.w, `<g class="f" data-x="%.8f" data-w="%.8f" data-d="%d"><title>%s (%s, %.2f%%)</title>`+
+		`<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2" ry="2"/><text x="%.1f" y="%.1f">%s</text></g>`+"\n",
+		float64(start)/float64(r.total), float64(n.Value)/float64(r.total), depth,
+		escaper.Replace(n.Name), profile.FormatValue(n.Value, r.o.Unit), percent,
+		x, y, width, frameHeight-1, color(r.o.Palette, n.Name),
+		x+3, float64(y)+frameHeight-4.5, escaper.Replace(truncate(n.Name, width)))
+	for _, c := range n.Children {
+		r.frame(c, depth+1, start)
+		start += c.Value
+	}
+}
+
+// script zooms in a frame by a click and searches the frames by a regular expression.
+const script = `var svg = document.documentElement;
+var width = +svg.getAttribute("data-width");
+var frames = Array.prototype.slice.call(document.querySelectorAll("g.f"));
+var details = document.getElementById("details"), reset = document.getElementById("reset");
+var search = document.getElementById("search"), matched = document.getElementById("matched");
+var eps = 1e-9;
+
+function name(g) {
+	var t = g.querySelector("title").textContent;
+	return t.substring(0, t.lastIndexOf(" ("));
+}
+function attr(g, a) { return +g.getAttribute("data-" + a); }
+function truncate(s, w) {
+	var n = Math.floor((w - 6) / (fontSize * fontWidth));
+	if (n < 3) return "";
+	return s.length <= n ? s : s.substring(0, n - 2) + "..";
+}
+function fit(g, x, w) {
+	var rect = g.querySelector("rect"), text = g.querySelector("text");
+	rect.setAttribute("x", x.toFixed(1));
+	rect.setAttribute("width", w.toFixed(1));
+	text.setAttribute("x", (x + 3).toFixed(1));
+	text.textContent = truncate(name(g), w);
+}
+function zoom(z) {
+	var x0 = attr(z, "x"), w0 = attr(z, "w"), d0 = attr(z, "d");
+	frames.forEach(function (g) {
+		var x = attr(g, "x"), w = attr(g, "w"), d = attr(g, "d");
+		g.classList.remove("hidden", "parent");
+		if (d >= d0 && x >= x0 - eps && x + w <= x0 + w0 + eps) {
+			fit(g, xPad + (x - x0) / w0 * width, w / w0 * width);
+		} else if (d < d0 && x <= x0 + eps && x + w >= x0 + w0 - eps) {
+			g.classList.add("parent");
+			fit(g, xPad, width);
+		} else {
+			g.classList.add("hidden");
+		}
+	});
+	reset.classList.toggle("hidden", d0 == 0);
+}
+function unzoom() {
+	zoom(frames[0]);
+}
+function clearSearch() {
+	frames.forEach(function (g) {
+		var rect = g.querySelector("rect");
+		if (rect.hasAttribute("data-fill")) {
+			rect.setAttribute("fill", rect.getAttribute("data-fill"));
+			rect.removeAttribute("data-fill");
+		}
+	});
+	matched.textContent = "";
+	search.textContent = "Search";
+}
+function find(term) {
+	var re;
+	try { re = new RegExp(term); } catch (e) { alert(e); return; }
+	clearSearch();
+	var spans = [];
+	frames.forEach(function (g) {
+		if (!re.test(name(g))) return;
+		var rect = g.querySelector("rect");
+		rect.setAttribute("data-fill", rect.getAttribute("fill"));
+		rect.setAttribute("fill", "rgb(230,0,230)");
+		spans.push([attr(g, "x"), attr(g, "x") + attr(g, "w")]);
+	});
+	// a matched frame on top of another matched frame is counted once
+	spans.sort(function (a, b) { return a[0] - b[0]; });
+	var total = 0, end = 0;
+	spans.forEach(function (s) {
+		if (s[1] <= end) return;
+		total += s[1] - Math.max(s[0], end);
+		end = s[1];
+	});
+	matched.textContent = "Matched: " + (total * 100).toFixed(2) + "%";
+	search.textContent = "Reset Search";
+}
+
+svg.addEventListener("click", function (e) {
+	var g = e.target.parentNode;
+	if (e.target === reset) unzoom();
+	else if (e.target === search) {
+		if (matched.textContent) clearSearch();
+		else { var term = prompt("Search (regular expression)", ""); if (term) find(term); }
+	} else if (g.classList && g.classList.contains("f")) zoom(g);
+});
+svg.addEventListener("mouseover", function (e) {
+	var g = e.target.parentNode;
+	if (g.classList && g.classList.contains("f")) details.textContent = g.querySelector("title").textContent;
+});
+svg.addEventListener("mouseout", function () { details.textContent = " "; });
+window.addEventListener("keydown", function (e) {
+	if (e.key === "Escape") unzoom();
+	if ((e.ctrlKey || e.metaKey) && e.key === "f") {
+		e.preventDefault();
+		var term = prompt("Search (regular expression)", "");
+		if (term) find(term);
+	}
+});
+`
@@ -0,0 +1,311 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/*
+	比較優化前後的兩個 profile（例如 processRequestOld 與 processRequest 的 cpu.prof），
+	算出每個 function 的 flat 與 cum 差多少，依差距排序：
+
+	report, err := profile.Diff(before, after, profile.Options{SampleType: "cpu"})
+	report.WriteText(os.Stdout, 20, 5)
+	if len(report.Regressions(5)) > 0 { os.Exit(3) }
+
+	* flat：function 本身花的，cum：function 加上它呼叫的
+	* regression：By 的增加量超過 before 總量的 threshold%，例如 before 總共 1s、threshold 5，
+	  增加超過 50ms 的 function 就算，用總量而不是 function 自己的量，新出現的小 function 才不會變成無限大
+	* 兩個 CPU profile 的時間長度不同時用 Normalize，把 before 縮放到 after 的總量再比較
+*/
+
+var UnknownMetricError = errors.New("unknown metric")
+
+// Metric is what the report is ranked by.
+type Metric string
+
+const (
+	Flat Metric = "flat"
+	Cum  Metric = "cum"
+)
+
+type Options struct {
+	// SampleType like cpu or alloc_space, the default sample type of the after profile when empty
+	SampleType string
+	// Normalize scales the before profile to the total of the after profile
+	Normalize bool
+	// By ranks the functions and finds the regressions, Flat by default
+	By Metric
+}
+
+// Delta of a function, a missing function has a zero Stat.
+type Delta struct {
+	Name   string
+	Before Stat
+	After  Stat
+}
+
+func (d Delta) Flat() int64 {
+	return d.After.Flat - d.Before.Flat
+}
+
+func (d Delta) Cum() int64 {
+	return d.After.Cum - d.Before.Cum
+}
+
+// Of is the delta of the metric m.
+func (d Delta) Of(m Metric) int64 {
+	if m == Cum {
+		return d.Cum()
+	}
+	return d.Flat()
+}
+
+type Report struct {
+	SampleType  ValueType
+	By          Metric
+	Normalized  bool
+	BeforeTotal int64
+	AfterTotal  int64
+	// Deltas of the changed functions, the largest absolute delta of By first
+	Deltas []Delta
+}
+
+// Diff compares the functions of before and after.
+func Diff(before, after *Profile, o Options) (*Report, error) {
+	if o.By == "" {
+		o.By = Flat
+	}
+	if o.By != Flat && o.By != Cum {
+		return nil, fmt.Errorf("%w %q, should be %s or %s", UnknownMetricError, o.By, Flat, Cum)
+	}
+	ai, err := after.SampleIndex(o.SampleType)
+	if err != nil {
+		return nil, fmt.Errorf("after: %w", err)
+	}
+	sampleType := after.SampleType[ai]
+	bi, err := before.SampleIndex(sampleType.Type)
+	if err != nil {
+		return nil, fmt.Errorf("before: %w", err)
+	}
+	if unit := before.SampleType[bi].Unit; unit != sampleType.Unit {
+		return nil, fmt.Errorf("%s is in %s before and in %s after", sampleType.Type, unit, sampleType.Unit)
+	}
+
+	r := &Report{SampleType: sampleType, By: o.By, BeforeTotal: before.Total(bi), AfterTotal: after.Total(ai)}
+	beforeStats, afterStats := before.Stats(bi), after.Stats(ai)
+	if o.Normalize && r.BeforeTotal != 0 && r.AfterTotal != 0 {
+		r.Normalized = true
+		scale := float64(r.AfterTotal) / float64(r.BeforeTotal)
+		for name, s := range beforeStats {
+			beforeStats[name] = Stat{Flat: int64(math.Round(float64(s.Flat) * scale)), Cum: int64(math.Round(float64(s.Cum) * scale))}
+		}
+		r.BeforeTotal = r.AfterTotal
+	}
+
+	for name, b := range beforeStats {
+		r.Deltas = append(r.Deltas, Delta{Name: name, Before: b, After: afterStats[name]})
+	}
+	for name, a := range afterStats {
+		if _, ok := beforeStats[name]; !ok {
+			r.Deltas = append(r.Deltas, Delta{Name: name, After: a})
+		}
+	}
+	changed := r.Deltas[:0]
+	for _, d := range r.Deltas {
+		if d.Flat() != 0 || d.Cum() != 0 {
+			changed = append(changed, d)
+		}
+	}
+	r.Deltas = changed
+	sort.Slice(r.Deltas, func(i, j int) bool {
+		di, dj := abs(r.Deltas[i].Of(o.By)), abs(r.Deltas[j].Of(o.By))
+		if di != dj {
+			return di > dj
+		}
+		return r.Deltas[i].Name < r.Deltas[j].Name
+	})
+	return r, nil
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Percent is v in percent of the before total, or of the after total when before is empty.
+func (r *Report) Percent(v int64) float64 {
+	total := r.BeforeTotal
+	if total == 0 {
+		total = r.AfterTotal
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(v) * 100 / float64(total)
+}
+
+// Regressions are the functions whose By grew more than threshold percent of the total, threshold <= 0 finds none.
+func (r *Report) Regressions(threshold float64) []Delta {
+	if threshold <= 0 {
+		return nil
+	}
+	var regressions []Delta
+	for _, d := range r.Deltas {
+		if v := d.Of(r.By); v > 0 && r.Percent(v) > threshold {
+			regressions = append(regressions, d)
+		}
+	}
+	return regressions
+}
+
+// Format is a value of the sample type, see FormatValue.
+func (r *Report) Format(v int64) string {
+	return FormatValue(v, r.SampleType.Unit)
+}
+
+func (r *Report) formatDelta(v int64) string {
+	if v > 0 {
+		return "+" + r.Format(v)
+	}
+	return r.Format(v)
+}
+
+// FormatValue formats v in the unit of a sample type, e.g. 120ms for nanoseconds, 1.50MB for bytes.
+func FormatValue(v int64, unit string) string {
+	if v == 0 {
+		return "0"
+	}
+	switch unit {
+	case "nanoseconds":
+		// rounding below a millisecond would show a 30ns frame as 0s
+		d := time.Duration(v)
+		if abs(v) >= int64(time.Millisecond) {
+			d = d.Round(time.Microsecond)
+		}
+		return d.String()
+	case "bytes":
+		f, sign := float64(v), ""
+		if f < 0 {
+			f, sign = -f, "-"
+		}
+		for _, u := range []string{"B", "kB", "MB", "GB"} {
+			if f < 1024 || u == "GB" {
+				if u == "B" {
+					return sign + strconv.FormatInt(int64(f), 10) + u
+				}
+				return sign + strconv.FormatFloat(f, 'f', 2, 64) + u
+			}
+			f /= 1024
+		}
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func (r *Report) header() string {
+	s := fmt.Sprintf("Type: %s, ranked by %s delta", r.SampleType, r.By)
+	if r.Normalized {
+		s += ", before normalized to the after total"
+	}
+	return s
+}
+
+func (r *Report) totals() string {
+	delta := r.AfterTotal - r.BeforeTotal
+	return fmt.Sprintf("Total: %s → %s (%s, %+.2f%%)", r.Format(r.BeforeTotal), r.Format(r.AfterTotal), r.formatDelta(delta), r.Percent(delta))
+}
+
+// row is the columns of a delta, the same in the text and the markdown report
+func (r *Report) row(d Delta) []string {
+	return []string{
+		r.Format(d.Before.Flat), r.Format(d.After.Flat), r.formatDelta(d.Flat()),
+		r.Format(d.Before.Cum), r.Format(d.After.Cum), r.formatDelta(d.Cum()),
+		fmt.Sprintf("%+.2f%%", r.Percent(d.Of(r.By))),
+	}
+}
+
+var columns = []string{"flat before", "flat after", "flat Δ", "cum before", "cum after", "cum Δ", "Δ%"}
+
+// top is the first n deltas, all of them when n <= 0.
+func (r *Report) top(n int) []Delta {
+	if n <= 0 || n > len(r.Deltas) {
+		return r.Deltas
+	}
+	return r.Deltas[:n]
+}
+
+func isRegression(regressions []Delta, d Delta) bool {
+	for _, reg := range regressions {
+		if reg.Name == d.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the first top deltas as aligned columns, the regressions beyond threshold percent marked with !.
+func (r *Report) WriteText(w io.Writer, top int, threshold float64) error {
+	regressions := r.Regressions(threshold)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, r.header())
+	fmt.Fprintln(tw, r.totals())
+	fmt.Fprintln(tw, " \t"+strings.Join(columns, "\t")+"\t  function")
+	for _, d := range r.top(top) {
+		mark := " "
+		if isRegression(regressions, d) {
+			mark = "!"
+		}
+		fmt.Fprintln(tw, mark+"\t"+strings.Join(r.row(d), "\t")+"\t  "+d.Name)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return r.writeSummary(w, regressions, threshold, "")
+}
+
+// WriteMarkdown writes the first top deltas as a markdown table, e.g. for a pull request comment.
+func (r *Report) WriteMarkdown(w io.Writer, top int, threshold float64) error {
+	regressions := r.Regressions(threshold)
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n\n%s\n\n", r.header(), r.totals())
+	b.WriteString("| function | " + strings.Join(columns, " | ") + " |\n")
+	b.WriteString("|---" + strings.Repeat("|--:", len(columns)) + "|\n")
+	for _, d := range r.top(top) {
+		name := "`" + d.Name + "`"
+		if isRegression(regressions, d) {
+			name = "**" + name + "** ⚠"
+		}
+		b.WriteString("| " + name + " | " + strings.Join(r.row(d), " | ") + " |\n")
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	return r.writeSummary(w, regressions, threshold, "\n")
+}
+
+func (r *Report) writeSummary(w io.Writer, regressions []Delta, threshold float64, prefix string) error {
+	if threshold <= 0 {
+		return nil
+	}
+	if len(regressions) == 0 {
+		_, err := fmt.Fprintf(w, "%sNo function regressed more than %.2f%%.\n", prefix, threshold)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s%d function(s) regressed more than %.2f%% by %s:\n", prefix, len(regressions), threshold, r.By)
+	for _, d := range regressions {
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "- %s %s (%+.2f%%)\n", d.Name, r.formatDelta(d.Of(r.By)), r.Percent(d.Of(r.By)))
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// cpuProfile has a sample of value ms milliseconds per stack, like "main.leaf main.main" leaf first.
+func cpuProfile(stacks map[string]int64) *Profile {
+	p := &Profile{SampleType: []ValueType{{"samples", "count"}, {"cpu", "nanoseconds"}}}
+	functions := map[string]*Function{}
+	for stack, ms := range stacks {
+		s := &Sample{Value: []int64{ms / 10, ms * 1000000}}
+		for _, name := range strings.Fields(stack) {
+			f := functions[name]
+			if f == nil {
+				f = &Function{ID: uint64(len(functions) + 1), Name: name}
+				functions[name] = f
+				p.Function = append(p.Function, f)
+			}
+			l := &Location{ID: uint64(len(p.Location) + 1), Line: []Line{{Function: f}}}
+			p.Location = append(p.Location, l)
+			s.Location = append(s.Location, l)
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	return p
+}
+
+func TestDiff(t *testing.T) {
+	before := cpuProfile(map[string]int64{
+		"main.processRequestOld main.main":                       600,
+		"runtime.concatstrings main.processRequestOld main.main": 300,
+		"runtime.mallocgc main.main":                             100,
+	})
+	after := cpuProfile(map[string]int64{
+		"main.processRequest main.main":                                200,
+		"strings.(*Builder).WriteString main.processRequest main.main": 50,
+		"runtime.mallocgc main.main":                                   160,
+	})
+	r, err := Diff(before, after, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SampleType.Type != "cpu" || r.By != Flat || r.BeforeTotal != 1000000000 || r.AfterTotal != 410000000 {
+		t.Errorf("unexpected %+v", r)
+	}
+	var names []string
+	for _, d := range r.Deltas {
+		names = append(names, d.Name)
+	}
+	// main.main is only in the cum
+	expected := "main.processRequestOld runtime.concatstrings main.processRequest runtime.mallocgc strings.(*Builder).WriteString main.main"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("unexpected order %v", names)
+	}
+	if d := r.Deltas[0]; d.Flat() != -600000000 || d.Cum() != -900000000 || d.After != (Stat{}) {
+		t.Errorf("unexpected %+v", d)
+	}
+
+	regressions := r.Regressions(5)
+	if len(regressions) != 2 || regressions[0].Name != "main.processRequest" || regressions[1].Name != "runtime.mallocgc" {
+		t.Errorf("unexpected regressions %+v", regressions)
+	}
+	// 60ms of the 1s total
+	if regressions := r.Regressions(6); len(regressions) != 1 {
+		t.Errorf("unexpected regressions %+v", regressions)
+	}
+	if r.Regressions(0) != nil {
+		t.Error("a zero threshold finds no regression")
+	}
+
+	byCum, err := Diff(before, after, Options{By: Cum, SampleType: "samples"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byCum.SampleType.Unit != "count" || byCum.Deltas[0].Name != "main.processRequestOld" || byCum.Deltas[1].Name != "main.main" {
+		t.Errorf("unexpected %+v", byCum.Deltas[:2])
+	}
+
+	if _, err := Diff(before, after, Options{By: "total"}); !errors.Is(err, UnknownMetricError) {
+		t.Errorf("expected UnknownMetricError, got %v", err)
+	}
+	if _, err := Diff(before, after, Options{SampleType: "inuse_space"}); !errors.Is(err, UnknownSampleTypeError) {
+		t.Errorf("expected UnknownSampleTypeError, got %v", err)
+	}
+}
+
+func TestDiffNormalize(t *testing.T) {
+	// twice the duration, the same proportions
+	before := cpuProfile(map[string]int64{"main.a main.main": 100, "main.b main.main": 300})
+	after := cpuProfile(map[string]int64{"main.a main.main": 250, "main.b main.main": 550})
+	r, err := Diff(before, after, Options{Normalize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Normalized || r.BeforeTotal != r.AfterTotal {
+		t.Errorf("unexpected %+v", r)
+	}
+	// main.a is 50ms more than its 25% of 800ms
+	if len(r.Deltas) != 2 || r.Deltas[0].Name != "main.a" || r.Deltas[0].Flat() != 50000000 || r.Deltas[1].Flat() != -50000000 {
+		t.Errorf("unexpected %+v", r.Deltas)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	before := cpuProfile(map[string]int64{"main.a main.main": 100, "main.b main.main": 100})
+	after := cpuProfile(map[string]int64{"main.a main.main": 150, "main.c main.main": 20})
+	r, err := Diff(before, after, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text bytes.Buffer
+	if err := r.WriteText(&text, 2, 10); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	if len(lines) != 7 || !strings.Contains(lines[1], "200ms → 170ms (-30ms, -15.00%)") {
+		t.Fatalf("unexpected\n%s", text.String())
+	}
+	if !strings.HasSuffix(lines[3], "0  -100ms  -50.00%  main.b") || strings.HasPrefix(strings.TrimSpace(lines[3]), "!") {
+		t.Errorf("expected main.b first, got %q", lines[3])
+	}
+	if !strings.HasPrefix(strings.TrimSpace(lines[4]), "!") || !strings.HasSuffix(lines[4], "+50ms  +25.00%  main.a") {
+		t.Errorf("expected main.a to be a regression, got %q", lines[4])
+	}
+	if lines[5] != "1 function(s) regressed more than 10.00% by flat:" || lines[6] != "- main.a +50ms (+25.00%)" {
+		t.Errorf("unexpected summary\n%s", text.String())
+	}
+
+	var md bytes.Buffer
+	if err := r.WriteMarkdown(&md, 0, 30); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.String(), "| `main.a` | 100ms | 150ms | +50ms | 100ms | 150ms | +50ms | +25.00% |") ||
+		!strings.Contains(md.String(), "| `main.c` | 0 | 20ms | +20ms |") ||
+		!strings.HasSuffix(md.String(), "\nNo function regressed more than 30.00%.\n") {
+		t.Errorf("unexpected\n%s", md.String())
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	for _, c := range []struct {
+		v        int64
+		unit     string
+		expected string
+	}{
+		{0, "bytes", "0"},
+		{512, "bytes", "512B"},
+		{-1536, "bytes", "-1.50kB"},
+		{3 << 30, "bytes", "3.00GB"},
+		{1234567, "nanoseconds", "1.235ms"},
+		{30, "nanoseconds", "30ns"},
+		{-1500, "nanoseconds", "-1.5µs"},
+		{42, "count", "42"},
+	} {
+		if s := FormatValue(c.v, c.unit); s != c.expected {
+			t.Errorf("%d %s: expected %s, got %s", c.v, c.unit, c.expected, s)
+		}
+	}
+}
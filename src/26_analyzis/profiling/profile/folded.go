@@ -0,0 +1,29 @@
+package profile
+
+import "strings"
+
+/*
+	folded stacks 是 flamegraph.pl 的輸入格式，一個 call stack 一行，從 root 到 leaf 用 ; 隔開，最後是值：
+		runtime.main;main.main;main.fillMatrix 10000000
+		runtime.main;main.main;main.caculate 30000000
+	相同的 stack 會合併成一行，寫檔與讀檔在 flamegraph package（flamegraph.WriteFolded、flamegraph.ReadFolded）
+*/
+
+// Folded are the values of the sample type at index by folded stack, root first and separated by ;.
+// A ; in a function name is replaced by :, so it doesn't split the frame.
+func (p *Profile) Folded(index int) map[string]int64 {
+	folded := map[string]int64{}
+	for _, s := range p.Sample {
+		v := s.Value[index]
+		if v == 0 {
+			continue
+		}
+		stack := s.Stack()
+		frames := make([]string, len(stack))
+		for i, name := range stack {
+			frames[len(stack)-1-i] = strings.ReplaceAll(name, ";", ":")
+		}
+		folded[strings.Join(frames, ";")] += v
+	}
+	return folded
+}
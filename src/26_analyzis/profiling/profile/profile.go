@@ -0,0 +1,489 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+/*
+	讀 pprof 的 profile 檔案（profile.proto，https://github.com/google/pprof/blob/main/proto/profile.proto），
+	不依賴 github.com/google/pprof：
+
+	* 檔案通常是 gzip 過的 protobuf，runtime/pprof、net/http/pprof、go test -cpuprofile 寫的都是
+	* 一個 sample 是一個 call stack 加上幾個值，值的意義由 SampleType 決定，例如
+		cpu profile：samples/count、cpu/nanoseconds
+		heap profile：alloc_objects/count、alloc_space/bytes、inuse_objects/count、inuse_space/bytes
+	* Sample.Location[0] 是最裡面（leaf）的位置，一個 Location 因為 inline 可能有好幾個 Line，
+	  Line[0] 是被 inline 進來的那個 function，最後一個是呼叫它的 function
+	* 所有字串都放在 string_table，其他欄位只記 index
+
+	只讀分析需要的欄位，mapping 與 label 會被略過。
+
+	p, err := profile.Open("cpu.prof")
+	i, err := p.SampleIndex("cpu")
+	for name, s := range p.Stats(i) { fmt.Println(name, s.Flat, s.Cum) }
+*/
+
+var UnknownSampleTypeError = errors.New("unknown sample type")
+
+// ValueType is the meaning of a value, like cpu in nanoseconds.
+type ValueType struct {
+	Type string
+	Unit string
+}
+
+func (v ValueType) String() string {
+	return v.Type + "/" + v.Unit
+}
+
+type Profile struct {
+	SampleType []ValueType
+	// DefaultSampleType is the Type of the value pprof shows by default, may be empty
+	DefaultSampleType string
+	Sample            []*Sample
+	Location          []*Location
+	Function          []*Function
+	PeriodType        ValueType
+	Period            int64
+	TimeNanos         int64
+	DurationNanos     int64
+	Comments          []string
+}
+
+// Sample is a call stack with a value per SampleType.
+type Sample struct {
+	// Location[0] is the leaf
+	Location []*Location
+	Value    []int64
+}
+
+type Location struct {
+	ID      uint64
+	Address uint64
+	// Line[0] is the innermost inlined function
+	Line []Line
+}
+
+type Line struct {
+	Function *Function
+	Line     int64
+}
+
+type Function struct {
+	ID         uint64
+	Name       string
+	SystemName string
+	Filename   string
+	StartLine  int64
+}
+
+// Open parses the profile file at path.
+func Open(path string) (*Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse parses a profile, gzip compressed or not.
+func Parse(r io.Reader) (*Profile, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", MalformedError, err)
+		}
+		if data, err = ioutil.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("%w: %v", MalformedError, err)
+		}
+	}
+	return ParseData(data)
+}
+
+// the messages as they are encoded, the strings are indexes of the string table
+type rawValueType struct{ typ, unit int64 }
+
+type rawSample struct {
+	locations []uint64
+	values    []uint64
+}
+
+type rawLine struct {
+	function uint64
+	line     int64
+}
+
+type rawLocation struct {
+	id, address uint64
+	lines       []rawLine
+}
+
+type rawFunction struct {
+	id                         uint64
+	name, systemName, filename int64
+	startLine                  int64
+}
+
+type rawProfile struct {
+	sampleTypes       []rawValueType
+	samples           []rawSample
+	locations         []rawLocation
+	functions         []rawFunction
+	strings           []string
+	periodType        rawValueType
+	period            int64
+	timeNanos         int64
+	durationNanos     int64
+	comments          []uint64
+	defaultSampleType int64
+}
+
+// ParseData parses an uncompressed profile.
+func ParseData(data []byte) (*Profile, error) {
+	var raw rawProfile
+	if err := raw.decode(data); err != nil {
+		return nil, err
+	}
+	return raw.resolve()
+}
+
+func (raw *rawProfile) decode(data []byte) error {
+	d := &decoder{data: data}
+	for {
+		ok, err := d.next()
+		if err != nil || !ok {
+			return err
+		}
+		var m []byte
+		switch d.field {
+		case 1, 11:
+			if m, err = d.message(); err == nil {
+				var v rawValueType
+				if v, err = decodeValueType(m); d.field == 1 {
+					raw.sampleTypes = append(raw.sampleTypes, v)
+				} else {
+					raw.periodType = v
+				}
+			}
+		case 2:
+			if m, err = d.message(); err == nil {
+				var s rawSample
+				s, err = decodeSample(m)
+				raw.samples = append(raw.samples, s)
+			}
+		case 4:
+			if m, err = d.message(); err == nil {
+				var l rawLocation
+				l, err = decodeLocation(m)
+				raw.locations = append(raw.locations, l)
+			}
+		case 5:
+			if m, err = d.message(); err == nil {
+				var f rawFunction
+				f, err = decodeFunction(m)
+				raw.functions = append(raw.functions, f)
+			}
+		case 6:
+			if m, err = d.message(); err == nil {
+				raw.strings = append(raw.strings, string(m))
+			}
+		case 9:
+			raw.timeNanos, err = d.int64()
+		case 10:
+			raw.durationNanos, err = d.int64()
+		case 12:
+			raw.period, err = d.int64()
+		case 13:
+			raw.comments, err = d.repeated(raw.comments)
+		case 14:
+			raw.defaultSampleType, err = d.int64()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func decodeValueType(data []byte) (v rawValueType, err error) {
+	d := &decoder{data: data}
+	for {
+		ok, err := d.next()
+		if err != nil || !ok {
+			return v, err
+		}
+		switch d.field {
+		case 1:
+			v.typ, err = d.int64()
+		case 2:
+			v.unit, err = d.int64()
+		}
+		if err != nil {
+			return v, err
+		}
+	}
+}
+
+func decodeSample(data []byte) (s rawSample, err error) {
+	d := &decoder{data: data}
+	for {
+		ok, err := d.next()
+		if err != nil || !ok {
+			return s, err
+		}
+		switch d.field {
+		case 1:
+			s.locations, err = d.repeated(s.locations)
+		case 2:
+			s.values, err = d.repeated(s.values)
+		}
+		if err != nil {
+			return s, err
+		}
+	}
+}
+
+func decodeLocation(data []byte) (l rawLocation, err error) {
+	d := &decoder{data: data}
+	for {
+		ok, err := d.next()
+		if err != nil || !ok {
+			return l, err
+		}
+		switch d.field {
+		case 1:
+			l.id, err = d.uint64()
+		case 3:
+			l.address, err = d.uint64()
+		case 4:
+			var m []byte
+			if m, err = d.message(); err == nil {
+				var line rawLine
+				line, err = decodeLine(m)
+				l.lines = append(l.lines, line)
+			}
+		}
+		if err != nil {
+			return l, err
+		}
+	}
+}
+
+func decodeLine(data []byte) (l rawLine, err error) {
+	d := &decoder{data: data}
+	for {
+		ok, err := d.next()
+		if err != nil || !ok {
+			return l, err
+		}
+		switch d.field {
+		case 1:
+			l.function, err = d.uint64()
+		case 2:
+			l.line, err = d.int64()
+		}
+		if err != nil {
+			return l, err
+		}
+	}
+}
+
+func decodeFunction(data []byte) (f rawFunction, err error) {
+	d := &decoder{data: data}
+	for {
+		ok, err := d.next()
+		if err != nil || !ok {
+			return f, err
+		}
+		switch d.field {
+		case 1:
+			f.id, err = d.uint64()
+		case 2:
+			f.name, err = d.int64()
+		case 3:
+			f.systemName, err = d.int64()
+		case 4:
+			f.filename, err = d.int64()
+		case 5:
+			f.startLine, err = d.int64()
+		}
+		if err != nil {
+			return f, err
+		}
+	}
+}
+
+// resolve replaces the ids and the string indexes by what they refer to.
+func (raw *rawProfile) resolve() (*Profile, error) {
+	if len(raw.strings) == 0 || raw.strings[0] != "" {
+		return nil, fmt.Errorf("%w: the string table should start with an empty string", MalformedError)
+	}
+	var err error
+	str := func(i int64) string {
+		if i < 0 || i >= int64(len(raw.strings)) {
+			if err == nil {
+				err = fmt.Errorf("%w: string index %d out of range", MalformedError, i)
+			}
+			return ""
+		}
+		return raw.strings[i]
+	}
+	valueType := func(v rawValueType) ValueType {
+		return ValueType{Type: str(v.typ), Unit: str(v.unit)}
+	}
+
+	p := &Profile{
+		DefaultSampleType: str(raw.defaultSampleType),
+		PeriodType:        valueType(raw.periodType),
+		Period:            raw.period,
+		TimeNanos:         raw.timeNanos,
+		DurationNanos:     raw.durationNanos,
+	}
+	for _, v := range raw.sampleTypes {
+		p.SampleType = append(p.SampleType, valueType(v))
+	}
+	for _, c := range raw.comments {
+		p.Comments = append(p.Comments, str(int64(c)))
+	}
+
+	functions := map[uint64]*Function{}
+	for _, rf := range raw.functions {
+		if rf.id == 0 || functions[rf.id] != nil {
+			return nil, fmt.Errorf("%w: invalid or duplicate function id %d", MalformedError, rf.id)
+		}
+		f := &Function{ID: rf.id, Name: str(rf.name), SystemName: str(rf.systemName), Filename: str(rf.filename), StartLine: rf.startLine}
+		functions[f.ID] = f
+		p.Function = append(p.Function, f)
+	}
+
+	locations := map[uint64]*Location{}
+	for _, rl := range raw.locations {
+		if rl.id == 0 || locations[rl.id] != nil {
+			return nil, fmt.Errorf("%w: invalid or duplicate location id %d", MalformedError, rl.id)
+		}
+		l := &Location{ID: rl.id, Address: rl.address}
+		for _, line := range rl.lines {
+			f := functions[line.function]
+			if f == nil {
+				return nil, fmt.Errorf("%w: location %d refers to the unknown function %d", MalformedError, rl.id, line.function)
+			}
+			l.Line = append(l.Line, Line{Function: f, Line: line.line})
+		}
+		locations[l.ID] = l
+		p.Location = append(p.Location, l)
+	}
+
+	for _, rs := range raw.samples {
+		if len(rs.values) != len(p.SampleType) {
+			return nil, fmt.Errorf("%w: a sample has %d values for %d sample types", MalformedError, len(rs.values), len(p.SampleType))
+		}
+		s := &Sample{Value: make([]int64, len(rs.values))}
+		for i, v := range rs.values {
+			s.Value[i] = int64(v)
+		}
+		for _, id := range rs.locations {
+			l := locations[id]
+			if l == nil {
+				return nil, fmt.Errorf("%w: a sample refers to the unknown location %d", MalformedError, id)
+			}
+			s.Location = append(s.Location, l)
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SampleIndex is the index of the values of the sample type name in Sample.Value.
+// An empty name is the default sample type, the last one when the profile has no default, like pprof.
+func (p *Profile) SampleIndex(name string) (int, error) {
+	if len(p.SampleType) == 0 {
+		return 0, fmt.Errorf("%w: the profile has no sample type", UnknownSampleTypeError)
+	}
+	if name == "" {
+		name = p.DefaultSampleType
+	}
+	if name == "" {
+		return len(p.SampleType) - 1, nil
+	}
+	var types []string
+	for i, t := range p.SampleType {
+		if t.Type == name {
+			return i, nil
+		}
+		types = append(types, t.Type)
+	}
+	return 0, fmt.Errorf("%w %q, the profile has %v", UnknownSampleTypeError, name, types)
+}
+
+// Stack is the function names of the call stack of s, the leaf first, the inlined functions included.
+// A location without symbols is its address like 0x4a5b6c.
+func (s *Sample) Stack() []string {
+	var stack []string
+	for _, l := range s.Location {
+		if len(l.Line) == 0 {
+			stack = append(stack, fmt.Sprintf("%#x", l.Address))
+			continue
+		}
+		for _, line := range l.Line {
+			stack = append(stack, line.Function.Name)
+		}
+	}
+	return stack
+}
+
+// Stat is the value of a function, Flat in the function itself, Cum in the function and what it calls.
+type Stat struct {
+	Flat int64
+	Cum  int64
+}
+
+// Stats are the flat and cum values of every function of the sample type at index.
+// A recursive function counts once in the cum of a sample.
+func (p *Profile) Stats(index int) map[string]Stat {
+	stats := map[string]Stat{}
+	for _, s := range p.Sample {
+		v := s.Value[index]
+		if v == 0 {
+			continue
+		}
+		stack := s.Stack()
+		seen := make(map[string]bool, len(stack))
+		for i, name := range stack {
+			st := stats[name]
+			if i == 0 {
+				st.Flat += v
+			}
+			if !seen[name] {
+				seen[name] = true
+				st.Cum += v
+			}
+			stats[name] = st
+		}
+	}
+	return stats
+}
+
+// Total is the sum of the values of the sample type at index.
+func (p *Profile) Total(index int) int64 {
+	var total int64
+	for _, s := range p.Sample {
+		total += s.Value[index]
+	}
+	return total
+}
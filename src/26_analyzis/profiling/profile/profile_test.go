@@ -0,0 +1,186 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	// written by go test -cpuprofile, see tools/README.md
+	p, err := Open("../../tools/cpu.prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.SampleType) != 2 || p.SampleType[1] != (ValueType{"cpu", "nanoseconds"}) || p.Period != 10000000 {
+		t.Errorf("unexpected %v %d", p.SampleType, p.Period)
+	}
+	i, err := p.SampleIndex("")
+	if err != nil || i != 1 {
+		t.Fatalf("expected the last sample type, got %d %v", i, err)
+	}
+	if total := p.Total(i); total != 280000000 {
+		t.Errorf("expected 280ms, got %d", total)
+	}
+	stats := p.Stats(i)
+	if s := stats["main.fillMatrix"]; s.Flat != 10000000 || s.Cum != 10000000 {
+		t.Errorf("unexpected main.fillMatrix %+v", s)
+	}
+	if s := stats["main.main"]; s.Flat != 0 || s.Cum != 40000000 {
+		t.Errorf("unexpected main.main %+v", s)
+	}
+
+	if _, err := p.SampleIndex("alloc_space"); !errors.Is(err, UnknownSampleTypeError) {
+		t.Errorf("expected UnknownSampleTypeError, got %v", err)
+	}
+	if _, err := Open("nothing.prof"); err == nil {
+		t.Error("expected an error of a missing file")
+	}
+}
+
+//go:noinline
+func allocate() []byte {
+	return make([]byte, 1<<20)
+}
+
+var sink [][]byte
+
+func TestParseHeap(t *testing.T) {
+	old := runtime.MemProfileRate
+	runtime.MemProfileRate = 1
+	defer func() { runtime.MemProfileRate = old }()
+	for i := 0; i < 4; i++ {
+		sink = append(sink, allocate())
+	}
+	runtime.GC()
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	sink = nil
+
+	// gzip compressed and not
+	gz, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := ioutil.ReadAll(gz)
+	for _, data := range [][]byte{buf.Bytes(), raw} {
+		p, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		i, err := p.SampleIndex("alloc_space")
+		if err != nil || p.SampleType[i].Unit != "bytes" {
+			t.Fatalf("unexpected %v %v", p.SampleType, err)
+		}
+		var name string
+		for n := range p.Stats(i) {
+			if strings.HasSuffix(n, ".allocate") {
+				name = n
+			}
+		}
+		if s := p.Stats(i)[name]; s.Flat < 4<<20 {
+			t.Errorf("expected 4MB allocated by allocate, got %q %+v", name, s)
+		}
+	}
+}
+
+// a few helpers to encode a profile by hand, the runtime always packs the repeated fields
+func key(field, wire int) []byte {
+	return varint(uint64(field<<3 | wire))
+}
+
+func varint(u uint64) []byte {
+	var b []byte
+	for u >= 0x80 {
+		b = append(b, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(b, byte(u))
+}
+
+func field(n int, u uint64) []byte {
+	return append(key(n, wireVarint), varint(u)...)
+}
+
+func message(n int, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	return append(append(key(n, wireBytes), varint(uint64(len(body)))...), body...)
+}
+
+func handmade() []byte {
+	return bytes.Join([][]byte{
+		message(6), message(6, []byte("samples")), message(6, []byte("count")),
+		message(6, []byte("main.leaf")), message(6, []byte("main.inlined")), message(6, []byte("main.main")),
+		message(1, field(1, 1), field(2, 2)),
+		message(5, field(1, 1), field(2, 3)),
+		message(5, field(1, 2), field(2, 4)),
+		message(5, field(1, 3), field(2, 5)),
+		// location 2 is main.inlined inlined into main.main
+		message(4, field(1, 1), message(4, field(1, 1), field(2, 10))),
+		message(4, field(1, 2), message(4, field(1, 2)), message(4, field(1, 3))),
+		message(4, field(1, 3), field(3, 0x4a5b)),
+		// unpacked location ids
+		message(2, field(1, 1), field(1, 2), field(2, 5)),
+		message(2, field(1, 3), field(1, 2), field(2, 3)),
+		field(14, 1),
+	}, nil)
+}
+
+func TestParseData(t *testing.T) {
+	p, err := ParseData(handmade())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DefaultSampleType != "samples" || len(p.Sample) != 2 {
+		t.Fatalf("unexpected %+v", p)
+	}
+	if stack := strings.Join(p.Sample[0].Stack(), " "); stack != "main.leaf main.inlined main.main" {
+		t.Errorf("unexpected stack %q", stack)
+	}
+	if stack := strings.Join(p.Sample[1].Stack(), " "); stack != "0x4a5b main.inlined main.main" {
+		t.Errorf("unexpected stack %q", stack)
+	}
+	stats := p.Stats(0)
+	if s := stats["main.main"]; s.Flat != 0 || s.Cum != 8 {
+		t.Errorf("unexpected main.main %+v", s)
+	}
+	if s := stats["main.leaf"]; s.Flat != 5 || s.Cum != 5 {
+		t.Errorf("unexpected main.leaf %+v", s)
+	}
+}
+
+func TestFolded(t *testing.T) {
+	p, err := ParseData(handmade())
+	if err != nil {
+		t.Fatal(err)
+	}
+	folded := p.Folded(0)
+	if len(folded) != 2 || folded["main.main;main.inlined;main.leaf"] != 5 || folded["main.main;main.inlined;0x4a5b"] != 3 {
+		t.Errorf("unexpected %v", folded)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	data := handmade()
+	for name, data := range map[string][]byte{
+		"truncated":        data[:len(data)-1],
+		"cut in a string":  data[:10],
+		"no string table":  message(1, field(1, 0)),
+		"bad string":       append(append([]byte{}, data...), field(14, 99)...),
+		"unknown location": append(append([]byte{}, data...), message(2, field(1, 9), field(2, 1))...),
+		"missing value":    append(append([]byte{}, data...), message(2, field(1, 1))...),
+		"wrong wire type":  append(append([]byte{}, data...), message(9)...),
+		"not gzip":         {0x1f, 0x8b, 0x00},
+	} {
+		if _, err := Parse(bytes.NewReader(data)); !errors.Is(err, MalformedError) {
+			t.Errorf("%s: expected MalformedError, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+	profile.proto 是 protobuf，用到的 wire format 只有兩種：
+	* varint（wire type 0）：int64、uint64，每個 byte 的低 7 bit 是資料，最高 bit 表示後面還有
+	* length-delimited（wire type 2）：字串、嵌套的 message、packed 的 repeated 數字，先是一個 varint 長度
+	每個欄位前面是一個 varint 的 key：欄位編號 << 3 | wire type
+	其他的 wire type（fixed32、fixed64）profile.proto 沒用到，只需要能跳過
+*/
+
+var MalformedError = errors.New("malformed profile")
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// decoder reads the fields of a protobuf message one after another.
+type decoder struct {
+	data []byte
+	// of the current field
+	field int
+	wire  int
+	u     uint64
+	bytes []byte
+}
+
+func (d *decoder) varint() (uint64, error) {
+	var u uint64
+	for i := 0; i < 10; i++ {
+		if len(d.data) == 0 {
+			return 0, fmt.Errorf("%w: truncated varint", MalformedError)
+		}
+		b := d.data[0]
+		d.data = d.data[1:]
+		u |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return u, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: varint overflows 64 bits", MalformedError)
+}
+
+// next reads the next field into field, wire and u or bytes, false at the end of the message.
+func (d *decoder) next() (bool, error) {
+	if len(d.data) == 0 {
+		return false, nil
+	}
+	key, err := d.varint()
+	if err != nil {
+		return false, err
+	}
+	d.field, d.wire = int(key>>3), int(key&7)
+	if d.field == 0 {
+		return false, fmt.Errorf("%w: field number 0", MalformedError)
+	}
+	switch d.wire {
+	case wireVarint:
+		d.u, err = d.varint()
+		return err == nil, err
+	case wireBytes:
+		n, err := d.varint()
+		if err != nil {
+			return false, err
+		}
+		if n > uint64(len(d.data)) {
+			return false, fmt.Errorf("%w: field %d needs %d bytes, %d left", MalformedError, d.field, n, len(d.data))
+		}
+		d.bytes, d.data = d.data[:n], d.data[n:]
+		return true, nil
+	case wireFixed64, wireFixed32:
+		n := 8
+		if d.wire == wireFixed32 {
+			n = 4
+		}
+		if n > len(d.data) {
+			return false, fmt.Errorf("%w: truncated field %d", MalformedError, d.field)
+		}
+		d.data = d.data[n:]
+		return true, nil
+	}
+	return false, fmt.Errorf("%w: unsupported wire type %d of field %d", MalformedError, d.wire, d.field)
+}
+
+// int64 is the value of a varint field.
+func (d *decoder) int64() (int64, error) {
+	if d.wire != wireVarint {
+		return 0, d.wireError()
+	}
+	return int64(d.u), nil
+}
+
+// uint64 is the value of a varint field.
+func (d *decoder) uint64() (uint64, error) {
+	if d.wire != wireVarint {
+		return 0, d.wireError()
+	}
+	return d.u, nil
+}
+
+// message is the content of an embedded message or a string.
+func (d *decoder) message() ([]byte, error) {
+	if d.wire != wireBytes {
+		return nil, d.wireError()
+	}
+	return d.bytes, nil
+}
+
+// repeated appends a repeated varint field to s, it may be packed or one value per field.
+func (d *decoder) repeated(s []uint64) ([]uint64, error) {
+	switch d.wire {
+	case wireVarint:
+		return append(s, d.u), nil
+	case wireBytes:
+		packed := &decoder{data: d.bytes}
+		for len(packed.data) > 0 {
+			u, err := packed.varint()
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, u)
+		}
+		return s, nil
+	}
+	return nil, d.wireError()
+}
+
+func (d *decoder) wireError() error {
+	return fmt.Errorf("%w: unexpected wire type %d of field %d", MalformedError, d.wire, d.field)
+}
@@ -0,0 +1,265 @@
+package profiling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+	把 runtime/pprof 與 runtime/trace 包成一個 Session，在一段程式（workload）前後開始與結束：
+
+	files, err := profiling.Run(profiling.Config{
+		Kinds: []profiling.Kind{profiling.CPU, profiling.Heap, profiling.Block},
+		Dir:   "prof",
+		BlockProfileRate: 1,
+	}, func(ctx context.Context) error {
+		return doWork(ctx)
+	})
+	// $ go tool pprof prof/cpu.prof
+
+	* cpu、trace：Start 時開始記錄，Stop 時結束，期間的所有 goroutine 都會被記錄
+	* heap、allocs、goroutine、block、mutex：Stop 時寫出快照
+	  heap 與 allocs 是同一份資料，heap 預設看 inuse_space，allocs 預設看 alloc_space
+	* block、mutex 預設不取樣，要設定 BlockProfileRate / MutexProfileFraction 才有資料
+	* MemProfileRate 越小記錄越多的 allocation，也越慢，預設 512KB 取樣一次
+*/
+
+type Kind string
+
+const (
+	CPU       Kind = "cpu"
+	Heap      Kind = "heap"
+	Allocs    Kind = "allocs"
+	Goroutine Kind = "goroutine"
+	Block     Kind = "block"
+	Mutex     Kind = "mutex"
+	Trace     Kind = "trace"
+)
+
+// Kinds are all the kinds, in the order their files are written.
+var Kinds = []Kind{CPU, Trace, Heap, Allocs, Goroutine, Block, Mutex}
+
+var UnknownKindError = errors.New("unknown profile kind")
+var StoppedError = errors.New("profiling session already stopped")
+
+// ParseKinds parses a comma separated list like "cpu,heap", "all" is every kind.
+func ParseKinds(s string) ([]Kind, error) {
+	if strings.TrimSpace(s) == "all" {
+		return append([]Kind(nil), Kinds...), nil
+	}
+	var kinds []Kind
+	seen := map[Kind]bool{}
+	for _, part := range strings.Split(s, ",") {
+		k := Kind(strings.TrimSpace(part))
+		if k == "" || seen[k] {
+			continue
+		}
+		if !k.valid() {
+			return nil, fmt.Errorf("%w %q, should be one of %v", UnknownKindError, k, Kinds)
+		}
+		seen[k] = true
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
+
+func (k Kind) valid() bool {
+	for _, known := range Kinds {
+		if k == known {
+			return true
+		}
+	}
+	return false
+}
+
+// File is the name of the file of the kind, trace.out for the execution trace, <kind>.prof for the others.
+func (k Kind) File() string {
+	if k == Trace {
+		return "trace.out"
+	}
+	return string(k) + ".prof"
+}
+
+// Config of a Session, zero rates keep the rates of the runtime.
+type Config struct {
+	Kinds []Kind
+	// Dir is created when missing, the working directory when empty
+	Dir string
+	// Duration is how long Run lets the workload run, 0 for no limit
+	Duration time.Duration
+	// MemProfileRate is runtime.MemProfileRate, bytes allocated between samples
+	MemProfileRate int
+	// BlockProfileRate is runtime.SetBlockProfileRate, 1 records every blocking event
+	BlockProfileRate int
+	// MutexProfileFraction is runtime.SetMutexProfileFraction, 1 records every contention
+	MutexProfileFraction int
+}
+
+// Session is a profiling in progress.
+type Session struct {
+	config  Config
+	files   map[Kind]*os.File
+	stopped bool
+
+	oldMemRate       int
+	oldMutexFraction int
+}
+
+// Start creates the files of the kinds and starts the CPU profile and the trace.
+// Only one Session with cpu or trace may run at a time in a process.
+func Start(c Config) (*Session, error) {
+	for _, k := range c.Kinds {
+		if !k.valid() {
+			return nil, fmt.Errorf("%w %q", UnknownKindError, k)
+		}
+	}
+	if c.Dir == "" {
+		c.Dir = "."
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create profile directory: %w", err)
+	}
+
+	s := &Session{config: c, files: map[Kind]*os.File{}, oldMemRate: runtime.MemProfileRate, oldMutexFraction: -1}
+	for _, k := range c.Kinds {
+		f, err := os.Create(filepath.Join(c.Dir, k.File()))
+		if err != nil {
+			s.closeFiles()
+			return nil, fmt.Errorf("create %s profile: %w", k, err)
+		}
+		s.files[k] = f
+	}
+
+	if c.MemProfileRate > 0 {
+		runtime.MemProfileRate = c.MemProfileRate
+	}
+	if c.BlockProfileRate > 0 {
+		runtime.SetBlockProfileRate(c.BlockProfileRate)
+	}
+	if c.MutexProfileFraction > 0 {
+		s.oldMutexFraction = runtime.SetMutexProfileFraction(c.MutexProfileFraction)
+	}
+
+	if f := s.files[CPU]; f != nil {
+		if err := pprof.StartCPUProfile(f); err != nil {
+			s.restoreRates()
+			s.closeFiles()
+			return nil, fmt.Errorf("start CPU profile: %w", err)
+		}
+	}
+	if f := s.files[Trace]; f != nil {
+		if err := trace.Start(f); err != nil {
+			if s.files[CPU] != nil {
+				pprof.StopCPUProfile()
+			}
+			s.restoreRates()
+			s.closeFiles()
+			return nil, fmt.Errorf("start trace: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Stop stops the CPU profile and the trace, writes the other profiles, and returns the paths written.
+// Every profile is attempted, the first error is returned.
+func (s *Session) Stop() ([]string, error) {
+	if s.stopped {
+		return nil, StoppedError
+	}
+	s.stopped = true
+	defer s.restoreRates()
+
+	if s.files[CPU] != nil {
+		pprof.StopCPUProfile()
+	}
+	if s.files[Trace] != nil {
+		trace.Stop()
+	}
+
+	var firstErr error
+	for _, k := range Kinds {
+		f := s.files[k]
+		if f == nil {
+			continue
+		}
+		var err error
+		switch k {
+		case Heap, Allocs:
+			// the heap profile is as of the last GC, collect so it includes the latest allocations
+			runtime.GC()
+			err = pprof.Lookup(string(k)).WriteTo(f, 0)
+		case Goroutine, Block, Mutex:
+			err = pprof.Lookup(string(k)).WriteTo(f, 0)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("write %s profile: %w", k, err)
+		}
+	}
+	if err := s.closeFiles(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return s.paths(), firstErr
+}
+
+func (s *Session) paths() []string {
+	var paths []string
+	for _, f := range s.files {
+		paths = append(paths, f.Name())
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (s *Session) closeFiles() error {
+	var firstErr error
+	for k, f := range s.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("close %s profile: %w", k, err)
+		}
+	}
+	return firstErr
+}
+
+func (s *Session) restoreRates() {
+	c := s.config
+	if c.MemProfileRate > 0 {
+		runtime.MemProfileRate = s.oldMemRate
+	}
+	if c.BlockProfileRate > 0 {
+		// the old rate can't be read, 0 is the default
+		runtime.SetBlockProfileRate(0)
+	}
+	if c.MutexProfileFraction > 0 {
+		runtime.SetMutexProfileFraction(s.oldMutexFraction)
+	}
+}
+
+// Run profiles workload, its ctx is canceled after c.Duration.
+// The profiles are written even when workload fails, its error is returned first.
+func Run(c Config, workload func(ctx context.Context) error) ([]string, error) {
+	s, err := Start(c)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if c.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Duration)
+		defer cancel()
+	}
+	workErr := workload(ctx)
+	paths, err := s.Stop()
+	if workErr != nil {
+		return paths, fmt.Errorf("workload: %w", workErr)
+	}
+	return paths, err
+}
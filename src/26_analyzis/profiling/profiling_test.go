@@ -0,0 +1,104 @@
+package profiling
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseKinds(t *testing.T) {
+	kinds, err := ParseKinds("cpu, heap,cpu,,trace")
+	if err != nil || len(kinds) != 3 || kinds[0] != CPU || kinds[1] != Heap || kinds[2] != Trace {
+		t.Errorf("unexpected %v %v", kinds, err)
+	}
+	if kinds, err := ParseKinds("all"); err != nil || len(kinds) != len(Kinds) {
+		t.Errorf("unexpected %v %v", kinds, err)
+	}
+	if _, err := ParseKinds("cpu,memory"); !errors.Is(err, UnknownKindError) {
+		t.Errorf("expected UnknownKindError, got %v", err)
+	}
+}
+
+// contend makes the block and mutex profiles non-empty.
+func contend(ctx context.Context) error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				mu.Lock()
+				time.Sleep(time.Millisecond)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	oldMemRate := runtime.MemProfileRate
+	paths, err := Run(Config{
+		Kinds:                Kinds,
+		Dir:                  dir,
+		Duration:             50 * time.Millisecond,
+		MemProfileRate:       1,
+		BlockProfileRate:     1,
+		MutexProfileFraction: 1,
+	}, contend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != len(Kinds) {
+		t.Errorf("expected a file per kind, got %v", paths)
+	}
+	for _, k := range Kinds {
+		info, err := os.Stat(filepath.Join(dir, k.File()))
+		if err != nil || info.Size() == 0 {
+			t.Errorf("%s: expected a non-empty file, got %v", k, err)
+		}
+	}
+	if runtime.MemProfileRate != oldMemRate {
+		t.Errorf("MemProfileRate should be restored to %d, got %d", oldMemRate, runtime.MemProfileRate)
+	}
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 0 {
+		t.Errorf("the mutex fraction should be restored, got %d", fraction)
+	}
+}
+
+func TestRunWorkloadError(t *testing.T) {
+	dir := t.TempDir()
+	boom := errors.New("boom")
+	paths, err := Run(Config{Kinds: []Kind{Heap}, Dir: dir}, func(ctx context.Context) error { return boom })
+	if !errors.Is(err, boom) || len(paths) != 1 {
+		t.Errorf("expected the workload error and the heap profile, got %v %v", paths, err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	s, err := Start(Config{Kinds: []Kind{CPU}, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the CPU profiler is taken by s
+	if _, err := Start(Config{Kinds: []Kind{CPU}, Dir: t.TempDir()}); err == nil {
+		t.Error("expected an error of a second CPU profile")
+	}
+	if _, err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stop(); !errors.Is(err, StoppedError) {
+		t.Errorf("expected StoppedError, got %v", err)
+	}
+	if _, err := Start(Config{Kinds: []Kind{"memory"}}); !errors.Is(err, UnknownKindError) {
+		t.Errorf("expected UnknownKindError, got %v", err)
+	}
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"go_learning/src/26_analyzis/profiling"
 	"log"
 	"math/rand"
-	"os"
-	"runtime"
-	"runtime/pprof"
 	"time"
 )
 
-
+func newMatrix(rows, cols int) [][]int {
+	m := make([][]int, rows)
+	for i := range m {
+		m[i] = make([]int, cols)
+	}
+	return m
+}
 
 func fillMatrix(m [][]int) {
 	s := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -30,46 +37,55 @@ func caculate(m [][]int) {
 	}
 }
 
-func main() {
-	// output file
-	f, err := os.Create("cpu.prof")
-	if err != nil{
-		log.Fatal("Could not create CPU profiler: ", err)
-	}
-	defer f.Close()
-
-	// get system info
-	if err := pprof.StartCPUProfile(f); err != nil {
-		log.Fatal("Could not start CPU profiler: ", err)
+// matrixWorkload fills and sums a size×size matrix, again and again until ctx is done when repeat is set.
+func matrixWorkload(size int, repeat bool) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			x := newMatrix(size, size)
+			fillMatrix(x)
+			caculate(x)
+			if !repeat || ctx.Err() != nil {
+				return nil
+			}
+		}
 	}
-	defer pprof.StopCPUProfile()
+}
 
-	row := 10000000
-	col := 10000000
-	x := make([][]int, row, col)
-	fillMatrix(x)
-	caculate(x)
+// $ go run . -profiles cpu,heap,goroutine -dir prof -size 2000
+// $ go run . -profiles all -duration 10s -block-rate 1 -mutex-fraction 1
+// $ go tool pprof prof/cpu.prof
+// $ go tool trace prof/trace.out
+// without -duration the matrix is filled once, with it the matrix is filled again until the time is up
+func main() {
+	kinds := flag.String("profiles", "cpu,heap,goroutine", "comma separated profiles: cpu, heap, allocs, goroutine, block, mutex, trace, or all")
+	dir := flag.String("dir", ".", "directory of the profile files")
+	duration := flag.Duration("duration", 0, "how long to run the workload, 0 runs it once")
+	size := flag.Int("size", 2000, "rows and columns of the matrix")
+	memRate := flag.Int("mem-rate", 0, "bytes allocated between heap samples, 0 keeps the default 512KB")
+	blockRate := flag.Int("block-rate", 0, "nanoseconds blocked between block samples, 1 records every event")
+	mutexFraction := flag.Int("mutex-fraction", 0, "record 1/n of the mutex contentions")
+	flag.Parse()
 
-	f1, err := os.Create("mem.prof")
-	if err != nil {
-		log.Fatal("Could not create memory profile: ", err)
+	c := profiling.Config{
+		Dir:                  *dir,
+		Duration:             *duration,
+		MemProfileRate:       *memRate,
+		BlockProfileRate:     *blockRate,
+		MutexProfileFraction: *mutexFraction,
+	}
+	var err error
+	if c.Kinds, err = profiling.ParseKinds(*kinds); err != nil {
+		log.Fatal(err)
 	}
-	// $ go build prof.go --> $ ./prof --> $ go tool pprof mem.prof
-	runtime.GC()
-	if err := pprof.WriteHeapProfile(f1); err != nil {
-		log.Fatal("Could not write heap profile: ", err)
+	if *size <= 0 {
+		log.Fatal("-size should be positive")
 	}
-	f1.Close()
 
-	f2, err := os.Create("goroutine.prof")
-	if err != nil {
-		log.Fatal("Could not create goroutine profile: ", err)
+	paths, err := profiling.Run(c, matrixWorkload(*size, *duration > 0))
+	for _, path := range paths {
+		fmt.Println(path)
 	}
-	if gProf := pprof.Lookup("goroutine"); gProf == nil {
-		log.Fatal("Could not write goroutine profile: ")
-	} else {
-		gProf.WriteTo(f2, 0)
+	if err != nil {
+		log.Fatal(err)
 	}
-	f2.Close()
 }
-
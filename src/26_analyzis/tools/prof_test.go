@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"testing"
 )
 
 func TestMain(t *testing.T) {
-	row := 100
-	col := 100
-	x := make([][]int, row)
-	for i := range x {
-		x[i] = make([]int, col)
-	}
+	x := newMatrix(100, 100)
 	fillMatrix(x)
 	caculate(x)
+	if len(x) != 100 || len(x[99]) != 100 {
+		t.Errorf("unexpected matrix %dx%d", len(x), len(x[0]))
+	}
+}
+
+func TestMatrixWorkload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := matrixWorkload(10, true)(ctx); err != nil {
+		t.Error(err)
+	}
 }
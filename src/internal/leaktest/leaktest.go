@@ -0,0 +1,33 @@
+package leaktest
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+/*
+	測試 goroutine 有沒有洩漏（例如 context 取消後還卡在 channel 上）：
+		leaktest.Check(t, func() {
+			f := future.Go(ctx, service)
+			f.Await()
+		})
+	fn 結束後 goroutine 的數量在 500ms 內要回到 fn 之前的數量，
+	剛結束的 goroutine 需要一點時間才會退出，所以會重試幾次。
+	放在 internal 裡，只有這個 repo 的測試可以用。
+*/
+
+// Check fails the test if goroutines started by fn are still alive shortly after it returned.
+func Check(t testing.TB, fn func()) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	fn()
+	var after int
+	for i := 0; i < 50; i++ {
+		time.Sleep(time.Millisecond * 10)
+		if after = runtime.NumGoroutine(); after <= before {
+			return
+		}
+	}
+	t.Errorf("goroutine leak: before %d, after %d", before, after)
+}